@@ -1,6 +1,12 @@
 package apiclient
 
-import "git.terah.dev/imterah/hermes/apiclient/users"
+import (
+	"time"
+
+	"git.terah.dev/imterah/hermes/apiclient/backends"
+	"git.terah.dev/imterah/hermes/apiclient/permissions"
+	"git.terah.dev/imterah/hermes/apiclient/users"
+)
 
 type HermesAPIClient struct {
 	URL string
@@ -8,14 +14,90 @@ type HermesAPIClient struct {
 
 /// Users
 
-func (api *HermesAPIClient) UserGetRefreshToken(username *string, email *string, password string) (string, error) {
-	return users.GetRefreshToken(api.URL, username, email, password)
+func (api *HermesAPIClient) UserGetRefreshToken(username *string, email *string, password string, totpCode, recoveryCode *string) (string, []string, error) {
+	return users.GetRefreshToken(api.URL, username, email, password, totpCode, recoveryCode)
 }
 
-func (api *HermesAPIClient) UserGetJWTFromToken(refreshToken string) (string, error) {
+func (api *HermesAPIClient) UserGetJWTFromToken(refreshToken string) (string, string, error) {
 	return users.GetJWTFromToken(api.URL, refreshToken)
 }
 
 func (api *HermesAPIClient) UserCreate(fullName, username, email, password string, isBot bool) (string, error) {
 	return users.CreateUser(api.URL, fullName, username, email, password, isBot)
 }
+
+func (api *HermesAPIClient) UserEdit(token string, uid *uint, name, email, username *string, disabled *bool) error {
+	return users.EditUser(api.URL, token, uid, name, email, username, disabled)
+}
+
+func (api *HermesAPIClient) UserTransferOwnership(token string, uid, newOwnerID uint) (int, int, error) {
+	return users.TransferOwnership(api.URL, token, uid, newOwnerID)
+}
+
+func (api *HermesAPIClient) UserChangePassword(token, oldPassword, newPassword string) (int, error) {
+	return users.ChangePassword(api.URL, token, oldPassword, newPassword)
+}
+
+func (api *HermesAPIClient) UserResetPassword(token string, uid uint, newPassword string) (int, error) {
+	return users.ResetPassword(api.URL, token, uid, newPassword)
+}
+
+/// Sessions
+
+func (api *HermesAPIClient) SessionsList(token string, uid *uint) ([]*users.Session, error) {
+	return users.ListSessions(api.URL, token, uid)
+}
+
+func (api *HermesAPIClient) SessionsRevoke(token string, sessionID uint) error {
+	return users.RevokeSession(api.URL, token, sessionID)
+}
+
+func (api *HermesAPIClient) SessionsRevokeOthers(token string, uid *uint) (int, error) {
+	return users.RevokeOtherSessions(api.URL, token, uid)
+}
+
+/// TOTP
+
+func (api *HermesAPIClient) TOTPEnroll(token string) (string, string, error) {
+	return users.EnrollTOTP(api.URL, token)
+}
+
+func (api *HermesAPIClient) TOTPVerify(token, code string) ([]string, error) {
+	return users.VerifyTOTP(api.URL, token, code)
+}
+
+func (api *HermesAPIClient) TOTPDisable(token string, uid *uint, code, recoveryCode *string) error {
+	return users.DisableTOTP(api.URL, token, uid, code, recoveryCode)
+}
+
+func (api *HermesAPIClient) TOTPRegenerateRecoveryCodes(token, code string) ([]string, error) {
+	return users.RegenerateRecoveryCodes(api.URL, token, code)
+}
+
+/// Permissions
+
+func (api *HermesAPIClient) PermissionsList(token string, uid *uint) ([]*permissions.Permission, error) {
+	return permissions.ListPermissions(api.URL, token, uid)
+}
+
+func (api *HermesAPIClient) PermissionsGrant(token string, uid uint, node string) error {
+	return permissions.ChangePermission(api.URL, token, uid, node, true)
+}
+
+func (api *HermesAPIClient) PermissionsRevoke(token string, uid uint, node string) error {
+	return permissions.ChangePermission(api.URL, token, uid, node, false)
+}
+
+func (api *HermesAPIClient) PermissionsReset(token string, uid uint, node string) error {
+	return permissions.ResetPermission(api.URL, token, uid, node)
+}
+
+/// Backends
+
+func (api *HermesAPIClient) BackendsGetLogs(token string, backendID uint, since *time.Time, tail uint) ([]*backends.LogLine, error) {
+	return backends.GetLogs(api.URL, token, backendID, since, tail)
+}
+
+func (api *HermesAPIClient) BackendsFollowLogs(token string, backendID uint, since *time.Time, tail uint, onLogLine func(*backends.LogLine)) error {
+	return backends.FollowLogs(api.URL, token, backendID, since, tail, onLogLine)
+}
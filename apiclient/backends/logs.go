@@ -0,0 +1,150 @@
+package backends
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
+)
+
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// Longest event that can be read while following logs. Backends can write very long lines (ex. dumps of their state).
+const maxEventSize = 16 * 1024 * 1024
+
+type getLogsResponse struct {
+	Error   string     `json:"error"`
+	Success bool       `json:"success"`
+	Data    []*LogLine `json:"data"`
+}
+
+func postLogsRequest(url, token string, backendID uint, since *time.Time, tail uint, follow bool) (*http.Response, error) {
+	body, err := json.Marshal(&backendstructs.BackendLogsRequest{
+		Token:     token,
+		BackendID: backendID,
+		Since:     since,
+		Tail:      tail,
+		Follow:    follow,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return http.Post(fmt.Sprintf("%s/api/v1/backends/logs", url), "application/json", bytes.NewBuffer(body))
+}
+
+func GetLogs(url, token string, backendID uint, since *time.Time, tail uint) ([]*LogLine, error) {
+	res, err := postLogsRequest(url, token, backendID, since, tail, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	response := &getLogsResponse{}
+
+	if err := json.Unmarshal(bodyContents, response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("failed to get logs")
+	}
+
+	return response.Data, nil
+}
+
+// Calls onLogLine for every line the backend already wrote (like GetLogs), and then for every new line, until the
+// backend gets stopped or the connection is closed.
+func FollowLogs(url, token string, backendID uint, since *time.Time, tail uint, onLogLine func(*LogLine)) error {
+	res, err := postLogsRequest(url, token, backendID, since, tail, true)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	// Errors come back as JSON instead of as events
+	if res.StatusCode != http.StatusOK {
+		bodyContents, err := io.ReadAll(res.Body)
+
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %s", err.Error())
+		}
+
+		response := &getLogsResponse{}
+
+		if err := json.Unmarshal(bodyContents, response); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+	event := ""
+	data := ""
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if event == "end" {
+				return nil
+			}
+
+			if event == "log" {
+				logLine := &LogLine{}
+
+				if err := json.Unmarshal([]byte(data), logLine); err != nil {
+					return fmt.Errorf("failed to parse log line: %s", err.Error())
+				}
+
+				onLogLine(logLine)
+			}
+
+			event = ""
+			data = ""
+
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data += value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read logs: %s", err.Error())
+	}
+
+	return fmt.Errorf("connection to the server was closed")
+}
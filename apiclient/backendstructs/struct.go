@@ -1,5 +1,7 @@
 package backendstructs
 
+import "time"
+
 type BackendCreationRequest struct {
 	Token             string      `validate:"required"`
 	Name              string      `validate:"required"`
@@ -16,6 +18,14 @@ type BackendLookupRequest struct {
 	Backend     *string `json:"backend"`
 }
 
+type BackendLogsRequest struct {
+	Token     string     `validate:"required" json:"token"`
+	BackendID uint       `json:"id" validate:"required"`
+	Since     *time.Time `json:"since,omitempty"`
+	Tail      uint       `json:"tail"`
+	Follow    bool       `json:"follow"`
+}
+
 type BackendRemovalRequest struct {
 	Token     string `validate:"required"`
 	BackendID uint   `json:"id" validate:"required"`
@@ -81,6 +91,9 @@ type UserLoginRequest struct {
 	Email    *string `json:"email"`
 
 	Password string `json:"password" validate:"required"`
+
+	TOTPCode     *string `json:"totpCode"`
+	RecoveryCode *string `json:"recoveryCode"`
 }
 
 type UserLookupRequest struct {
@@ -90,13 +103,88 @@ type UserLookupRequest struct {
 	Email    *string `json:"email"`
 	Username *string `json:"username"`
 	IsBot    *bool   `json:"isServiceAccount"`
+	Disabled *bool   `json:"disabled"`
 }
 
 type UserRefreshRequest struct {
 	Token string `json:"token" validate:"required"`
 }
 
+type UserEditRequest struct {
+	Token    string  `json:"token" validate:"required"`
+	UID      *uint   `json:"id"`
+	Name     *string `json:"name"`
+	Email    *string `json:"email"`
+	Username *string `json:"username"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type UserTransferRequest struct {
+	Token      string `json:"token" validate:"required"`
+	UID        uint   `json:"id" validate:"required"`
+	NewOwnerID uint   `json:"newOwnerID" validate:"required"`
+}
+
 type UserRemovalRequest struct {
 	Token string `json:"token" validate:"required"`
 	UID   *uint  `json:"uid"`
 }
+
+type SessionListRequest struct {
+	Token string `json:"token" validate:"required"`
+	UID   *uint  `json:"id"`
+}
+
+type SessionRevokeRequest struct {
+	Token     string `json:"token" validate:"required"`
+	SessionID uint   `json:"id" validate:"required"`
+}
+
+type SessionRevokeOthersRequest struct {
+	Token string `json:"token" validate:"required"`
+	UID   *uint  `json:"id"`
+}
+
+type PasswordChangeRequest struct {
+	Token       string `json:"token" validate:"required"`
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token" validate:"required"`
+	UID         uint   `json:"id" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+type TOTPEnrollRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type TOTPVerifyRequest struct {
+	Token string `json:"token" validate:"required"`
+	Code  string `json:"code" validate:"required"`
+}
+
+type TOTPDisableRequest struct {
+	Token        string  `json:"token" validate:"required"`
+	UID          *uint   `json:"userID"`
+	Code         *string `json:"code"`
+	RecoveryCode *string `json:"recoveryCode"`
+}
+
+type TOTPRecoveryCodesRequest struct {
+	Token string `json:"token" validate:"required"`
+	Code  string `json:"code" validate:"required"`
+}
+
+type PermissionListRequest struct {
+	Token string `validate:"required" json:"token"`
+	UID   *uint  `json:"id"`
+}
+
+type PermissionChangeRequest struct {
+	Token          string `validate:"required" json:"token"`
+	UID            uint   `validate:"required" json:"id"`
+	PermissionNode string `validate:"required" json:"node"`
+}
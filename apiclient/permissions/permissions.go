@@ -0,0 +1,122 @@
+package permissions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
+)
+
+type Permission struct {
+	PermissionNode string `json:"node"`
+	HasPermission  bool   `json:"hasPermission"`
+	IsOverride     bool   `json:"isOverride"`
+}
+
+type listPermissionsResponse struct {
+	Error   string        `json:"error"`
+	Success bool          `json:"success"`
+	Data    []*Permission `json:"data"`
+}
+
+type changePermissionResponse struct {
+	Error   string `json:"error"`
+	Success bool   `json:"success"`
+}
+
+func ListPermissions(url, token string, uid *uint) ([]*Permission, error) {
+	body, err := json.Marshal(&backendstructs.PermissionListRequest{
+		Token: token,
+		UID:   uid,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.Post(fmt.Sprintf("%s/api/v1/permissions/list", url), "application/json", bytes.NewBuffer(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	response := &listPermissionsResponse{}
+
+	if err := json.Unmarshal(bodyContents, response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("failed to get permissions")
+	}
+
+	return response.Data, nil
+}
+
+func ChangePermission(url, token string, uid uint, node string, hasPermission bool) error {
+	endpoint := "revoke"
+
+	if hasPermission {
+		endpoint = "grant"
+	}
+
+	return sendPermissionChange(url, endpoint, token, uid, node)
+}
+
+// Removes the permission override of a user, so it comes from their roles again
+func ResetPermission(url, token string, uid uint, node string) error {
+	return sendPermissionChange(url, "reset", token, uid, node)
+}
+
+func sendPermissionChange(url, endpoint, token string, uid uint, node string) error {
+	body, err := json.Marshal(&backendstructs.PermissionChangeRequest{
+		Token:          token,
+		UID:            uid,
+		PermissionNode: node,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(fmt.Sprintf("%s/api/v1/permissions/%s", url, endpoint), "application/json", bytes.NewBuffer(body))
+
+	if err != nil {
+		return err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	response := &changePermissionResponse{}
+
+	if err := json.Unmarshal(bodyContents, response); err != nil {
+		return err
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("failed to change permission")
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package users
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,90 +11,137 @@ import (
 	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
 )
 
+// Returned by GetRefreshToken when the user has TOTP enabled, and no code was given
+var ErrTOTPRequired = errors.New("two-factor authentication code required")
+
+// Returned by GetRefreshToken when the user has to enroll into TOTP before they can log in. Logging in again with a
+// code for this secret finishes enrolling.
+type TOTPEnrollRequiredError struct {
+	Secret string
+	URL    string
+}
+
+func (err *TOTPEnrollRequiredError) Error() string {
+	return "two-factor authentication enrollment required"
+}
+
 type refreshTokenResponse struct {
+	Error        string `json:"error"`
 	Success      bool   `json:"success"`
 	RefreshToken string `json:"refreshToken"`
+
+	TOTPRequired       bool     `json:"totpRequired"`
+	TOTPEnrollRequired bool     `json:"totpEnrollRequired"`
+	TOTPSecret         string   `json:"totpSecret"`
+	TOTPURL            string   `json:"totpURL"`
+	RecoveryCodes      []string `json:"recoveryCodes"`
 }
 
 type jwtTokenResponse struct {
-	Success bool   `json:"success"`
-	JWT     string `json:"token"`
+	Success      bool   `json:"success"`
+	JWT          string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
 }
 
-func GetRefreshToken(url string, username, email *string, password string) (string, error) {
+// Logs in, and gets a refresh token. The TOTP code (or recovery code) is only needed if the user has TOTP enabled, or
+// has to enroll into it. The recovery codes are only returned if the user just enrolled into TOTP.
+func GetRefreshToken(url string, username, email *string, password string, totpCode, recoveryCode *string) (string, []string, error) {
 	body, err := json.Marshal(&backendstructs.UserLoginRequest{
-		Username: username,
-		Email:    email,
-		Password: password,
+		Username:     username,
+		Email:        email,
+		Password:     password,
+		TOTPCode:     totpCode,
+		RecoveryCode: recoveryCode,
 	})
 
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	res, err := http.Post(fmt.Sprintf("%s/api/v1/users/login", url), "application/json", bytes.NewBuffer(body))
 
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	bodyContents, err := io.ReadAll(res.Body)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %s", err.Error())
+		return "", nil, fmt.Errorf("failed to read response body: %s", err.Error())
 	}
 
 	response := &refreshTokenResponse{}
 
 	if err := json.Unmarshal(bodyContents, response); err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	if response.TOTPRequired {
+		return "", nil, ErrTOTPRequired
+	}
+
+	if response.TOTPEnrollRequired {
+		return "", nil, &TOTPEnrollRequiredError{
+			Secret: response.TOTPSecret,
+			URL:    response.TOTPURL,
+		}
+	}
+
+	if response.Error != "" {
+		return "", nil, fmt.Errorf("error from server: %s", response.Error)
 	}
 
 	if !response.Success {
-		return "", fmt.Errorf("failed to get refresh token")
+		return "", nil, fmt.Errorf("failed to get refresh token")
 	}
 
 	if response.RefreshToken == "" {
-		return "", fmt.Errorf("refresh token is empty")
+		return "", nil, fmt.Errorf("refresh token is empty")
 	}
 
-	return response.RefreshToken, nil
+	return response.RefreshToken, response.RecoveryCodes, nil
 }
 
-func GetJWTFromToken(url, refreshToken string) (string, error) {
+// Gets a JWT token from a refresh token. The server may replace the refresh token when it gets used, in which case the
+// new one is returned, and has to be used from now on. Otherwise, the same refresh token is returned.
+func GetJWTFromToken(url, refreshToken string) (string, string, error) {
 	body, err := json.Marshal(&backendstructs.UserRefreshRequest{
 		Token: refreshToken,
 	})
 
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	res, err := http.Post(fmt.Sprintf("%s/api/v1/users/refresh", url), "application/json", bytes.NewBuffer(body))
 
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	bodyContents, err := io.ReadAll(res.Body)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %s", err.Error())
+		return "", "", fmt.Errorf("failed to read response body: %s", err.Error())
 	}
 
 	response := &jwtTokenResponse{}
 
 	if err := json.Unmarshal(bodyContents, response); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if !response.Success {
-		return "", fmt.Errorf("failed to get JWT token")
+		return "", "", fmt.Errorf("failed to get JWT token")
 	}
 
 	if response.JWT == "" {
-		return "", fmt.Errorf("JWT token is empty")
+		return "", "", fmt.Errorf("JWT token is empty")
+	}
+
+	if response.RefreshToken == "" {
+		response.RefreshToken = refreshToken
 	}
 
-	return response.JWT, nil
+	return response.JWT, response.RefreshToken, nil
 }
@@ -14,8 +14,12 @@ type createUserResponse struct {
 	Error        string `json:"error"`
 	Success      bool   `json:"success"`
 	RefreshToken string `json:"refreshToken"`
+
+	TOTPEnrollRequired bool `json:"totpEnrollRequired"`
 }
 
+// Creates a user, and gets a refresh token for them. The refresh token is empty if the user has to log in to enroll
+// into TOTP first.
 func CreateUser(url, fullName, username, email, password string, isBot bool) (string, error) {
 	body, err := json.Marshal(&backendstructs.UserCreationRequest{
 		Username: username,
@@ -55,6 +59,11 @@ func CreateUser(url, fullName, username, email, password string, isBot bool) (st
 		return "", fmt.Errorf("failed to get refresh token")
 	}
 
+	// The user has to log in to enroll into TOTP before they can get a refresh token
+	if response.TOTPEnrollRequired {
+		return "", nil
+	}
+
 	if response.RefreshToken == "" {
 		return "", fmt.Errorf("refresh token is empty")
 	}
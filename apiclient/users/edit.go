@@ -0,0 +1,98 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
+)
+
+type editUserResponse struct {
+	Error   string `json:"error"`
+	Success bool   `json:"success"`
+}
+
+type transferOwnershipResponse struct {
+	Error    string `json:"error"`
+	Success  bool   `json:"success"`
+	Backends int    `json:"backends"`
+	Proxies  int    `json:"proxies"`
+}
+
+func postUserRequest(url, endpoint string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(fmt.Sprintf("%s/api/v1/users/%s", url, endpoint), "application/json", bytes.NewBuffer(body))
+
+	if err != nil {
+		return err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	return json.Unmarshal(bodyContents, response)
+}
+
+// Edits a user (defaults to yourself). Only the fields that are set get changed.
+func EditUser(url, token string, uid *uint, name, email, username *string, disabled *bool) error {
+	response := &editUserResponse{}
+
+	err := postUserRequest(url, "edit", &backendstructs.UserEditRequest{
+		Token:    token,
+		UID:      uid,
+		Name:     name,
+		Email:    email,
+		Username: username,
+		Disabled: disabled,
+	}, response)
+
+	if err != nil {
+		return err
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("failed to edit user")
+	}
+
+	return nil
+}
+
+// Gives every backend and proxy of a user to another user. Returns how many backends and proxies were transferred.
+func TransferOwnership(url, token string, uid, newOwnerID uint) (int, int, error) {
+	response := &transferOwnershipResponse{}
+
+	err := postUserRequest(url, "transfer", &backendstructs.UserTransferRequest{
+		Token:      token,
+		UID:        uid,
+		NewOwnerID: newOwnerID,
+	}, response)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if response.Error != "" {
+		return 0, 0, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return 0, 0, fmt.Errorf("failed to transfer ownership")
+	}
+
+	return response.Backends, response.Proxies, nil
+}
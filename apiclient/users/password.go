@@ -0,0 +1,71 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
+)
+
+type passwordResponse struct {
+	Error   string `json:"error"`
+	Success bool   `json:"success"`
+	Revoked int    `json:"revoked"`
+}
+
+func postPasswordRequest(url, endpoint string, request interface{}) (int, error) {
+	body, err := json.Marshal(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := http.Post(fmt.Sprintf("%s/api/v1/users/password/%s", url, endpoint), "application/json", bytes.NewBuffer(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	response := &passwordResponse{}
+
+	if err := json.Unmarshal(bodyContents, response); err != nil {
+		return 0, err
+	}
+
+	if response.Error != "" {
+		return 0, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return 0, fmt.Errorf("failed to set password")
+	}
+
+	return response.Revoked, nil
+}
+
+// Changes the password of the current user. Returns how many other sessions got logged out.
+func ChangePassword(url, token, oldPassword, newPassword string) (int, error) {
+	return postPasswordRequest(url, "change", &backendstructs.PasswordChangeRequest{
+		Token:       token,
+		OldPassword: oldPassword,
+		NewPassword: newPassword,
+	})
+}
+
+// Sets the password of another user. Returns how many of their sessions got logged out.
+func ResetPassword(url, token string, uid uint, newPassword string) (int, error) {
+	return postPasswordRequest(url, "reset", &backendstructs.PasswordResetRequest{
+		Token:       token,
+		UID:         uid,
+		NewPassword: newPassword,
+	})
+}
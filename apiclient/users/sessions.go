@@ -0,0 +1,127 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
+)
+
+type Session struct {
+	SessionID      uint       `json:"id"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	CreationIPAddr string     `json:"creationIP"`
+	LastUsedAt     *time.Time `json:"lastUsedAt"`
+	LastUsedIPAddr string     `json:"lastUsedIP"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	NeverExpires   bool       `json:"neverExpires"`
+	IsCurrent      bool       `json:"isCurrent"`
+}
+
+type listSessionsResponse struct {
+	Error   string     `json:"error"`
+	Success bool       `json:"success"`
+	Data    []*Session `json:"data"`
+}
+
+type revokeSessionsResponse struct {
+	Error   string `json:"error"`
+	Success bool   `json:"success"`
+	Revoked int    `json:"revoked"`
+}
+
+func postSessionRequest(url, endpoint string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(fmt.Sprintf("%s/api/v1/users/sessions/%s", url, endpoint), "application/json", bytes.NewBuffer(body))
+
+	if err != nil {
+		return err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	return json.Unmarshal(bodyContents, response)
+}
+
+func ListSessions(url, token string, uid *uint) ([]*Session, error) {
+	response := &listSessionsResponse{}
+
+	err := postSessionRequest(url, "list", &backendstructs.SessionListRequest{
+		Token: token,
+		UID:   uid,
+	}, response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("failed to get sessions")
+	}
+
+	return response.Data, nil
+}
+
+func RevokeSession(url, token string, sessionID uint) error {
+	response := &revokeSessionsResponse{}
+
+	err := postSessionRequest(url, "revoke", &backendstructs.SessionRevokeRequest{
+		Token:     token,
+		SessionID: sessionID,
+	}, response)
+
+	if err != nil {
+		return err
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("failed to revoke session")
+	}
+
+	return nil
+}
+
+// Revokes every session except the current one. If another user is given, every one of their sessions gets revoked.
+func RevokeOtherSessions(url, token string, uid *uint) (int, error) {
+	response := &revokeSessionsResponse{}
+
+	err := postSessionRequest(url, "revokeOthers", &backendstructs.SessionRevokeOthersRequest{
+		Token: token,
+		UID:   uid,
+	}, response)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if response.Error != "" {
+		return 0, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return 0, fmt.Errorf("failed to revoke sessions")
+	}
+
+	return response.Revoked, nil
+}
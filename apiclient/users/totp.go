@@ -0,0 +1,106 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/apiclient/backendstructs"
+)
+
+type totpResponse struct {
+	Error         string   `json:"error"`
+	Success       bool     `json:"success"`
+	Secret        string   `json:"secret"`
+	URL           string   `json:"url"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func postTOTPRequest(url, endpoint string, request interface{}) (*totpResponse, error) {
+	body, err := json.Marshal(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.Post(fmt.Sprintf("%s/api/v1/users/totp/%s", url, endpoint), "application/json", bytes.NewBuffer(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	response := &totpResponse{}
+
+	if err := json.Unmarshal(bodyContents, response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("error from server: %s", response.Error)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("request failed")
+	}
+
+	return response, nil
+}
+
+// Starts enrolling into TOTP. Returns the new secret, and the otpauth:// URL for it.
+func EnrollTOTP(url, token string) (string, string, error) {
+	response, err := postTOTPRequest(url, "enroll", &backendstructs.TOTPEnrollRequest{
+		Token: token,
+	})
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return response.Secret, response.URL, nil
+}
+
+// Finishes enrolling into TOTP. Returns the recovery codes.
+func VerifyTOTP(url, token, code string) ([]string, error) {
+	response, err := postTOTPRequest(url, "verify", &backendstructs.TOTPVerifyRequest{
+		Token: token,
+		Code:  code,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.RecoveryCodes, nil
+}
+
+func DisableTOTP(url, token string, uid *uint, code, recoveryCode *string) error {
+	_, err := postTOTPRequest(url, "disable", &backendstructs.TOTPDisableRequest{
+		Token:        token,
+		UID:          uid,
+		Code:         code,
+		RecoveryCode: recoveryCode,
+	})
+
+	return err
+}
+
+func RegenerateRecoveryCodes(url, token, code string) ([]string, error) {
+	response, err := postTOTPRequest(url, "recoveryCodes", &backendstructs.TOTPRecoveryCodesRequest{
+		Token: token,
+		Code:  code,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.RecoveryCodes, nil
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// Entry is something a user is doing through the API. It gets started once the user is known, and finished (usually
+// deferred) once the response has been sent, so that denied and failed requests get recorded too.
+type Entry struct {
+	context  *gin.Context
+	actor    *dbcore.User
+	action   string
+	targetID *uint
+	before   map[string]interface{}
+	after    map[string]interface{}
+}
+
+func Start(c *gin.Context, actor *dbcore.User, action string) *Entry {
+	return &Entry{
+		context: c,
+		actor:   actor,
+		action:  action,
+	}
+}
+
+func (entry *Entry) SetTarget(targetID uint) {
+	entry.targetID = &targetID
+}
+
+// Sets what the target looked like before the action (see the snapshot functions)
+func (entry *Entry) SetBefore(snapshot map[string]interface{}) {
+	entry.before = snapshot
+}
+
+// Sets what the target looked like after the action (see the snapshot functions)
+func (entry *Entry) SetAfter(snapshot map[string]interface{}) {
+	entry.after = snapshot
+}
+
+// Saves the entry into the audit log. Failing to save it doesn't fail the request, as whatever got recorded has
+// already happened by then.
+func (entry *Entry) Finish() {
+	before, after := entry.before, entry.after
+
+	// Only keep what changed, so that edits are easy to read
+	if before != nil && after != nil {
+		before, after = map[string]interface{}{}, map[string]interface{}{}
+
+		for key, value := range entry.before {
+			if !reflect.DeepEqual(value, entry.after[key]) {
+				before[key] = value
+			}
+		}
+
+		for key, value := range entry.after {
+			if !reflect.DeepEqual(value, entry.before[key]) {
+				after[key] = value
+			}
+		}
+	}
+
+	statusCode := entry.context.Writer.Status()
+
+	auditEntry := &dbcore.AuditEntry{
+		UserID:     entry.actor.ID,
+		ClientIP:   entry.context.ClientIP(),
+		Action:     entry.action,
+		TargetID:   entry.targetID,
+		Before:     marshalSnapshot(before),
+		After:      marshalSnapshot(after),
+		StatusCode: statusCode,
+		Success:    statusCode < 400,
+	}
+
+	if entry.actor.Session != nil {
+		auditEntry.SessionID = &entry.actor.Session.ID
+	}
+
+	if entry.actor.APIKey != nil {
+		auditEntry.APIKeyID = &entry.actor.APIKey.ID
+	}
+
+	if err := dbcore.DB.Create(auditEntry).Error; err != nil {
+		log.Warnf("Failed to save audit log entry for '%s' by user #%d: %s", entry.action, entry.actor.ID, err.Error())
+	}
+}
+
+func marshalSnapshot(snapshot map[string]interface{}) *string {
+	if snapshot == nil {
+		return nil
+	}
+
+	snapshotBytes, err := json.Marshal(snapshot)
+
+	if err != nil {
+		log.Warnf("Failed to marshal audit log snapshot: %s", err.Error())
+		return nil
+	}
+
+	snapshotString := string(snapshotBytes)
+	return &snapshotString
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+)
+
+func ProxySnapshot(proxy *dbcore.Proxy) map[string]interface{} {
+	return map[string]interface{}{
+		"name":            proxy.Name,
+		"description":     proxy.Description,
+		"ownerID":         proxy.UserID,
+		"backendID":       proxy.BackendID,
+		"protocol":        proxy.Protocol,
+		"sourceIP":        proxy.SourceIP,
+		"sourcePort":      proxy.SourcePort,
+		"destinationPort": proxy.DestinationPort,
+		"autoStart":       proxy.AutoStart,
+	}
+}
+
+// The connection details of backends usually hold secrets, so only a hash of them is kept. That's still enough to tell
+// if they changed.
+func BackendSnapshot(backend *dbcore.Backend) map[string]interface{} {
+	connectionDetailsHash := sha256.Sum256([]byte(backend.BackendParameters))
+
+	return map[string]interface{}{
+		"name":                  backend.Name,
+		"description":           backend.Description,
+		"ownerID":               backend.UserID,
+		"backend":               backend.Backend,
+		"connectionDetailsHash": hex.EncodeToString(connectionDetailsHash[:]),
+		"autoStart":             backend.AutoStart,
+	}
+}
+
+func RoleSnapshot(role *dbcore.Role) map[string]interface{} {
+	permissionNodes := make([]string, len(role.Permissions))
+
+	for permissionIndex, permission := range role.Permissions {
+		permissionNodes[permissionIndex] = permission.PermissionNode
+	}
+
+	return map[string]interface{}{
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": permissionNodes,
+	}
+}
+
+func APIKeySnapshot(apiKey *dbcore.APIKey) map[string]interface{} {
+	permissionNodes := make([]string, len(apiKey.Permissions))
+
+	for permissionIndex, permission := range apiKey.Permissions {
+		permissionNodes[permissionIndex] = permission.PermissionNode
+	}
+
+	return map[string]interface{}{
+		"name":        apiKey.Name,
+		"userID":      apiKey.UserID,
+		"keyPrefix":   apiKey.KeyPrefix,
+		"expiresAt":   apiKey.ExpiresAt,
+		"permissions": permissionNodes,
+	}
+}
+
+func UserSnapshot(user *dbcore.User) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     user.Name,
+		"email":    user.Email,
+		"username": user.Username,
+		"disabled": user.Disabled,
+	}
+}
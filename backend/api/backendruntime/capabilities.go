@@ -0,0 +1,58 @@
+package backendruntime
+
+import (
+	"fmt"
+	"strings"
+
+	"git.terah.dev/imterah/hermes/backend/commonbackend"
+)
+
+var capabilityNames = []struct {
+	Capability uint32
+	Name       string
+}{
+	{commonbackend.CapabilityTCP, "tcp"},
+	{commonbackend.CapabilityUDP, "udp"},
+	{commonbackend.CapabilityConnectionListing, "connectionListing"},
+	{commonbackend.CapabilityProxyInstances, "proxyInstances"},
+}
+
+func (runtime *Runtime) HasCapability(capability uint32) bool {
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	return runtime.capabilities&capability == capability
+}
+
+// Checks if the backend can run proxies of a protocol ('tcp' or 'udp'). The error is meant to be shown to the user.
+func (runtime *Runtime) CheckProtocol(protocol string) error {
+	var capability uint32
+
+	switch protocol {
+	case "tcp":
+		capability = commonbackend.CapabilityTCP
+	case "udp":
+		capability = commonbackend.CapabilityUDP
+	default:
+		return fmt.Errorf("Protocol must be either 'tcp' or 'udp'")
+	}
+
+	if !runtime.HasCapability(capability) {
+		return fmt.Errorf("Backend doesn't support %s proxies", strings.ToUpper(protocol))
+	}
+
+	return nil
+}
+
+// Gets the names of everything that the backend supports, for showing to users
+func (runtime *Runtime) GetCapabilityNames() []string {
+	names := []string{}
+
+	for _, capability := range capabilityNames {
+		if runtime.HasCapability(capability.Capability) {
+			names = append(names, capability.Name)
+		}
+	}
+
+	return names
+}
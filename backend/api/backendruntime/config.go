@@ -0,0 +1,110 @@
+package backendruntime
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	// How often backends get pinged, and how long they have to respond
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+
+	// How long backends have to respond to commands
+	CommandTimeout time.Duration
+
+	// How many pings in a row a backend can miss before its process gets restarted. 0 turns restarting off.
+	MaxMissedPings int
+
+	// Backend processes can be restarted MaxRestarts times within RestartWindow before we give up on them. 0 allows any
+	// number of restarts.
+	MaxRestarts   int
+	RestartWindow time.Duration
+
+	// How long to wait before restarting a backend process. It doubles with every recent crash, up to MaxRestartBackoff.
+	RestartBackoff    time.Duration
+	MaxRestartBackoff time.Duration
+
+	// How many lines of logs are kept for each backend
+	LogLines int
+)
+
+func getDuration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s: invalid duration '%s'", name, value)
+	}
+
+	return duration, nil
+}
+
+func getInt(name string, defaultValue int) (int, error) {
+	value := os.Getenv(name)
+
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("%s: invalid number '%s'", name, value)
+	}
+
+	return number, nil
+}
+
+func loadConfig() error {
+	var err error
+
+	if PingInterval, err = getDuration("HERMES_BACKEND_PING_INTERVAL", 5*time.Second); err != nil {
+		return err
+	}
+
+	if PingTimeout, err = getDuration("HERMES_BACKEND_PING_TIMEOUT", 5*time.Second); err != nil {
+		return err
+	}
+
+	if CommandTimeout, err = getDuration("HERMES_BACKEND_COMMAND_TIMEOUT", time.Minute); err != nil {
+		return err
+	}
+
+	if MaxMissedPings, err = getInt("HERMES_BACKEND_MAX_MISSED_PINGS", 3); err != nil {
+		return err
+	}
+
+	if MaxRestarts, err = getInt("HERMES_BACKEND_MAX_RESTARTS", 5); err != nil {
+		return err
+	}
+
+	if RestartWindow, err = getDuration("HERMES_BACKEND_RESTART_WINDOW", 10*time.Minute); err != nil {
+		return err
+	}
+
+	if RestartBackoff, err = getDuration("HERMES_BACKEND_RESTART_BACKOFF", 5*time.Second); err != nil {
+		return err
+	}
+
+	if MaxRestartBackoff, err = getDuration("HERMES_BACKEND_MAX_RESTART_BACKOFF", 5*time.Minute); err != nil {
+		return err
+	}
+
+	if LogLines, err = getInt("HERMES_BACKEND_LOG_LINES", 1000); err != nil {
+		return err
+	}
+
+	if MaxRestartBackoff < RestartBackoff {
+		return fmt.Errorf("HERMES_BACKEND_MAX_RESTART_BACKOFF: can't be shorter than HERMES_BACKEND_RESTART_BACKOFF")
+	}
+
+	return nil
+}
@@ -1,15 +1,88 @@
 package backendruntime
 
-import "os"
+import (
+	"os"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
 
 var (
 	AvailableBackends []*Backend
-	RunningBackends   map[uint]*Runtime
 	TempDir           string
 	isDevelopmentMode bool
+
+	// Backends that are running, and the IDs of ones that are being started, by backend ID
+	runningBackends     map[uint]*Runtime
+	startingBackends    map[uint]bool
+	runningBackendsLock sync.Mutex
 )
 
 func init() {
-	RunningBackends = make(map[uint]*Runtime)
+	runningBackends = make(map[uint]*Runtime)
+	startingBackends = make(map[uint]bool)
 	isDevelopmentMode = os.Getenv("HERMES_DEVELOPMENT_MODE") != ""
 }
+
+func GetRunningBackend(backendID uint) (*Runtime, bool) {
+	runningBackendsLock.Lock()
+	defer runningBackendsLock.Unlock()
+
+	runtime, ok := runningBackends[backendID]
+	return runtime, ok
+}
+
+func SetRunningBackend(backendID uint, runtime *Runtime) {
+	runningBackendsLock.Lock()
+	defer runningBackendsLock.Unlock()
+
+	runningBackends[backendID] = runtime
+}
+
+// Removes a backend from the running backends, and returns it if it was running. Only one caller gets it, so it can
+// safely be stopped afterwards.
+func RemoveRunningBackend(backendID uint) (*Runtime, bool) {
+	runningBackendsLock.Lock()
+	defer runningBackendsLock.Unlock()
+
+	runtime, ok := runningBackends[backendID]
+	delete(runningBackends, backendID)
+
+	return runtime, ok
+}
+
+// Marks a backend as being started, so that it doesn't get started twice at once. Returns false if it is already
+// running, or being started. ReleaseBackend has to be called once starting it is done (whether it worked or not).
+//
+// Backends that crashed too often to be restarted don't count as running, so they can be started again without having
+// to be stopped first.
+func ReserveBackend(backendID uint) bool {
+	runningBackendsLock.Lock()
+
+	runtime, ok := runningBackends[backendID]
+
+	if (ok && !runtime.IsCrashLooping()) || startingBackends[backendID] {
+		runningBackendsLock.Unlock()
+		return false
+	}
+
+	delete(runningBackends, backendID)
+	startingBackends[backendID] = true
+
+	runningBackendsLock.Unlock()
+
+	if ok {
+		if err := runtime.Stop(); err != nil {
+			log.Warnf("Failed to stop crash-looping backend: %s", err.Error())
+		}
+	}
+
+	return true
+}
+
+func ReleaseBackend(backendID uint) {
+	runningBackendsLock.Lock()
+	defer runningBackendsLock.Unlock()
+
+	delete(startingBackends, backendID)
+}
@@ -0,0 +1,56 @@
+package backendruntime
+
+import "testing"
+
+func TestReserveBackend(t *testing.T) {
+	const backendID = 1000
+
+	if !ReserveBackend(backendID) {
+		t.Fatal("failed to reserve a backend that isn't running")
+	}
+
+	if ReserveBackend(backendID) {
+		t.Fatal("reserved a backend that is already being started")
+	}
+
+	ReleaseBackend(backendID)
+
+	SetRunningBackend(backendID, &Runtime{
+		isRuntimeRunning: true,
+		logs:             newLogBuffer(0),
+	})
+
+	t.Cleanup(func() {
+		RemoveRunningBackend(backendID)
+	})
+
+	if ReserveBackend(backendID) {
+		t.Fatal("reserved a backend that is already running")
+	}
+}
+
+func TestReserveCrashLoopingBackend(t *testing.T) {
+	const backendID = 1001
+
+	runtime := &Runtime{
+		isRuntimeRunning: true,
+		isCrashLooping:   true,
+		logs:             newLogBuffer(0),
+	}
+
+	SetRunningBackend(backendID, runtime)
+
+	if !ReserveBackend(backendID) {
+		t.Fatal("failed to reserve a backend that gave up on restarting")
+	}
+
+	defer ReleaseBackend(backendID)
+
+	if _, ok := GetRunningBackend(backendID); ok {
+		t.Fatal("crash-looping backend is still running")
+	}
+
+	if runtime.isRuntimeRunning {
+		t.Fatal("crash-looping backend wasn't stopped")
+	}
+}
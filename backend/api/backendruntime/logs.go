@@ -0,0 +1,192 @@
+package backendruntime
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// How many lines a follower can fall behind by before lines start getting dropped for it
+const logSubscriberBuffer = 256
+
+// Level prefixes that charmbracelet/log writes, which is what backends log with
+var logLevels = map[string]string{
+	"DEBU": "debug",
+	"INFO": "info",
+	"WARN": "warn",
+	"ERRO": "error",
+	"FATA": "fatal",
+}
+
+// Keeps the last LogLines lines that a backend wrote, and passes new lines on to anyone following them
+type logBuffer struct {
+	lock sync.Mutex
+
+	lines     []*LogLine
+	nextIndex int
+	isFull    bool
+
+	// Text after the last newline, which gets finished by the next write
+	partialLine string
+
+	subscribers map[chan *LogLine]bool
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		lines:       make([]*LogLine, size),
+		subscribers: make(map[chan *LogLine]bool),
+	}
+}
+
+func parseLogLine(rawLine string) *LogLine {
+	logLine := &LogLine{
+		Time:    time.Now(),
+		Level:   "info",
+		Message: rawLine,
+	}
+
+	// Lines look like "2006/01/02 15:04:05 INFO message", but the timestamp is optional
+	fields := strings.Fields(rawLine)
+
+	for fieldIndex, field := range fields {
+		if fieldIndex > 2 {
+			break
+		}
+
+		if level, ok := logLevels[field]; ok {
+			logLine.Level = level
+			logLine.Message = strings.TrimSpace(strings.SplitN(rawLine, field, 2)[1])
+
+			break
+		}
+	}
+
+	return logLine
+}
+
+func (buffer *logBuffer) add(logLine *LogLine) {
+	if len(buffer.lines) != 0 {
+		buffer.lines[buffer.nextIndex] = logLine
+		buffer.nextIndex = (buffer.nextIndex + 1) % len(buffer.lines)
+
+		if buffer.nextIndex == 0 {
+			buffer.isFull = true
+		}
+	}
+
+	for subscriber := range buffer.subscribers {
+		select {
+		case subscriber <- logLine:
+		default:
+		}
+	}
+}
+
+func (buffer *logBuffer) write(data string) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	logSplit := strings.Split(buffer.partialLine+data, "\n")
+	buffer.partialLine = logSplit[len(logSplit)-1]
+
+	for _, rawLine := range logSplit[:len(logSplit)-1] {
+		rawLine = strings.TrimRight(rawLine, "\r")
+
+		if rawLine == "" {
+			continue
+		}
+
+		if isDevelopmentMode {
+			log.Debug("spawned backend logs: " + rawLine)
+		}
+
+		buffer.add(parseLogLine(rawLine))
+	}
+}
+
+func (buffer *logBuffer) get(since time.Time, tail int) []*LogLine {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	return buffer.getLines(since, tail)
+}
+
+func (buffer *logBuffer) getLines(since time.Time, tail int) []*LogLine {
+	var orderedLines []*LogLine
+
+	if buffer.isFull {
+		orderedLines = append(orderedLines, buffer.lines[buffer.nextIndex:]...)
+	}
+
+	orderedLines = append(orderedLines, buffer.lines[:buffer.nextIndex]...)
+	logLines := []*LogLine{}
+
+	for _, logLine := range orderedLines {
+		if since.IsZero() || logLine.Time.After(since) {
+			logLines = append(logLines, logLine)
+		}
+	}
+
+	if tail != 0 && len(logLines) > tail {
+		logLines = logLines[len(logLines)-tail:]
+	}
+
+	return logLines
+}
+
+// Gets the lines that are already buffered (like get), and subscribes to new ones at the same time, so that nothing
+// gets missed or sent twice in between
+func (buffer *logBuffer) subscribe(since time.Time, tail int) ([]*LogLine, chan *LogLine) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	logLines := buffer.getLines(since, tail)
+	subscriber := make(chan *LogLine, logSubscriberBuffer)
+	buffer.subscribers[subscriber] = true
+
+	return logLines, subscriber
+}
+
+func (buffer *logBuffer) unsubscribe(subscriber chan *LogLine) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	if _, ok := buffer.subscribers[subscriber]; ok {
+		delete(buffer.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+func (buffer *logBuffer) unsubscribeAll() {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	for subscriber := range buffer.subscribers {
+		delete(buffer.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// Gets the lines that the backend wrote after since (if it isn't zero), limited to the last tail lines (if it isn't 0)
+func (runtime *Runtime) GetLogs(since time.Time, tail int) []*LogLine {
+	return runtime.logs.get(since, tail)
+}
+
+// Like GetLogs, but also returns a channel that gets every new line. It gets closed once the runtime is stopped, or
+// StopFollowingLogs is called.
+func (runtime *Runtime) FollowLogs(since time.Time, tail int) ([]*LogLine, chan *LogLine) {
+	return runtime.logs.subscribe(since, tail)
+}
+
+func (runtime *Runtime) StopFollowingLogs(subscriber chan *LogLine) {
+	runtime.logs.unsubscribe(subscriber)
+}
@@ -6,8 +6,6 @@ import (
 	"net"
 	"os"
 	"os/exec"
-	"strings"
-	"sync"
 	"time"
 
 	"git.terah.dev/imterah/hermes/backend/backendlauncher"
@@ -15,35 +13,257 @@ import (
 	"github.com/charmbracelet/log"
 )
 
-func handleCommand(command interface{}, sock net.Conn, rtcChan chan interface{}) error {
-	bytes, err := commonbackend.Marshal(command)
+// How long a backend has to send its protocol version after connecting, before it is assumed to be too old
+const protocolHandshakeTimeout = 5 * time.Second
+
+// How long commands wait for the backend to (re)connect before giving up
+const connectionTimeout = 30 * time.Second
+
+// Exchanges Hello messages with the backend, which it sends as soon as it connects. Backends built before request IDs
+// were added don't send anything, so they get caught by the timeout.
+func handshake(sock net.Conn) (*commonbackend.Hello, error) {
+	if err := sock.SetReadDeadline(time.Now().Add(protocolHandshakeTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set read deadline: %s", err.Error())
+	}
+
+	_, message, err := commonbackend.Unmarshal(sock)
 
 	if err != nil {
-		log.Warnf("Failed to marshal message: %s", err.Error())
-		rtcChan <- fmt.Errorf("failed to marshal message: %s", err.Error())
+		return nil, fmt.Errorf("backend didn't send its protocol version (it is likely built for an older version of Hermes): %s", err.Error())
+	}
+
+	hello, ok := message.(*commonbackend.Hello)
 
-		return fmt.Errorf("failed to marshal message: %s", err.Error())
+	if !ok {
+		return nil, fmt.Errorf("backend sent %T instead of Hello", message)
 	}
 
-	if _, err := sock.Write(bytes); err != nil {
-		log.Warnf("Failed to write message: %s", err.Error())
-		rtcChan <- fmt.Errorf("failed to write message: %s", err.Error())
+	if hello.Version != commonbackend.CurrentProtocolVersion {
+		return nil, fmt.Errorf("backend speaks protocol version %d, but only version %d is supported", hello.Version, commonbackend.CurrentProtocolVersion)
+	}
 
-		return fmt.Errorf("failed to write message: %s", err.Error())
+	if err := sock.SetReadDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to clear read deadline: %s", err.Error())
 	}
 
-	data, err := commonbackend.Unmarshal(sock)
+	helloBytes, err := commonbackend.Marshal(0, &commonbackend.Hello{
+		Version: commonbackend.CurrentProtocolVersion,
+	})
 
 	if err != nil {
-		log.Warnf("Failed to unmarshal message: %s", err.Error())
-		rtcChan <- fmt.Errorf("failed to unmarshal message: %s", err.Error())
+		return nil, fmt.Errorf("failed to marshal Hello: %s", err.Error())
+	}
 
-		return fmt.Errorf("failed to unmarshal message: %s", err.Error())
+	if _, err := sock.Write(helloBytes); err != nil {
+		return nil, fmt.Errorf("failed to send Hello: %s", err.Error())
 	}
 
-	rtcChan <- data
+	return hello, nil
+}
+
+// Gives every pending request an error, ex. when the backend disconnects before responding
+func (runtime *Runtime) failPendingRequests(err error) {
+	runtime.pendingRequestsLock.Lock()
+	defer runtime.pendingRequestsLock.Unlock()
 
-	return nil
+	for requestID, responseChannel := range runtime.pendingRequests {
+		responseChannel <- err
+		delete(runtime.pendingRequests, requestID)
+	}
+}
+
+// Sets the current connection, and wakes up everything that is waiting for one
+func (runtime *Runtime) setConnection(sock net.Conn) {
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	runtime.connection = sock
+
+	select {
+	case <-runtime.connectionReady:
+	default:
+		close(runtime.connectionReady)
+	}
+
+	if sock == nil {
+		runtime.connectionReady = make(chan struct{})
+	}
+}
+
+// Checks if the process crashed too often, and won't be restarted anymore
+func (runtime *Runtime) IsCrashLooping() bool {
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	return runtime.isCrashLooping
+}
+
+// Gets a short description of what the backend is doing, for showing to users
+func (runtime *Runtime) GetStatus() string {
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	switch {
+	case runtime.isCrashLooping:
+		return "crash-looping"
+	case runtime.connectionError != nil:
+		return "incompatible"
+	case runtime.connection != nil:
+		return "running"
+	case runtime.crashCount != 0:
+		return "restarting"
+	default:
+		return "starting"
+	}
+}
+
+func (runtime *Runtime) GetStats() *Stats {
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	return &Stats{
+		IsBackendRunning:     runtime.isBackendRunning,
+		BackendStatusMessage: runtime.backendStatusMessage,
+		Latency:              runtime.latency,
+
+		CrashCount:     runtime.crashCount,
+		LastExitCode:   runtime.lastExitCode,
+		LastExitReason: runtime.lastExitReason,
+		LastCrashAt:    runtime.lastCrashAt,
+	}
+}
+
+func (runtime *Runtime) getConnection() net.Conn {
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	return runtime.connection
+}
+
+// Reads responses from the backend, and hands them to whoever sent the request, until the connection is closed
+func (runtime *Runtime) handleConnection(sock net.Conn) {
+	for {
+		requestID, response, err := commonbackend.Unmarshal(sock)
+
+		if err != nil {
+			if runtime.isRuntimeRunning {
+				log.Warnf("Failed to unmarshal message: %s", err.Error())
+			}
+
+			break
+		}
+
+		runtime.pendingRequestsLock.Lock()
+		responseChannel, ok := runtime.pendingRequests[requestID]
+		delete(runtime.pendingRequests, requestID)
+		runtime.pendingRequestsLock.Unlock()
+
+		if !ok {
+			log.Warnf("Got response for unknown request #%d from backend: %T", requestID, response)
+			continue
+		}
+
+		responseChannel <- response
+	}
+
+	runtime.setConnection(nil)
+	sock.Close()
+
+	runtime.failPendingRequests(fmt.Errorf("backend disconnected before responding"))
+}
+
+// Kills the backend process, so that it gets started again
+func (runtime *Runtime) restartProcess(sock net.Conn, reason string) {
+	runtime.connectionLock.Lock()
+	runtime.restartReason = reason
+	runtime.connectionLock.Unlock()
+
+	if runtime.currentProcess != nil && runtime.currentProcess.Process != nil {
+		if err := runtime.currentProcess.Process.Kill(); err != nil {
+			log.Warnf("Failed to kill backend process: %s", err.Error())
+		}
+	}
+
+	// Anything still waiting on the old process gets an error right away
+	if err := sock.Close(); err != nil {
+		log.Debugf("Failed to close socket: %s", err.Error())
+	}
+}
+
+func (runtime *Runtime) keepAlive(sock net.Conn) {
+	log.Debug("Setting up Hermes keepalive Goroutine")
+	hasFailedBackendRunningCheckAlready := false
+	missedPings := 0
+
+	for {
+		if !runtime.isRuntimeRunning || runtime.getConnection() != sock {
+			return
+		}
+
+		pingStartedAt := time.Now()
+		pingResponse, err := runtime.processCommand(&commonbackend.Ping{}, PingTimeout)
+
+		if err == nil {
+			if _, ok := pingResponse.(*commonbackend.Pong); !ok {
+				err = fmt.Errorf("got illegal response type: %T", pingResponse)
+			}
+		}
+
+		if err != nil {
+			missedPings++
+			log.Warnf("Backend missed a ping (%d in a row): %s", missedPings, err.Error())
+
+			if MaxMissedPings != 0 && missedPings >= MaxMissedPings {
+				log.Errorf("Backend missed %d pings in a row. Restarting it...", missedPings)
+				runtime.restartProcess(sock, fmt.Sprintf("missed %d pings in a row", missedPings))
+
+				return
+			}
+
+			time.Sleep(PingInterval)
+			continue
+		}
+
+		missedPings = 0
+
+		runtime.connectionLock.Lock()
+		runtime.latency = time.Since(pingStartedAt)
+		runtime.connectionLock.Unlock()
+
+		// The status isn't needed to know if the backend is alive, but it provides useful telemetry
+		statusResponse, err := runtime.processCommand(&commonbackend.BackendStatusRequest{}, PingTimeout)
+
+		if err != nil {
+			log.Warnf("Failed to get backend status (in backend keepalive): %s", err.Error())
+			time.Sleep(PingInterval)
+
+			continue
+		}
+
+		switch responseMessage := statusResponse.(type) {
+		case *commonbackend.BackendStatusResponse:
+			runtime.connectionLock.Lock()
+			runtime.isBackendRunning = responseMessage.IsRunning
+			runtime.backendStatusMessage = responseMessage.Message
+			runtime.connectionLock.Unlock()
+
+			if !responseMessage.IsRunning {
+				if hasFailedBackendRunningCheckAlready {
+					if responseMessage.Message != "" {
+						log.Warnf("Backend (in backend keepalive) is up but not active: %s", responseMessage.Message)
+					} else {
+						log.Warnf("Backend (in backend keepalive) is up but not active")
+					}
+				}
+
+				hasFailedBackendRunningCheckAlready = true
+			}
+		default:
+			log.Errorf("Got illegal response type for backend (in backend keepalive): %T", responseMessage)
+		}
+
+		time.Sleep(PingInterval)
+	}
 }
 
 func (runtime *Runtime) goRoutineHandler() error {
@@ -74,7 +294,32 @@ func (runtime *Runtime) goRoutineHandler() error {
 				return
 			}
 
-			log.Debug("Recieved connection. Attempting to figure out backend state...")
+			log.Debug("Recieved connection. Exchanging Hello messages...")
+
+			hello, err := handshake(sock)
+
+			if err != nil {
+				log.Errorf("Refusing backend connection: %s", err.Error())
+
+				runtime.connectionLock.Lock()
+				runtime.connectionError = err
+				runtime.connectionLock.Unlock()
+
+				// Wakes up anything waiting for the connection, so that it sees the error
+				runtime.setConnection(nil)
+
+				sock.Close()
+				continue
+			}
+
+			// An earlier connection may have been refused (ex. the backend was too slow to start), which doesn't matter
+			// anymore now that this one succeeded
+			runtime.connectionLock.Lock()
+			runtime.connectionError = nil
+			runtime.capabilities = hello.Capabilities
+			runtime.connectionLock.Unlock()
+
+			log.Debug("Attempting to figure out backend state...")
 
 			timeoutChannel := time.After(500 * time.Millisecond)
 
@@ -93,93 +338,15 @@ func (runtime *Runtime) goRoutineHandler() error {
 						log.Debug("We have restarted. Running the restart callback...")
 						runtime.OnCrashCallback(sock)
 					}
-
-					log.Debug("Clearing caches...")
-					runtime.cleanUpPendingCommandProcessingJobs()
-					runtime.messageBufferLock = sync.Mutex{}
 				} else {
 					log.Debug("We have not restarted.")
 				}
 			}
 
-			go func() {
-				log.Debug("Setting up Hermes keepalive Goroutine")
-				hasFailedBackendRunningCheckAlready := false
-
-				for {
-					if !runtime.isRuntimeRunning {
-						return
-					}
-
-					// Asking for the backend status seems to be a "good-enough" keepalive system. Plus, it provides useful telemetry.
-					// There isn't a ping command in the backend API, so we have to make do with what we have.
-					//
-					// To be safe here, we have to use the proper (yet annoying) facilities to prevent cross-talk, since we're in
-					// a goroutine, and can't talk directly. This actually has benefits, as the OuterLoop should exit on its own, if we
-					// encounter a critical error.
-					statusResponse, err := runtime.ProcessCommand(&commonbackend.BackendStatusRequest{})
-
-					if err != nil {
-						log.Warnf("Failed to get response for backend (in backend runtime keep alive): %s", err.Error())
-						log.Debugf("Attempting to close socket...")
-						err := sock.Close()
-
-						if err != nil {
-							log.Debugf("Failed to close socket: %s", err.Error())
-						}
-
-						continue
-					}
-
-					switch responseMessage := statusResponse.(type) {
-					case *commonbackend.BackendStatusResponse:
-						if !responseMessage.IsRunning {
-							if hasFailedBackendRunningCheckAlready {
-								if responseMessage.Message != "" {
-									log.Warnf("Backend (in backend keepalive) is up but not active: %s", responseMessage.Message)
-								} else {
-									log.Warnf("Backend (in backend keepalive) is up but not active")
-								}
-							}
-
-							hasFailedBackendRunningCheckAlready = true
-						}
-					default:
-						log.Errorf("Got illegal response type for backend (in backend keepalive): %T", responseMessage)
-						log.Debugf("Attempting to close socket...")
-						err := sock.Close()
-
-						if err != nil {
-							log.Debugf("Failed to close socket: %s", err.Error())
-						}
-					}
-
-					time.Sleep(5 * time.Second)
-				}
-			}()
-
-		OuterLoop:
-			for {
-				for chanIndex, messageData := range runtime.messageBuffer {
-					if messageData == nil {
-						continue
-					}
-
-					err := handleCommand(messageData.Message, sock, messageData.Channel)
+			runtime.setConnection(sock)
 
-					if err != nil {
-						log.Warnf("failed to handle command in backend runtime instance: %s", err.Error())
-
-						if strings.HasPrefix(err.Error(), "failed to write message") {
-							break OuterLoop
-						}
-					}
-
-					runtime.messageBuffer[chanIndex] = nil
-				}
-			}
-
-			sock.Close()
+			go runtime.keepAlive(sock)
+			runtime.handleConnection(sock)
 		}
 	}()
 
@@ -193,20 +360,32 @@ func (runtime *Runtime) goRoutineHandler() error {
 		runtime.currentProcess = exec.CommandContext(ctx, runtime.ProcessPath)
 		runtime.currentProcess.Env = append(runtime.currentProcess.Env, fmt.Sprintf("HERMES_API_SOCK=%s", sockPath), fmt.Sprintf("HERMES_LOG_LEVEL=%s", logLevel))
 
+		// Used by the SSH backends to check which SSH agents they can use
+		runtime.currentProcess.Env = append(runtime.currentProcess.Env, fmt.Sprintf("HERMES_SSH_AGENT_SOCKETS=%s", os.Getenv("HERMES_SSH_AGENT_SOCKETS")))
+
 		runtime.currentProcess.Stdout = runtime.logger
 		runtime.currentProcess.Stderr = runtime.logger
 
 		err := runtime.currentProcess.Run()
 
+		exitCode := -1
+		var exitReason string
+
 		if err != nil {
-			if err, ok := err.(*exec.ExitError); ok {
-				if err.ExitCode() != -1 && err.ExitCode() != 0 {
-					log.Warnf("A backend process died with exit code '%d' and with error '%s'", err.ExitCode(), err.Error())
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				exitCode = exitErr.ExitCode()
+				exitReason = exitErr.Error()
+
+				if exitErr.ExitCode() != -1 && exitErr.ExitCode() != 0 {
+					log.Warnf("A backend process died with exit code '%d' and with error '%s'", exitErr.ExitCode(), exitErr.Error())
 				}
 			} else {
+				exitReason = err.Error()
 				log.Warnf("A backend process died with error: %s", err.Error())
 			}
 		} else {
+			exitCode = 0
+			exitReason = "exited"
 			log.Debug("Process exited gracefully.")
 		}
 
@@ -214,26 +393,107 @@ func (runtime *Runtime) goRoutineHandler() error {
 			return nil
 		}
 
-		log.Debug("Sleeping 5 seconds, and then restarting process")
-		time.Sleep(5 * time.Second)
+		runtime.connectionLock.Lock()
+
+		if runtime.restartReason != "" {
+			exitReason = runtime.restartReason
+			runtime.restartReason = ""
+		}
 
-		// NOTE(imterah): This could cause hangs if we're not careful. If the process dies so much that we can't keep up, it should deserve to be hung, really.
-		// There's probably a better way to do this, but this works.
-		//
-		// If this does turn out to be a problem, just increase the Goroutine buffer size.
-		runtime.processRestartNotification <- true
+		runtime.connectionLock.Unlock()
+
+		restartDelay, shouldRestart := runtime.recordCrash(exitCode, exitReason)
+
+		if !shouldRestart {
+			log.Errorf("A backend process has crashed %d times in %s. Giving up on restarting it (last exit reason: %s)", len(runtime.recentCrashes), RestartWindow, exitReason)
+
+			runtime.connectionLock.Lock()
+			runtime.connectionError = fmt.Errorf("backend keeps crashing (last exit reason: %s)", exitReason)
+			runtime.connectionLock.Unlock()
+
+			// Wakes up anything waiting for the connection, so that it sees the error
+			runtime.setConnection(nil)
+
+			return nil
+		}
+
+		log.Debugf("Sleeping %s, and then restarting process", restartDelay)
+		time.Sleep(restartDelay)
+
+		// The runtime could've been stopped while we were sleeping
+		if !runtime.isRuntimeRunning {
+			return nil
+		}
+
+		select {
+		case runtime.processRestartNotification <- true:
+		default:
+			// The process crashed before it connected, so the last notification hasn't been picked up yet. That one is
+			// enough, as the connection handler only needs to know if it has to reinitialize the backend.
+		}
 
 		log.Debug("Sent off notification.")
 	}
 }
 
+// Records that the process has crashed, and works out how long to wait before starting it again. If it has crashed too
+// often, it shouldn't be started again at all.
+func (runtime *Runtime) recordCrash(exitCode int, exitReason string) (time.Duration, bool) {
+	now := time.Now()
+
+	runtime.connectionLock.Lock()
+	defer runtime.connectionLock.Unlock()
+
+	runtime.crashCount++
+	runtime.lastExitCode = exitCode
+	runtime.lastExitReason = exitReason
+	runtime.lastCrashAt = now
+
+	recentCrashes := []time.Time{now}
+
+	for _, crashedAt := range runtime.recentCrashes {
+		if now.Sub(crashedAt) < RestartWindow {
+			recentCrashes = append(recentCrashes, crashedAt)
+		}
+	}
+
+	runtime.recentCrashes = recentCrashes
+
+	if MaxRestarts != 0 && len(recentCrashes) > MaxRestarts {
+		runtime.isCrashLooping = true
+		return 0, false
+	}
+
+	restartDelay := RestartBackoff
+
+	for crashIndex := 1; crashIndex < len(recentCrashes) && restartDelay < MaxRestartBackoff; crashIndex++ {
+		restartDelay *= 2
+	}
+
+	return min(restartDelay, MaxRestartBackoff), true
+}
+
 func (runtime *Runtime) Start() error {
 	if runtime.isRuntimeRunning {
 		return fmt.Errorf("runtime already running")
 	}
 
-	runtime.messageBuffer = make([]*messageForBuf, 10)
-	runtime.messageBufferLock = sync.Mutex{}
+	runtime.pendingRequests = make(map[uint32]chan interface{})
+
+	runtime.connectionLock.Lock()
+	runtime.connection = nil
+	runtime.connectionReady = make(chan struct{})
+	runtime.connectionError = nil
+	runtime.capabilities = 0
+	runtime.crashCount = 0
+	runtime.lastExitCode = 0
+	runtime.lastExitReason = ""
+	runtime.lastCrashAt = time.Time{}
+	runtime.isCrashLooping = false
+	runtime.restartReason = ""
+	runtime.connectionLock.Unlock()
+
+	runtime.recentCrashes = nil
 
 	runtime.processRestartNotification = make(chan bool, 1)
 
@@ -259,8 +519,11 @@ func (runtime *Runtime) Stop() error {
 	}
 
 	runtime.isRuntimeRunning = false
+	runtime.logs.unsubscribeAll()
 
-	if runtime.currentProcess != nil && runtime.currentProcess.Cancel != nil {
+	if runtime.currentProcess != nil && runtime.currentProcess.ProcessState != nil {
+		log.Debug("Process has already exited, so there is nothing to kill")
+	} else if runtime.currentProcess != nil && runtime.currentProcess.Process != nil && runtime.currentProcess.Cancel != nil {
 		err := runtime.currentProcess.Cancel()
 
 		if err != nil {
@@ -283,89 +546,117 @@ func (runtime *Runtime) Stop() error {
 	return nil
 }
 
-func (runtime *Runtime) ProcessCommand(command interface{}) (interface{}, error) {
-	schedulingAttempts := 0
-	var commandChannel chan interface{}
+// Waits for the backend to be connected, in case it is still starting up (or restarting)
+func (runtime *Runtime) waitForConnection() (net.Conn, error) {
+	timeoutChannel := time.After(connectionTimeout)
 
-SchedulingLoop:
 	for {
 		if !runtime.isRuntimeRunning {
-			time.Sleep(10 * time.Millisecond)
+			return nil, fmt.Errorf("runtime not running")
 		}
 
-		if schedulingAttempts > 50 {
-			return nil, fmt.Errorf("failed to schedule message transmission after 50 tries (REPORT THIS ISSUE)")
+		runtime.connectionLock.Lock()
+		sock, connectionReady, connectionError := runtime.connection, runtime.connectionReady, runtime.connectionError
+		runtime.connectionLock.Unlock()
+
+		if connectionError != nil {
+			return nil, connectionError
 		}
 
-		runtime.messageBufferLock.Lock()
+		if sock != nil {
+			return sock, nil
+		}
 
-		// Attempt to find spot in buffer to schedule message transmission
-		for i, message := range runtime.messageBuffer {
-			if message != nil {
-				continue
-			}
+		select {
+		case <-connectionReady:
+		case <-timeoutChannel:
+			return nil, fmt.Errorf("timed out waiting for the backend to connect")
+		}
+	}
+}
 
-			commandChannel = make(chan interface{})
+// Sends a command to the backend, and waits for its response (up to CommandTimeout). This is safe to call from many
+// goroutines at once, as every request is tagged with its own ID.
+func (runtime *Runtime) ProcessCommand(command interface{}) (interface{}, error) {
+	return runtime.processCommand(command, CommandTimeout)
+}
 
-			runtime.messageBuffer[i] = &messageForBuf{
-				Channel: commandChannel,
-				Message: command,
-			}
+// Same as ProcessCommand, but gives up after the timeout (if it isn't 0)
+func (runtime *Runtime) processCommand(command interface{}, timeout time.Duration) (interface{}, error) {
+	sock, err := runtime.waitForConnection()
 
-			runtime.messageBufferLock.Unlock()
-			break SchedulingLoop
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		runtime.messageBufferLock.Unlock()
-		time.Sleep(100 * time.Millisecond)
+	responseChannel := make(chan interface{}, 1)
 
-		schedulingAttempts++
-	}
+	runtime.pendingRequestsLock.Lock()
 
-	// Fetch response and close Channel
-	response, ok := <-commandChannel
+	runtime.lastRequestID++
 
-	if !ok {
-		return nil, fmt.Errorf("failed to read from command channel: recieved signal that is not OK")
+	// Request ID 0 is used for messages that aren't responses to anything (ex. Hello)
+	if runtime.lastRequestID == 0 {
+		runtime.lastRequestID++
 	}
 
-	close(commandChannel)
+	requestID := runtime.lastRequestID
+	runtime.pendingRequests[requestID] = responseChannel
 
-	err, ok := response.(error)
+	runtime.pendingRequestsLock.Unlock()
 
-	if ok {
-		return nil, err
+	bytes, err := commonbackend.Marshal(requestID, command)
+
+	if err != nil {
+		runtime.removePendingRequest(requestID)
+		return nil, fmt.Errorf("failed to marshal message: %s", err.Error())
 	}
 
-	return response, nil
-}
+	runtime.writeLock.Lock()
+	_, err = sock.Write(bytes)
+	runtime.writeLock.Unlock()
 
-func (runtime *Runtime) cleanUpPendingCommandProcessingJobs() {
-	for messageIndex, message := range runtime.messageBuffer {
-		if message == nil {
-			continue
-		}
+	if err != nil {
+		runtime.removePendingRequest(requestID)
+		return nil, fmt.Errorf("failed to write message: %s", err.Error())
+	}
 
-		timeoutChannel := time.After(100 * time.Millisecond)
+	var timeoutChannel <-chan time.Time
 
-		select {
-		case <-timeoutChannel:
-			log.Warn("Message channel is likely running (timed out reading from it without an error)")
-			close(message.Channel)
-		case _, ok := <-message.Channel:
-			if ok {
-				log.Warn("Message channel is running, but should be stopped (since message is NOT nil!)")
-				close(message.Channel)
-			}
-		}
+	if timeout != 0 {
+		timeoutChannel = time.After(timeout)
+	}
+
+	var response interface{}
+
+	select {
+	case response = <-responseChannel:
+	case <-timeoutChannel:
+		runtime.removePendingRequest(requestID)
+		return nil, fmt.Errorf("timed out waiting for response")
+	}
 
-		runtime.messageBuffer[messageIndex] = nil
+	switch response := response.(type) {
+	case error:
+		return nil, response
+	case *commonbackend.ErrorResponse:
+		return nil, fmt.Errorf("backend failed to handle command: %s", response.Message)
 	}
+
+	return response, nil
+}
+
+func (runtime *Runtime) removePendingRequest(requestID uint32) {
+	runtime.pendingRequestsLock.Lock()
+	defer runtime.pendingRequestsLock.Unlock()
+
+	delete(runtime.pendingRequests, requestID)
 }
 
 func NewBackend(path string) *Runtime {
 	return &Runtime{
 		ProcessPath: path,
+		logs:        newLogBuffer(LogLines),
 	}
 }
 
@@ -377,6 +668,10 @@ func Init(backends []*Backend) error {
 		return err
 	}
 
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
 	AvailableBackends = backends
 
 	return nil
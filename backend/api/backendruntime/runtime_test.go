@@ -0,0 +1,273 @@
+package backendruntime
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/commonbackend"
+)
+
+// How long the tests wait for responses, so that a broken runtime fails the test instead of hanging it
+const testTimeout = 5 * time.Second
+
+// Creates a runtime that is connected to one end of a pipe, without starting a backend process. The other end acts as
+// the backend.
+func newTestRuntime(t *testing.T) (*Runtime, net.Conn) {
+	apiSock, backendSock := net.Pipe()
+
+	runtime := &Runtime{
+		isRuntimeRunning: true,
+		pendingRequests:  make(map[uint32]chan interface{}),
+		connectionReady:  make(chan struct{}),
+	}
+
+	// Keeps the tests from hanging if the runtime never sends or responds to anything
+	if err := backendSock.SetDeadline(time.Now().Add(testTimeout)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	runtime.setConnection(apiSock)
+	go runtime.handleConnection(apiSock)
+
+	t.Cleanup(func() {
+		backendSock.Close()
+	})
+
+	return runtime, backendSock
+}
+
+func writeTestMessage(sock net.Conn, requestID uint32, message interface{}) error {
+	messageMarshalled, err := commonbackend.Marshal(requestID, message)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = sock.Write(messageMarshalled)
+	return err
+}
+
+func TestOutOfOrderResponses(t *testing.T) {
+	runtime, backendSock := newTestRuntime(t)
+
+	const requestCount = 3
+
+	var waitGroup sync.WaitGroup
+	errors := make(chan error, requestCount)
+
+	for requestIndex := 0; requestIndex < requestCount; requestIndex++ {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			arguments := fmt.Sprintf("request %d", requestIndex)
+
+			response, err := runtime.processCommand(&commonbackend.CheckServerParameters{
+				Arguments: []byte(arguments),
+			}, testTimeout)
+
+			if err != nil {
+				errors <- err
+				return
+			}
+
+			checkResponse, ok := response.(*commonbackend.CheckParametersResponse)
+
+			if !ok {
+				errors <- fmt.Errorf("got illegal response type: %T", response)
+				return
+			}
+
+			if checkResponse.Message != arguments {
+				errors <- fmt.Errorf("got response for '%s' instead of '%s'", checkResponse.Message, arguments)
+			}
+		}()
+	}
+
+	requestIDs := []uint32{}
+	requestArguments := []string{}
+
+	for len(requestIDs) < requestCount {
+		requestID, commandRaw, err := commonbackend.Unmarshal(backendSock)
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		command, ok := commandRaw.(*commonbackend.CheckServerParameters)
+
+		if !ok {
+			t.Fatalf("got illegal command type: %T", commandRaw)
+		}
+
+		requestIDs = append(requestIDs, requestID)
+		requestArguments = append(requestArguments, string(command.Arguments))
+	}
+
+	// Respond in the opposite order that the requests came in
+	for requestIndex := requestCount - 1; requestIndex >= 0; requestIndex-- {
+		err := writeTestMessage(backendSock, requestIDs[requestIndex], &commonbackend.CheckParametersResponse{
+			InResponseTo: "checkServerParameters",
+			IsValid:      true,
+			Message:      requestArguments[requestIndex],
+		})
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	waitGroup.Wait()
+	close(errors)
+
+	for err := range errors {
+		t.Error(err.Error())
+	}
+}
+
+func TestPendingRequestsFailOnDisconnect(t *testing.T) {
+	runtime, backendSock := newTestRuntime(t)
+
+	go func() {
+		// Wait for the request to come in, so that it is pending, and disconnect without responding
+		commonbackend.Unmarshal(backendSock)
+		backendSock.Close()
+	}()
+
+	_, err := runtime.processCommand(&commonbackend.BackendStatusRequest{}, testTimeout)
+
+	if err == nil {
+		t.Fatal("request succeeded, even though the backend disconnected")
+	}
+
+	if !strings.Contains(err.Error(), "disconnected") {
+		t.Fatalf("got unexpected error: %s", err.Error())
+	}
+}
+
+func TestRequestIDSkipsZero(t *testing.T) {
+	runtime, backendSock := newTestRuntime(t)
+	runtime.lastRequestID = math.MaxUint32
+
+	go func() {
+		requestID, _, err := commonbackend.Unmarshal(backendSock)
+
+		if err != nil {
+			return
+		}
+
+		if requestID == 0 {
+			// Nobody is waiting on request ID 0, so the request would time out
+			t.Error("request ID 0 was used")
+			return
+		}
+
+		if err := writeTestMessage(backendSock, requestID, &commonbackend.Pong{}); err != nil {
+			t.Error(err.Error())
+		}
+	}()
+
+	if _, err := runtime.processCommand(&commonbackend.Ping{}, testTimeout); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if runtime.lastRequestID != 1 {
+		t.Fatalf("expected request ID to wrap around to 1, got %d", runtime.lastRequestID)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	runtime, backendSock := newTestRuntime(t)
+
+	go func() {
+		requestID, _, err := commonbackend.Unmarshal(backendSock)
+
+		if err != nil {
+			return
+		}
+
+		err = writeTestMessage(backendSock, requestID, &commonbackend.ErrorResponse{
+			Message: "unsupported command",
+		})
+
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}()
+
+	_, err := runtime.processCommand(&commonbackend.BackendStatusRequest{}, testTimeout)
+
+	if err == nil || !strings.Contains(err.Error(), "unsupported command") {
+		t.Fatalf("expected the error from the backend, got: %v", err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	apiSock, backendSock := net.Pipe()
+	defer apiSock.Close()
+	defer backendSock.Close()
+
+	go func() {
+		err := writeTestMessage(backendSock, 0, &commonbackend.Hello{
+			Version:      commonbackend.CurrentProtocolVersion,
+			Capabilities: commonbackend.CapabilityTCP,
+		})
+
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		// The API responds with its own Hello
+		commonbackend.Unmarshal(backendSock)
+	}()
+
+	hello, err := handshake(apiSock)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if hello.Capabilities != commonbackend.CapabilityTCP {
+		t.Fatalf("got wrong capabilities: %d", hello.Capabilities)
+	}
+}
+
+func TestHandshakeRejectsOldVersion(t *testing.T) {
+	apiSock, backendSock := net.Pipe()
+	defer apiSock.Close()
+	defer backendSock.Close()
+
+	go writeTestMessage(backendSock, 0, &commonbackend.Hello{
+		Version: commonbackend.CurrentProtocolVersion - 1,
+	})
+
+	if _, err := handshake(apiSock); err == nil {
+		t.Fatal("handshake succeeded with an old protocol version")
+	}
+}
+
+func TestHandshakeRejectsOldBackend(t *testing.T) {
+	t.Parallel()
+
+	apiSock, backendSock := net.Pipe()
+	defer apiSock.Close()
+	defer backendSock.Close()
+
+	// Backends from before the handshake existed wait for the API to send something first, so they never send Hello
+	_, err := handshake(apiSock)
+
+	if err == nil {
+		t.Fatal("handshake succeeded without the backend sending Hello")
+	}
+
+	if !strings.Contains(err.Error(), "older version") {
+		t.Fatalf("got unexpected error: %s", err.Error())
+	}
+}
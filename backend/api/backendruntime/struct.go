@@ -3,10 +3,8 @@ package backendruntime
 import (
 	"net"
 	"os/exec"
-	"strings"
 	"sync"
-
-	"github.com/charmbracelet/log"
+	"time"
 )
 
 type Backend struct {
@@ -14,11 +12,6 @@ type Backend struct {
 	Path string `validate:"required"`
 }
 
-type messageForBuf struct {
-	Channel chan interface{}
-	Message interface{}
-}
-
 type Runtime struct {
 	isRuntimeRunning           bool
 	logger                     *writeLogger
@@ -26,33 +19,71 @@ type Runtime struct {
 	currentListener            net.Listener
 	processRestartNotification chan bool
 
-	messageBufferLock sync.Mutex
-	messageBuffer     []*messageForBuf
+	// Requests that are waiting for a response from the backend, by request ID
+	pendingRequestsLock sync.Mutex
+	pendingRequests     map[uint32]chan interface{}
+	lastRequestID       uint32
+
+	// Current connection to the backend. It is nil while the backend isn't connected, and connectionReady gets closed
+	// once it is.
+	connectionLock  sync.Mutex
+	connection      net.Conn
+	connectionReady chan struct{}
+	writeLock       sync.Mutex
+
+	// Set if the backend can't be talked to anymore (ex. it speaks a protocol version that we can't use, or it keeps
+	// crashing)
+	connectionError error
+
+	// Why the process is being restarted, if we're the ones restarting it. Guarded by connectionLock, as it is set by the
+	// keepalive loop.
+	restartReason string
+	recentCrashes []time.Time
 
 	ProcessPath string
-	Logs        []string
+
+	// Last LogLines lines that the backend process wrote. It is kept across restarts of the process.
+	logs *logBuffer
+
+	// Last status reported by the backend (ex. if it is reconnecting to something), and how long the last ping took to
+	// get a response. Both are updated by the keepalive loop, and guarded by connectionLock.
+	isBackendRunning     bool
+	backendStatusMessage string
+	latency              time.Duration
+
+	// Crash statistics, guarded by connectionLock. Restarts because of missed pings count as crashes too.
+	crashCount     int
+	lastExitCode   int
+	lastExitReason string
+	lastCrashAt    time.Time
+
+	// Set if the process crashed too often, and won't be restarted anymore
+	isCrashLooping bool
+
+	// What the backend supports (commonbackend.Capability* constants), as told to us in its Hello message. Guarded by
+	// connectionLock.
+	capabilities uint32
 
 	OnCrashCallback func(sock net.Conn)
 }
 
+// Copy of what is known about a running backend, for showing to users
+type Stats struct {
+	IsBackendRunning     bool
+	BackendStatusMessage string
+	Latency              time.Duration
+
+	CrashCount     int
+	LastExitCode   int
+	LastExitReason string
+	LastCrashAt    time.Time
+}
+
 type writeLogger struct {
 	Runtime *Runtime
 }
 
 func (writer writeLogger) Write(p []byte) (n int, err error) {
-	logSplit := strings.Split(string(p), "\n")
-
-	if isDevelopmentMode {
-		for _, logLine := range logSplit {
-			if logLine == "" {
-				continue
-			}
-
-			log.Debug("spawned backend logs: " + logLine)
-		}
-	}
-
-	writer.Runtime.Logs = append(writer.Runtime.Logs, logSplit...)
-
-	return len(p), err
+	writer.Runtime.logs.write(string(p))
+	return len(p), nil
 }
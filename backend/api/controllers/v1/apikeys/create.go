@@ -0,0 +1,147 @@
+package apikeys
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type APIKeyCreationRequest struct {
+	Token           string     `validate:"required" json:"token"`
+	Name            string     `validate:"required" json:"name"`
+	ExpiresAt       *time.Time `json:"expiresAt"`
+	PermissionNodes []string   `validate:"required,min=1" json:"permissions"`
+}
+
+func CreateAPIKey(c *gin.Context) {
+	var req APIKeyCreationRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "apikeys.create")
+	defer auditEntry.Finish()
+
+	// Otherwise, a leaked key could be used to make keys that outlive it
+	if user.APIKey != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "API keys can't be used to create other API keys",
+		})
+
+		return
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Expiry date is in the past",
+		})
+
+		return
+	}
+
+	apiKeyPermissions := make([]dbcore.APIKeyPermission, len(req.PermissionNodes))
+
+	for nodeIndex, node := range req.PermissionNodes {
+		if !permissions.IsValidPermissionNode(node) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Unknown permission node: %s", node),
+			})
+
+			return
+		}
+
+		// Keys can't do more than their users, but this makes it obvious up front
+		if !permissions.UserHasPermission(user, node) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("You don't have the permission you're trying to give the key: %s", node),
+			})
+
+			return
+		}
+
+		apiKeyPermissions[nodeIndex] = dbcore.APIKeyPermission{
+			PermissionNode: node,
+		}
+	}
+
+	key, keyHash, err := jwtcore.GenerateAPIKey()
+
+	if err != nil {
+		log.Warnf("Failed to generate API key: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to generate API key",
+		})
+
+		return
+	}
+
+	apiKey := &dbcore.APIKey{
+		UserID:      user.ID,
+		Name:        req.Name,
+		KeyHash:     keyHash,
+		KeyPrefix:   key[:len(jwtcore.APIKeyPrefix)+6],
+		ExpiresAt:   req.ExpiresAt,
+		Permissions: apiKeyPermissions,
+	}
+
+	if err := dbcore.DB.Create(apiKey).Error; err != nil {
+		log.Warnf("Failed to create API key: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add API key into database",
+		})
+
+		return
+	}
+
+	auditEntry.SetTarget(apiKey.ID)
+	auditEntry.SetAfter(audit.APIKeySnapshot(apiKey))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"id":      apiKey.ID,
+		"key":     key,
+	})
+}
@@ -0,0 +1,127 @@
+package apikeys
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type APIKeyLookupRequest struct {
+	Token string `validate:"required" json:"token"`
+	UID   *uint  `json:"userID"`
+}
+
+type SanitizedAPIKey struct {
+	ID          uint       `json:"id"`
+	UserID      uint       `json:"userID"`
+	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"keyPrefix"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	Permissions []string   `json:"permissions"`
+}
+
+type APIKeyLookupResponse struct {
+	Success bool               `json:"success"`
+	Data    []*SanitizedAPIKey `json:"data"`
+}
+
+func LookupAPIKey(c *gin.Context) {
+	var req APIKeyLookupRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	uid := user.ID
+
+	if req.UID != nil {
+		uid = *req.UID
+
+		if uid != user.ID && !permissions.UserHasPermission(user, "users.lookup") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Missing permissions",
+			})
+
+			return
+		}
+	}
+
+	apiKeys := []dbcore.APIKey{}
+
+	if err := dbcore.DB.Preload("Permissions").Where("user_id = ?", uid).Find(&apiKeys).Error; err != nil {
+		log.Warnf("Failed to get API keys: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get API keys",
+		})
+
+		return
+	}
+
+	sanitizedAPIKeys := make([]*SanitizedAPIKey, len(apiKeys))
+
+	for apiKeyIndex, apiKey := range apiKeys {
+		sanitizedAPIKey := &SanitizedAPIKey{
+			ID:          apiKey.ID,
+			UserID:      apiKey.UserID,
+			Name:        apiKey.Name,
+			KeyPrefix:   apiKey.KeyPrefix,
+			CreatedAt:   apiKey.CreatedAt,
+			ExpiresAt:   apiKey.ExpiresAt,
+			LastUsedAt:  apiKey.LastUsedAt,
+			Permissions: make([]string, len(apiKey.Permissions)),
+		}
+
+		for permissionIndex, permission := range apiKey.Permissions {
+			sanitizedAPIKey.Permissions[permissionIndex] = permission.PermissionNode
+		}
+
+		sanitizedAPIKeys[apiKeyIndex] = sanitizedAPIKey
+	}
+
+	c.JSON(http.StatusOK, &APIKeyLookupResponse{
+		Success: true,
+		Data:    sanitizedAPIKeys,
+	})
+}
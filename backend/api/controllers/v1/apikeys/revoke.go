@@ -0,0 +1,111 @@
+package apikeys
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type APIKeyRevokeRequest struct {
+	Token string `validate:"required" json:"token"`
+	ID    uint   `validate:"required" json:"id"`
+}
+
+func RevokeAPIKey(c *gin.Context) {
+	var req APIKeyRevokeRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "apikeys.revoke")
+	auditEntry.SetTarget(req.ID)
+	defer auditEntry.Finish()
+
+	var apiKey *dbcore.APIKey
+	apiKeyRequest := dbcore.DB.Preload("Permissions").Where("id = ?", req.ID).Find(&apiKey)
+
+	if apiKeyRequest.Error != nil {
+		log.Warnf("failed to find if API key exists or not: %s", apiKeyRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if API key exists",
+		})
+
+		return
+	}
+
+	apiKeyExists := apiKeyRequest.RowsAffected > 0
+
+	if !apiKeyExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "API key doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetBefore(audit.APIKeySnapshot(apiKey))
+
+	// Admins can kill the keys of other users, ex. if a bot's key leaks
+	if apiKey.UserID != user.ID && !permissions.UserHasPermission(user, "users.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if err := dbcore.DB.Select("Permissions").Delete(apiKey).Error; err != nil {
+		log.Warnf("Failed to revoke API key: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke API key",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
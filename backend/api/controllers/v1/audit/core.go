@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"gorm.io/gorm"
+)
+
+type AuditFilters struct {
+	UID       *uint      `json:"userID"`
+	APIKeyID  *uint      `json:"apiKeyID"`
+	SessionID *uint      `json:"sessionID"`
+	Action    *string    `json:"action"`
+	TargetID  *uint      `json:"targetID"`
+	Success   *bool      `json:"success"`
+	Since     *time.Time `json:"since"`
+	Until     *time.Time `json:"until"`
+}
+
+type SanitizedAuditEntry struct {
+	ID         uint                   `json:"id"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	UserID     uint                   `json:"userID"`
+	SessionID  *uint                  `json:"sessionID,omitempty"`
+	APIKeyID   *uint                  `json:"apiKeyID,omitempty"`
+	ClientIP   string                 `json:"clientIP"`
+	Action     string                 `json:"action"`
+	TargetID   *uint                  `json:"targetID,omitempty"`
+	Before     map[string]interface{} `json:"before,omitempty"`
+	After      map[string]interface{} `json:"after,omitempty"`
+	StatusCode int                    `json:"statusCode"`
+	Success    bool                   `json:"success"`
+}
+
+func (filters *AuditFilters) apply(query *gorm.DB) *gorm.DB {
+	if filters.UID != nil {
+		query = query.Where("user_id = ?", *filters.UID)
+	}
+
+	if filters.APIKeyID != nil {
+		query = query.Where("api_key_id = ?", *filters.APIKeyID)
+	}
+
+	if filters.SessionID != nil {
+		query = query.Where("session_id = ?", *filters.SessionID)
+	}
+
+	if filters.Action != nil {
+		query = query.Where("action = ?", *filters.Action)
+	}
+
+	if filters.TargetID != nil {
+		query = query.Where("target_id = ?", *filters.TargetID)
+	}
+
+	if filters.Success != nil {
+		query = query.Where("success = ?", *filters.Success)
+	}
+
+	if filters.Since != nil {
+		query = query.Where("created_at >= ?", *filters.Since)
+	}
+
+	if filters.Until != nil {
+		query = query.Where("created_at < ?", *filters.Until)
+	}
+
+	return query
+}
+
+func sanitizeAuditEntry(entry *dbcore.AuditEntry) *SanitizedAuditEntry {
+	sanitizedEntry := &SanitizedAuditEntry{
+		ID:         entry.ID,
+		CreatedAt:  entry.CreatedAt,
+		UserID:     entry.UserID,
+		SessionID:  entry.SessionID,
+		APIKeyID:   entry.APIKeyID,
+		ClientIP:   entry.ClientIP,
+		Action:     entry.Action,
+		TargetID:   entry.TargetID,
+		StatusCode: entry.StatusCode,
+		Success:    entry.Success,
+	}
+
+	// The snapshots are written by us, so failing to parse them would only lose the diff
+	if entry.Before != nil {
+		json.Unmarshal([]byte(*entry.Before), &sanitizedEntry.Before)
+	}
+
+	if entry.After != nil {
+		json.Unmarshal([]byte(*entry.After), &sanitizedEntry.After)
+	}
+
+	return sanitizedEntry
+}
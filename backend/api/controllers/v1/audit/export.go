@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type AuditExportRequest struct {
+	Token string `validate:"required" json:"token"`
+	AuditFilters
+}
+
+// Exports the audit log as JSON Lines (one entry per line, oldest first), so that it can be fed into other tools
+// without having to page through it.
+func ExportAuditLog(c *gin.Context) {
+	var req AuditExportRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	if !permissions.UserHasPermission(user, "audit.visible") {
+		if req.UID != nil && *req.UID != user.ID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Missing permissions",
+			})
+
+			return
+		}
+
+		req.UID = &user.ID
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	entries := []dbcore.AuditEntry{}
+
+	// The status has already been sent by the time anything can go wrong, so errors can only cut the export short
+	err = req.apply(dbcore.DB).Order("id asc").FindInBatches(&entries, 500, func(tx *gorm.DB, batch int) error {
+		for _, entry := range entries {
+			if err := encoder.Encode(sanitizeAuditEntry(&entry)); err != nil {
+				return err
+			}
+		}
+
+		c.Writer.Flush()
+		return nil
+	}).Error
+
+	if err != nil {
+		log.Warnf("Failed to export audit log: %s", err.Error())
+	}
+}
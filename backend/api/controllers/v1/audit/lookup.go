@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AuditLookupRequest struct {
+	Token string `validate:"required" json:"token"`
+	AuditFilters
+
+	Limit  *int `validate:"omitempty,min=1,max=1000" json:"limit"`
+	Offset *int `validate:"omitempty,min=0" json:"offset"`
+}
+
+type AuditLookupResponse struct {
+	Success bool                   `json:"success"`
+	Total   int64                  `json:"total"`
+	Data    []*SanitizedAuditEntry `json:"data"`
+}
+
+func LookupAuditLog(c *gin.Context) {
+	var req AuditLookupRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	// Without audit.visible, users can only look at what they've done themselves
+	if !permissions.UserHasPermission(user, "audit.visible") {
+		if req.UID != nil && *req.UID != user.ID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Missing permissions",
+			})
+
+			return
+		}
+
+		req.UID = &user.ID
+	}
+
+	limit := 100
+	offset := 0
+
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+
+	if req.Offset != nil {
+		offset = *req.Offset
+	}
+
+	var total int64
+
+	if err := req.apply(dbcore.DB.Model(&dbcore.AuditEntry{})).Count(&total).Error; err != nil {
+		log.Warnf("Failed to count audit log entries: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get audit log entries",
+		})
+
+		return
+	}
+
+	entries := []dbcore.AuditEntry{}
+
+	if err := req.apply(dbcore.DB).Order("id desc").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		log.Warnf("Failed to get audit log entries: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get audit log entries",
+		})
+
+		return
+	}
+
+	sanitizedEntries := make([]*SanitizedAuditEntry, len(entries))
+
+	for entryIndex, entry := range entries {
+		sanitizedEntries[entryIndex] = sanitizeAuditEntry(&entry)
+	}
+
+	c.JSON(http.StatusOK, &AuditLookupResponse{
+		Success: true,
+		Total:   total,
+		Data:    sanitizedEntries,
+	})
+}
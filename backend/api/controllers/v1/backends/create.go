@@ -4,8 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 
+	"git.terah.dev/imterah/hermes/backend/api/audit"
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
@@ -63,6 +65,9 @@ func CreateBackend(c *gin.Context) {
 		}
 	}
 
+	auditEntry := audit.Start(c, user, "backends.create")
+	defer auditEntry.Finish()
+
 	if !permissions.UserHasPermission(user, "backends.add") {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Missing permissions",
@@ -214,6 +219,16 @@ func CreateBackend(c *gin.Context) {
 		return
 	}
 
+	auditEntry.SetTarget(backendInDatabase.ID)
+	auditEntry.SetAfter(audit.BackendSnapshot(backendInDatabase))
+
+	// Without this, the backend wouldn't get initialized again if it has to be restarted
+	backendID := backendInDatabase.ID
+
+	backend.OnCrashCallback = func(conn net.Conn) {
+		onBackendCrash(backend, backendID, conn)
+	}
+
 	backendStartResponse, err := backend.ProcessCommand(&commonbackend.Start{
 		Arguments: backendParameters,
 	})
@@ -261,7 +276,26 @@ func CreateBackend(c *gin.Context) {
 		log.Warnf("Got illegal response type for backend: %T", responseMessage)
 	}
 
-	backendruntime.RunningBackends[backendInDatabase.ID] = backend
+	backendParametersResponse, err := backend.ProcessCommand(&commonbackend.BackendParametersRequest{})
+
+	if err != nil {
+		log.Warnf("Failed to get updated parameters for backend: %s", err.Error())
+	} else {
+		switch responseMessage := backendParametersResponse.(type) {
+		case *commonbackend.BackendParametersResponse:
+			if len(responseMessage.Arguments) != 0 {
+				backendInDatabase.BackendParameters = base64.StdEncoding.EncodeToString(responseMessage.Arguments)
+
+				if err := dbcore.DB.Save(backendInDatabase).Error; err != nil {
+					log.Warnf("Failed to save updated parameters for backend: %s", err.Error())
+				}
+			}
+		default:
+			log.Warnf("Got illegal response type for backend: %T", responseMessage)
+		}
+	}
+
+	backendruntime.SetRunningBackend(backendInDatabase.ID, backend)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
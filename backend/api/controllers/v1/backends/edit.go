@@ -0,0 +1,352 @@
+package backends
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"git.terah.dev/imterah/hermes/backend/commonbackend"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type BackendEditRequest struct {
+	Token             string      `validate:"required"`
+	BackendID         uint        `json:"id" validate:"required"`
+	Name              *string     `json:"name"`
+	Description       *string     `json:"description"`
+	BackendParameters interface{} `json:"connectionDetails"`
+}
+
+type BackendEditResponse struct {
+	Success bool                `json:"success"`
+	Proxies []*ProxyStartResult `json:"proxies"`
+}
+
+func EditBackend(c *gin.Context) {
+	var req BackendEditRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "backends.edit")
+	auditEntry.SetTarget(req.BackendID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "backends.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var backend *dbcore.Backend
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.BackendID).Find(&backend)
+
+	if backendRequest.Error != nil {
+		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if backend exists",
+		})
+
+		return
+	}
+
+	backendExists := backendRequest.RowsAffected > 0
+
+	if !backendExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetBefore(audit.BackendSnapshot(backend))
+
+	if !permissions.UserCanAccessBackend(user, backend, "backends.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if req.Name != nil {
+		backend.Name = *req.Name
+	}
+
+	if req.Description != nil {
+		backend.Description = req.Description
+	}
+
+	if req.BackendParameters == nil {
+		if err := dbcore.DB.Save(backend).Error; err != nil {
+			log.Warnf("Failed to update backend: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update backend",
+			})
+
+			return
+		}
+
+		auditEntry.SetAfter(audit.BackendSnapshot(backend))
+
+		c.JSON(http.StatusOK, &BackendEditResponse{
+			Success: true,
+			Proxies: []*ProxyStartResult{},
+		})
+
+		return
+	}
+
+	var backendParameters []byte
+
+	switch parameters := req.BackendParameters.(type) {
+	case string:
+		backendParameters = []byte(parameters)
+	case map[string]interface{}:
+		backendParameters, err = json.Marshal(parameters)
+
+		if err != nil {
+			log.Warnf("Failed to marshal JSON recieved as BackendParameters: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to prepare parameters",
+			})
+
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid type for connectionDetails (recieved %T)", parameters),
+		})
+
+		return
+	}
+
+	// Checking parameters doesn't depend on the state of the backend, so we can ask the running instance if there is
+	// one. If there isn't, we spin up a temporary one, like when creating a backend.
+	oldBackendInstance, isRunning := backendruntime.GetRunningBackend(backend.ID)
+	checkBackendInstance := oldBackendInstance
+
+	if !isRunning {
+		var backendRuntimeFilePath string
+
+		for _, runtime := range backendruntime.AvailableBackends {
+			if runtime.Name == backend.Backend {
+				backendRuntimeFilePath = runtime.Path
+			}
+		}
+
+		if backendRuntimeFilePath == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unsupported backend recieved",
+			})
+
+			return
+		}
+
+		checkBackendInstance = backendruntime.NewBackend(backendRuntimeFilePath)
+
+		if err := checkBackendInstance.Start(); err != nil {
+			log.Warnf("Failed to start backend: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to start backend",
+			})
+
+			return
+		}
+
+		defer func() {
+			if err := checkBackendInstance.Stop(); err != nil {
+				log.Warnf("Failed to stop backend: %s", err.Error())
+			}
+		}()
+	}
+
+	backendParamCheckResponse, err := checkBackendInstance.ProcessCommand(&commonbackend.CheckServerParameters{
+		Arguments: backendParameters,
+	})
+
+	if err != nil {
+		log.Warnf("Failed to get response for backend: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get status response from backend",
+		})
+
+		return
+	}
+
+	switch responseMessage := backendParamCheckResponse.(type) {
+	case *commonbackend.CheckParametersResponse:
+		if responseMessage.InResponseTo != "checkServerParameters" {
+			log.Errorf("Got illegal response to CheckServerParameters: %s", responseMessage.InResponseTo)
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to get status response from backend",
+			})
+
+			return
+		}
+
+		if !responseMessage.IsValid {
+			var errorMessage string
+
+			if responseMessage.Message == "" {
+				errorMessage = "Unkown error while trying to parse connectionDetails"
+			} else {
+				errorMessage = fmt.Sprintf("Invalid backend parameters: %s", responseMessage.Message)
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errorMessage,
+			})
+
+			return
+		}
+	default:
+		log.Warnf("Got illegal response type for backend: %T", responseMessage)
+	}
+
+	log.Info("Passed backend checks successfully")
+
+	oldBackendParameters := backend.BackendParameters
+	backend.BackendParameters = base64.StdEncoding.EncodeToString(backendParameters)
+
+	if err := dbcore.DB.Save(backend).Error; err != nil {
+		log.Warnf("Failed to update backend: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update backend",
+		})
+
+		return
+	}
+
+	auditEntry.SetAfter(audit.BackendSnapshot(backend))
+
+	// If the backend has been stopped on purpose, it should stay that way
+	if !isRunning && !backend.AutoStart {
+		c.JSON(http.StatusOK, &BackendEditResponse{
+			Success: true,
+			Proxies: []*ProxyStartResult{},
+		})
+
+		return
+	}
+
+	if isRunning {
+		log.Infof("Restarting backend #%d with its new parameters", backend.ID)
+
+		// Another request could've stopped it in the meantime, in which case there's nothing to stop
+		if oldBackendInstance, ok := backendruntime.RemoveRunningBackend(backend.ID); ok {
+			if err := oldBackendInstance.Stop(); err != nil {
+				log.Warnf("Failed to stop backend: %s", err.Error())
+			}
+		}
+	}
+
+	if !backendruntime.ReserveBackend(backend.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend was started by another request while it was being edited",
+		})
+
+		return
+	}
+
+	defer backendruntime.ReleaseBackend(backend.ID)
+
+	backendInstance, err := StartBackendRuntime(backend)
+
+	if err != nil {
+		log.Warnf("Failed to start backend #%d with its new parameters: %s", backend.ID, err.Error())
+
+		// Go back to the parameters that we know work
+		backend.BackendParameters = oldBackendParameters
+
+		if err := dbcore.DB.Save(backend).Error; err != nil {
+			log.Errorf("Failed to restore parameters of backend #%d: %s", backend.ID, err.Error())
+		} else {
+			auditEntry.SetAfter(audit.BackendSnapshot(backend))
+		}
+
+		if isRunning {
+			backendInstance, err := StartBackendRuntime(backend)
+
+			if err != nil {
+				log.Errorf("Failed to restart backend #%d with its old parameters: %s", backend.ID, err.Error())
+			} else if _, err := StartAutoStartProxies(backend.ID, backendInstance); err != nil {
+				log.Errorf("Failed to start proxies for backend #%d: %s", backend.ID, err.Error())
+			}
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to start backend with the new parameters. Changes have been rolled back: %s", err.Error()),
+		})
+
+		return
+	}
+
+	proxyResults, err := StartAutoStartProxies(backend.ID, backendInstance)
+
+	if err != nil {
+		log.Warnf("Failed to start proxies for backend #%d: %s", backend.ID, err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Backend restarted, but failed to start proxies",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, &BackendEditResponse{
+		Success: true,
+		Proxies: proxyResults,
+	})
+}
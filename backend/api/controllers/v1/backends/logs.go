@@ -0,0 +1,164 @@
+package backends
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type BackendLogsRequest struct {
+	Token     string     `validate:"required"`
+	BackendID uint       `json:"id" validate:"required"`
+	Since     *time.Time `json:"since"`
+	Tail      uint       `json:"tail"`
+	Follow    bool       `json:"follow"`
+}
+
+type LogsResponse struct {
+	Success bool                      `json:"success"`
+	Data    []*backendruntime.LogLine `json:"data"`
+}
+
+func GetBackendLogs(c *gin.Context) {
+	var req BackendLogsRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	if !permissions.UserHasPermission(user, "backends.visible") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var backend *dbcore.Backend
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.BackendID).Find(&backend)
+
+	if backendRequest.Error != nil {
+		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if backend exists",
+		})
+
+		return
+	}
+
+	backendExists := backendRequest.RowsAffected > 0
+
+	if !backendExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend doesn't exist",
+		})
+
+		return
+	}
+
+	if !permissions.UserCanAccessBackend(user, backend, "backends.visible") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	backendInstance, ok := backendruntime.GetRunningBackend(backend.ID)
+
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend is not running",
+		})
+
+		return
+	}
+
+	since := time.Time{}
+
+	if req.Since != nil {
+		since = *req.Since
+	}
+
+	if !req.Follow {
+		c.JSON(http.StatusOK, &LogsResponse{
+			Success: true,
+			Data:    backendInstance.GetLogs(since, int(req.Tail)),
+		})
+
+		return
+	}
+
+	logLines, subscriber := backendInstance.FollowLogs(since, int(req.Tail))
+	defer backendInstance.StopFollowingLogs(subscriber)
+
+	// Every line gets sent as a "log" event. An "end" event gets sent if the backend gets stopped.
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	for _, logLine := range logLines {
+		c.SSEvent("log", logLine)
+	}
+
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case logLine, ok := <-subscriber:
+			if !ok {
+				c.SSEvent("end", gin.H{
+					"reason": "Backend was stopped",
+				})
+
+				c.Writer.Flush()
+				return
+			}
+
+			c.SSEvent("log", logLine)
+			c.Writer.Flush()
+		}
+	}
+}
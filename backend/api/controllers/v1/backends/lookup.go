@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
@@ -15,6 +16,9 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// Only the last few lines of logs are included when looking up backends. The rest can be fetched from the logs endpoint.
+const lookupLogLines = 50
+
 type BackendLookupRequest struct {
 	Token       string `validate:"required"`
 	BackendID   *uint  `json:"id"`
@@ -30,7 +34,23 @@ type SanitizedBackend struct {
 	Description       *string  `json:"description,omitempty"`
 	Backend           string   `json:"backend"`
 	BackendParameters *string  `json:"connectionDetails,omitempty"`
+	AutoStart         bool     `json:"autoStart"`
 	Logs              []string `json:"logs"`
+	IsRunning         bool     `json:"isRunning"`
+	Status            string   `json:"status"`
+	StatusMessage     string   `json:"statusMessage,omitempty"`
+	CrashCount        int      `json:"crashCount"`
+	LastExitCode      *int     `json:"lastExitCode,omitempty"`
+	LastExitReason    string   `json:"lastExitReason,omitempty"`
+	Capabilities      []string `json:"capabilities,omitempty"`
+	Latency           float64  `json:"latencyMs,omitempty"`
+
+	Shares []*SanitizedBackendShare `json:"shares,omitempty"`
+}
+
+type SanitizedBackendShare struct {
+	UserID *uint `json:"userID,omitempty"`
+	RoleID *uint `json:"roleID,omitempty"`
 }
 
 type LookupResponse struct {
@@ -109,7 +129,7 @@ func LookupBackend(c *gin.Context) {
 		queryParameters = append(queryParameters, req.Backend)
 	}
 
-	if err := dbcore.DB.Where(strings.Join(queryString, " AND "), queryParameters...).Find(&backends).Error; err != nil {
+	if err := dbcore.DB.Preload("Shares").Where(strings.Join(queryString, " AND "), queryParameters...).Find(&backends).Error; err != nil {
 		log.Warnf("Failed to get backends: %s", err.Error())
 
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -119,29 +139,47 @@ func LookupBackend(c *gin.Context) {
 		return
 	}
 
-	sanitizedBackends := make([]*SanitizedBackend, len(backends))
+	sanitizedBackends := []*SanitizedBackend{}
 	hasSecretVisibility := permissions.UserHasPermission(user, "backends.secretVis")
 
-	for backendIndex, backend := range backends {
-		foundBackend, ok := backendruntime.RunningBackends[backend.ID]
-
-		if !ok {
-			log.Warnf("Failed to get backend #%d controller", backend.ID)
-
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get backends",
-			})
-
-			return
+	for _, backend := range backends {
+		if !permissions.UserCanAccessBackend(user, &backend, "backends.visible") {
+			continue
 		}
 
-		sanitizedBackends[backendIndex] = &SanitizedBackend{
+		sanitizedBackend := &SanitizedBackend{
 			BackendID:   backend.ID,
 			OwnerID:     backend.UserID,
 			Name:        backend.Name,
 			Description: backend.Description,
 			Backend:     backend.Backend,
-			Logs:        foundBackend.Logs,
+			AutoStart:   backend.AutoStart,
+			Logs:        []string{},
+			Status:      "stopped",
+		}
+
+		sanitizedBackends = append(sanitizedBackends, sanitizedBackend)
+
+		// Stopped backends (and ones that failed to start) don't have a runtime
+		if foundBackend, ok := backendruntime.GetRunningBackend(backend.ID); ok {
+			for _, logLine := range foundBackend.GetLogs(time.Time{}, lookupLogLines) {
+				sanitizedBackend.Logs = append(sanitizedBackend.Logs, logLine.Message)
+			}
+
+			stats := foundBackend.GetStats()
+
+			sanitizedBackend.IsRunning = stats.IsBackendRunning
+			sanitizedBackend.StatusMessage = stats.BackendStatusMessage
+			sanitizedBackend.Status = foundBackend.GetStatus()
+			sanitizedBackend.CrashCount = stats.CrashCount
+			sanitizedBackend.LastExitReason = stats.LastExitReason
+
+			if stats.CrashCount != 0 {
+				sanitizedBackend.LastExitCode = &stats.LastExitCode
+			}
+
+			sanitizedBackend.Capabilities = foundBackend.GetCapabilityNames()
+			sanitizedBackend.Latency = float64(stats.Latency.Microseconds()) / 1000
 		}
 
 		if backend.UserID == user.ID || hasSecretVisibility {
@@ -152,7 +190,19 @@ func LookupBackend(c *gin.Context) {
 			}
 
 			backendParameters := string(backendParametersBytes)
-			sanitizedBackends[backendIndex].BackendParameters = &backendParameters
+			sanitizedBackend.BackendParameters = &backendParameters
+		}
+
+		// Only the people who can change who a backend is shared with get to see it
+		if permissions.UserCanAccessBackend(user, &backend, "backends.edit") {
+			sanitizedBackend.Shares = make([]*SanitizedBackendShare, len(backend.Shares))
+
+			for shareIndex, share := range backend.Shares {
+				sanitizedBackend.Shares[shareIndex] = &SanitizedBackendShare{
+					UserID: share.UserID,
+					RoleID: share.RoleID,
+				}
+			}
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"git.terah.dev/imterah/hermes/backend/api/audit"
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
@@ -57,6 +58,10 @@ func RemoveBackend(c *gin.Context) {
 		}
 	}
 
+	auditEntry := audit.Start(c, user, "backends.remove")
+	auditEntry.SetTarget(req.BackendID)
+	defer auditEntry.Finish()
+
 	if !permissions.UserHasPermission(user, "backends.remove") {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Missing permissions",
@@ -66,7 +71,7 @@ func RemoveBackend(c *gin.Context) {
 	}
 
 	var backend *dbcore.Backend
-	backendRequest := dbcore.DB.Where("id = ?", req.BackendID).Find(&backend)
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.BackendID).Find(&backend)
 
 	if backendRequest.Error != nil {
 		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
@@ -88,7 +93,17 @@ func RemoveBackend(c *gin.Context) {
 		return
 	}
 
-	if err := dbcore.DB.Delete(backend).Error; err != nil {
+	auditEntry.SetBefore(audit.BackendSnapshot(backend))
+
+	if !permissions.UserCanAccessBackend(user, backend, "backends.remove") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if err := dbcore.DB.Select("Shares").Delete(backend).Error; err != nil {
 		log.Warnf("failed to delete backend: %s", err.Error())
 
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -98,7 +113,7 @@ func RemoveBackend(c *gin.Context) {
 		return
 	}
 
-	backendInstance, ok := backendruntime.RunningBackends[req.BackendID]
+	backendInstance, ok := backendruntime.RemoveRunningBackend(req.BackendID)
 
 	if ok {
 		err = backendInstance.Stop()
@@ -110,11 +125,8 @@ func RemoveBackend(c *gin.Context) {
 				"error": "Backend deleted, but failed to stop",
 			})
 
-			delete(backendruntime.RunningBackends, req.BackendID)
 			return
 		}
-
-		delete(backendruntime.RunningBackends, req.BackendID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,212 @@
+package backends
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type BackendShareRequest struct {
+	Token     string `validate:"required"`
+	BackendID uint   `json:"id" validate:"required"`
+	UserID    *uint  `json:"userID" validate:"required_without=RoleID,excluded_with=RoleID"`
+	RoleID    *uint  `json:"roleID"`
+}
+
+func ShareBackend(c *gin.Context) {
+	changeBackendShare(c, "backends.share", true)
+}
+
+func UnshareBackend(c *gin.Context) {
+	changeBackendShare(c, "backends.unshare", false)
+}
+
+func changeBackendShare(c *gin.Context, action string, isShared bool) {
+	var req BackendShareRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, action)
+	auditEntry.SetTarget(req.BackendID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "backends.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var backend *dbcore.Backend
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.BackendID).Find(&backend)
+
+	if backendRequest.Error != nil {
+		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if backend exists",
+		})
+
+		return
+	}
+
+	backendExists := backendRequest.RowsAffected > 0
+
+	if !backendExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetAfter(map[string]interface{}{
+		"userID": req.UserID,
+		"roleID": req.RoleID,
+	})
+
+	if !permissions.UserCanAccessBackend(user, backend, "backends.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var existingShare *dbcore.BackendShare
+
+	for _, share := range backend.Shares {
+		if req.UserID != nil && share.UserID != nil && *share.UserID == *req.UserID {
+			existingShare = &share
+			break
+		}
+
+		if req.RoleID != nil && share.RoleID != nil && *share.RoleID == *req.RoleID {
+			existingShare = &share
+			break
+		}
+	}
+
+	if !isShared {
+		if existingShare == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Backend isn't shared with them",
+			})
+
+			return
+		}
+
+		if err := dbcore.DB.Delete(existingShare).Error; err != nil {
+			log.Warnf("Failed to remove backend share: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to unshare backend",
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+
+		return
+	}
+
+	if existingShare != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend is already shared with them",
+		})
+
+		return
+	}
+
+	var targetRequest *gorm.DB
+
+	if req.UserID != nil {
+		targetRequest = dbcore.DB.Where("id = ?", *req.UserID).Find(&dbcore.User{})
+	} else {
+		targetRequest = dbcore.DB.Where("id = ?", *req.RoleID).Find(&dbcore.Role{})
+	}
+
+	if targetRequest.Error != nil {
+		log.Warnf("failed to find if user or role exists or not: %s", targetRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if user or role exists",
+		})
+
+		return
+	}
+
+	if targetRequest.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User or role doesn't exist",
+		})
+
+		return
+	}
+
+	share := &dbcore.BackendShare{
+		BackendID: backend.ID,
+		UserID:    req.UserID,
+		RoleID:    req.RoleID,
+	}
+
+	if err := dbcore.DB.Create(share).Error; err != nil {
+		log.Warnf("Failed to add backend share: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to share backend",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
@@ -0,0 +1,152 @@
+package backends
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type BackendStartRequest struct {
+	Token     string `validate:"required"`
+	BackendID uint   `json:"id" validate:"required"`
+}
+
+type BackendStartResponse struct {
+	Success bool                `json:"success"`
+	Proxies []*ProxyStartResult `json:"proxies"`
+}
+
+func StartBackend(c *gin.Context) {
+	var req BackendStartRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "backends.start")
+	auditEntry.SetTarget(req.BackendID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "backends.start") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var backend *dbcore.Backend
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.BackendID).Find(&backend)
+
+	if backendRequest.Error != nil {
+		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if backend exists",
+		})
+
+		return
+	}
+
+	backendExists := backendRequest.RowsAffected > 0
+
+	if !backendExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend doesn't exist",
+		})
+
+		return
+	}
+
+	if !permissions.UserCanAccessBackend(user, backend, "backends.start") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	// Keeps the backend from being started twice, if several requests to start it come in at once
+	if !backendruntime.ReserveBackend(backend.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend is already running",
+		})
+
+		return
+	}
+
+	defer backendruntime.ReleaseBackend(backend.ID)
+
+	backendInstance, err := StartBackendRuntime(backend)
+
+	if err != nil {
+		log.Warnf("Failed to start backend #%d: %s", backend.ID, err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to start backend: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := dbcore.DB.Model(backend).Update("auto_start", true).Error; err != nil {
+		log.Warnf("Failed to update backend: %s", err.Error())
+	}
+
+	proxyResults, err := StartAutoStartProxies(backend.ID, backendInstance)
+
+	if err != nil {
+		log.Warnf("Failed to start proxies for backend #%d: %s", backend.ID, err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Backend started, but failed to start proxies",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, &BackendStartResponse{
+		Success: true,
+		Proxies: proxyResults,
+	})
+}
@@ -0,0 +1,309 @@
+package backends
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+
+	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/commonbackend"
+	"github.com/charmbracelet/log"
+)
+
+type ProxyStartResult struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Persists parameters that the backend has changed on its own (ex. a host key trusted on first use)
+func saveUpdatedBackendParameters(backend *dbcore.Backend, response interface{}) {
+	switch responseMessage := response.(type) {
+	case *commonbackend.BackendParametersResponse:
+		if len(responseMessage.Arguments) == 0 {
+			return
+		}
+
+		encodedParameters := base64.StdEncoding.EncodeToString(responseMessage.Arguments)
+
+		if encodedParameters == backend.BackendParameters {
+			return
+		}
+
+		if err := dbcore.DB.Model(backend).Update("backend_parameters", encodedParameters).Error; err != nil {
+			log.Errorf("Failed to save updated parameters for backend #%d: %s", backend.ID, err.Error())
+			return
+		}
+
+		backend.BackendParameters = encodedParameters
+		log.Infof("Saved updated parameters for backend #%d", backend.ID)
+	default:
+		log.Errorf("Got illegal response type for backend #%d: %T", backend.ID, responseMessage)
+	}
+}
+
+// Nothing else talks to the backend while the crash callback runs, so every request of it can share the same ID
+const crashCallbackRequestID = 1
+
+// Gets run when the backend process restarts. We can't use ProcessCommand here, as the runtime doesn't process
+// commands until we're done, so we have to talk over the socket directly.
+func onBackendCrash(backendInstance *backendruntime.Runtime, backendID uint, conn net.Conn) {
+	// Fetch the backend again, as the parameters could've been changed since the backend was started
+	var backend dbcore.Backend
+
+	if err := dbcore.DB.First(&backend, backendID).Error; err != nil {
+		log.Errorf("Failed to get backend #%d: %s", backendID, err.Error())
+		return
+	}
+
+	backendParameters, err := base64.StdEncoding.DecodeString(backend.BackendParameters)
+
+	if err != nil {
+		log.Errorf("Failed to decode backend parameters for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	marshalledStartCommand, err := commonbackend.Marshal(crashCallbackRequestID, &commonbackend.Start{
+		Arguments: backendParameters,
+	})
+
+	if err != nil {
+		log.Errorf("Failed to marshal start command for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	if _, err := conn.Write(marshalledStartCommand); err != nil {
+		log.Errorf("Failed to send start command for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	_, backendResponse, err := commonbackend.Unmarshal(conn)
+
+	if err != nil {
+		log.Errorf("Failed to get start command response for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	switch responseMessage := backendResponse.(type) {
+	case *commonbackend.BackendStatusResponse:
+		if !responseMessage.IsRunning {
+			log.Errorf("Failed to start backend #%d: %s", backend.ID, responseMessage.Message)
+			return
+		}
+
+		log.Infof("Backend #%d has been reinitialized successfully", backend.ID)
+	}
+
+	marshalledParametersRequest, err := commonbackend.Marshal(crashCallbackRequestID, &commonbackend.BackendParametersRequest{})
+
+	if err != nil {
+		log.Errorf("Failed to marshal parameters request for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	if _, err := conn.Write(marshalledParametersRequest); err != nil {
+		log.Errorf("Failed to send parameters request for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	_, backendResponse, err = commonbackend.Unmarshal(conn)
+
+	if err != nil {
+		log.Errorf("Failed to get parameters request response for backend #%d: %s", backend.ID, err.Error())
+		return
+	}
+
+	saveUpdatedBackendParameters(&backend, backendResponse)
+
+	log.Warnf("Backend #%d has reinitialized! Starting up auto-starting proxies...", backend.ID)
+
+	autoStartProxies := []dbcore.Proxy{}
+
+	if err := dbcore.DB.Where("backend_id = ? AND auto_start = true", backend.ID).Find(&autoStartProxies).Error; err != nil {
+		log.Errorf("Failed to query proxies to autostart: %s", err.Error())
+		return
+	}
+
+	for _, proxy := range autoStartProxies {
+		log.Infof("Starting up route #%d for backend #%d: %s", proxy.ID, backend.ID, proxy.Name)
+
+		if err := backendInstance.CheckProtocol(proxy.Protocol); err != nil {
+			log.Warnf("Not starting route #%d for backend #%d: %s", proxy.ID, backend.ID, err.Error())
+			continue
+		}
+
+		marhalledCommand, err := commonbackend.Marshal(crashCallbackRequestID, &commonbackend.AddProxy{
+			SourceIP:   proxy.SourceIP,
+			SourcePort: proxy.SourcePort,
+			DestPort:   proxy.DestinationPort,
+			Protocol:   proxy.Protocol,
+		})
+
+		if err != nil {
+			log.Errorf("Failed to marshal proxy adding request for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
+			continue
+		}
+
+		if _, err := conn.Write(marhalledCommand); err != nil {
+			log.Errorf("Failed to send proxy adding request for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
+			continue
+		}
+
+		_, backendResponse, err := commonbackend.Unmarshal(conn)
+
+		if err != nil {
+			log.Errorf("Failed to get response for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
+			continue
+		}
+
+		switch responseMessage := backendResponse.(type) {
+		case *commonbackend.ProxyStatusResponse:
+			if !responseMessage.IsActive {
+				log.Warnf("Failed to start proxy for backend #%d and route #%d", proxy.BackendID, proxy.ID)
+			}
+		default:
+			log.Errorf("Got illegal response type for backend #%d and proxy #%d: %T", proxy.BackendID, proxy.ID, responseMessage)
+			continue
+		}
+	}
+}
+
+// StartBackendRuntime starts the runtime for a backend in the database, initializes it with its parameters, and
+// registers it as a running backend. Proxies aren't started (see StartAutoStartProxies).
+func StartBackendRuntime(backend *dbcore.Backend) (*backendruntime.Runtime, error) {
+	var backendRuntimeFilePath string
+
+	for _, runtime := range backendruntime.AvailableBackends {
+		if runtime.Name == backend.Backend {
+			backendRuntimeFilePath = runtime.Path
+		}
+	}
+
+	if backendRuntimeFilePath == "" {
+		return nil, fmt.Errorf("unsupported backend: %s", backend.Backend)
+	}
+
+	backendParameters, err := base64.StdEncoding.DecodeString(backend.BackendParameters)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode backend parameters: %s", err.Error())
+	}
+
+	backendID := backend.ID
+	backendInstance := backendruntime.NewBackend(backendRuntimeFilePath)
+
+	backendInstance.OnCrashCallback = func(conn net.Conn) {
+		onBackendCrash(backendInstance, backendID, conn)
+	}
+
+	if err := backendInstance.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start backend runtime: %s", err.Error())
+	}
+
+	backendStartResponse, err := backendInstance.ProcessCommand(&commonbackend.Start{
+		Arguments: backendParameters,
+	})
+
+	if err != nil {
+		if err := backendInstance.Stop(); err != nil {
+			log.Warnf("Failed to stop backend: %s", err.Error())
+		}
+
+		return nil, fmt.Errorf("failed to get response from backend: %s", err.Error())
+	}
+
+	switch responseMessage := backendStartResponse.(type) {
+	case *commonbackend.BackendStatusResponse:
+		if !responseMessage.IsRunning {
+			if err := backendInstance.Stop(); err != nil {
+				log.Warnf("Failed to stop backend: %s", err.Error())
+			}
+
+			if responseMessage.Message == "" {
+				return nil, fmt.Errorf("unknown error while trying to start the backend")
+			}
+
+			return nil, fmt.Errorf("failed to start backend: %s", responseMessage.Message)
+		}
+	default:
+		if err := backendInstance.Stop(); err != nil {
+			log.Warnf("Failed to stop backend: %s", err.Error())
+		}
+
+		return nil, fmt.Errorf("got illegal response type from backend: %T", responseMessage)
+	}
+
+	backendParametersResponse, err := backendInstance.ProcessCommand(&commonbackend.BackendParametersRequest{})
+
+	if err != nil {
+		log.Warnf("Failed to get updated parameters for backend #%d: %s", backend.ID, err.Error())
+	} else {
+		saveUpdatedBackendParameters(backend, backendParametersResponse)
+	}
+
+	backendruntime.SetRunningBackend(backend.ID, backendInstance)
+
+	return backendInstance, nil
+}
+
+// StartAutoStartProxies starts every proxy of a backend that has auto start enabled, and reports how each one went.
+func StartAutoStartProxies(backendID uint, backendInstance *backendruntime.Runtime) ([]*ProxyStartResult, error) {
+	autoStartProxies := []dbcore.Proxy{}
+
+	if err := dbcore.DB.Where("backend_id = ? AND auto_start = true", backendID).Find(&autoStartProxies).Error; err != nil {
+		return nil, fmt.Errorf("failed to query proxies to autostart: %s", err.Error())
+	}
+
+	results := make([]*ProxyStartResult, len(autoStartProxies))
+
+	for proxyIndex, proxy := range autoStartProxies {
+		log.Infof("Starting up route #%d for backend #%d: %s", proxy.ID, backendID, proxy.Name)
+
+		result := &ProxyStartResult{
+			ID:   proxy.ID,
+			Name: proxy.Name,
+		}
+
+		results[proxyIndex] = result
+
+		if err := backendInstance.CheckProtocol(proxy.Protocol); err != nil {
+			log.Warnf("Not starting route #%d for backend #%d: %s", proxy.ID, backendID, err.Error())
+			result.Error = err.Error()
+
+			continue
+		}
+
+		backendResponse, err := backendInstance.ProcessCommand(&commonbackend.AddProxy{
+			SourceIP:   proxy.SourceIP,
+			SourcePort: proxy.SourcePort,
+			DestPort:   proxy.DestinationPort,
+			Protocol:   proxy.Protocol,
+		})
+
+		if err != nil {
+			log.Errorf("Failed to get response for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
+			result.Error = "Failed to get response from backend"
+
+			continue
+		}
+
+		switch responseMessage := backendResponse.(type) {
+		case *commonbackend.ProxyStatusResponse:
+			if !responseMessage.IsActive {
+				log.Warnf("Failed to start proxy for backend #%d and route #%d", proxy.BackendID, proxy.ID)
+				result.Error = "Failed to start proxy"
+
+				continue
+			}
+
+			result.Success = true
+		default:
+			log.Errorf("Got illegal response type for backend #%d and proxy #%d: %T", proxy.BackendID, proxy.ID, responseMessage)
+			result.Error = "Got invalid response from backend"
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,147 @@
+package backends
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type BackendStopRequest struct {
+	Token     string `validate:"required"`
+	BackendID uint   `json:"id" validate:"required"`
+}
+
+func StopBackend(c *gin.Context) {
+	var req BackendStopRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "backends.stop")
+	auditEntry.SetTarget(req.BackendID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "backends.stop") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var backend *dbcore.Backend
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.BackendID).Find(&backend)
+
+	if backendRequest.Error != nil {
+		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if backend exists",
+		})
+
+		return
+	}
+
+	backendExists := backendRequest.RowsAffected > 0
+
+	if !backendExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend doesn't exist",
+		})
+
+		return
+	}
+
+	if !permissions.UserCanAccessBackend(user, backend, "backends.stop") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if _, ok := backendruntime.GetRunningBackend(backend.ID); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend is not running",
+		})
+
+		return
+	}
+
+	// Keep the backend stopped across restarts of the API, until it gets started again
+	if err := dbcore.DB.Model(backend).Update("auto_start", false).Error; err != nil {
+		log.Warnf("Failed to update backend: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update backend",
+		})
+
+		return
+	}
+
+	// Another request could've stopped it in the meantime, in which case there's nothing left to do
+	backendInstance, ok := backendruntime.RemoveRunningBackend(backend.ID)
+
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend is not running",
+		})
+
+		return
+	}
+
+	if err := backendInstance.Stop(); err != nil {
+		log.Warnf("Failed to stop backend: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to stop backend",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
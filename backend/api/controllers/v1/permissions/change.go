@@ -0,0 +1,208 @@
+package permissions
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	permissionHelper "git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type PermissionChangeRequest struct {
+	Token          string `validate:"required" json:"token"`
+	UID            uint   `validate:"required" json:"id"`
+	PermissionNode string `validate:"required" json:"node"`
+}
+
+func GrantPermission(c *gin.Context) {
+	hasPermission := true
+	changePermission(c, "permissions.grant", &hasPermission)
+}
+
+func RevokePermission(c *gin.Context) {
+	hasPermission := false
+	changePermission(c, "permissions.revoke", &hasPermission)
+}
+
+// Removes the override on the user, so that the permission comes from their roles again
+func ResetPermission(c *gin.Context) {
+	changePermission(c, "permissions.reset", nil)
+}
+
+func changePermission(c *gin.Context, action string, hasPermission *bool) {
+	var req PermissionChangeRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, action)
+	auditEntry.SetTarget(req.UID)
+	auditEntry.SetAfter(map[string]interface{}{
+		"node": req.PermissionNode,
+	})
+
+	defer auditEntry.Finish()
+
+	if !permissionHelper.UserHasPermission(user, "permissions.see") || !permissionHelper.UserHasPermission(user, "permissions.manage") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if !permissionHelper.IsValidPermissionNode(req.PermissionNode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown permission node",
+		})
+
+		return
+	}
+
+	var targetUser *dbcore.User
+	userRequest := dbcore.DB.Where("id = ?", req.UID).Find(&targetUser)
+
+	if userRequest.Error != nil {
+		log.Warnf("failed to find if user exists or not: %s", userRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if user exists",
+		})
+
+		return
+	}
+
+	userExists := userRequest.RowsAffected > 0
+
+	if !userExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User doesn't exist",
+		})
+
+		return
+	}
+
+	tx := dbcore.DB.Begin()
+
+	var permission *dbcore.Permission
+	permissionRequest := tx.Where("user_id = ? AND permission_node = ?", req.UID, req.PermissionNode).Find(&permission)
+
+	if permissionRequest.Error != nil {
+		tx.Rollback()
+		log.Warnf("failed to find if permission exists or not: %s", permissionRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if permission exists",
+		})
+
+		return
+	}
+
+	permissionExists := permissionRequest.RowsAffected > 0
+
+	if hasPermission == nil {
+		if permissionExists {
+			err = tx.Delete(permission).Error
+		}
+	} else {
+		if !permissionExists {
+			permission = &dbcore.Permission{
+				PermissionNode: req.PermissionNode,
+				UserID:         req.UID,
+			}
+		}
+
+		permission.HasPermission = *hasPermission
+		err = tx.Save(permission).Error
+	}
+
+	if err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to save permission: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update permission",
+		})
+
+		return
+	}
+
+	if req.UID == user.ID {
+		// Otherwise, it'd be way too easy to lock everyone out of managing permissions
+		updatedUser, err := permissionHelper.GetUserWithPermissions(tx, user.ID)
+
+		if err != nil {
+			tx.Rollback()
+			log.Warnf("Failed to get updated permissions: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update permission",
+			})
+
+			return
+		}
+
+		if !permissionHelper.UserHasPermission(updatedUser, "permissions.manage") {
+			tx.Rollback()
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "You can't remove permissions.manage from yourself",
+			})
+
+			return
+		}
+	}
+
+	// Permissions get loaded on every request, so this takes effect on the next request of the user
+	if err := tx.Commit().Error; err != nil {
+		log.Warnf("Failed to save permission: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update permission",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
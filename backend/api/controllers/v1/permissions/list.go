@@ -0,0 +1,144 @@
+package permissions
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	permissionHelper "git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type PermissionListRequest struct {
+	Token string `validate:"required" json:"token"`
+	UID   *uint  `json:"id"`
+}
+
+type SanitizedPermission struct {
+	PermissionNode string `json:"node"`
+	HasPermission  bool   `json:"hasPermission"`
+	IsOverride     bool   `json:"isOverride"` // If true, this is set on the user itself, instead of coming from its roles
+}
+
+type PermissionListResponse struct {
+	Success bool                   `json:"success"`
+	Roles   []string               `json:"roles"`
+	Data    []*SanitizedPermission `json:"data"`
+}
+
+func ListPermissions(c *gin.Context) {
+	var req PermissionListRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	if !permissionHelper.UserHasPermission(user, "permissions.see") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	targetUserID := user.ID
+
+	// Looking at the permissions of other users is part of managing them
+	if req.UID != nil && *req.UID != user.ID {
+		if !permissionHelper.UserHasPermission(user, "permissions.manage") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Missing permissions",
+			})
+
+			return
+		}
+
+		targetUserID = *req.UID
+	}
+
+	targetUser, err := permissionHelper.GetUserWithPermissions(dbcore.DB, targetUserID)
+
+	if err != nil {
+		if err.Error() == "user does not exist" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "User doesn't exist",
+			})
+
+			return
+		}
+
+		log.Warnf("Failed to get permissions: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get permissions",
+		})
+
+		return
+	}
+
+	roles := make([]string, len(targetUser.Roles))
+
+	for roleIndex, role := range targetUser.Roles {
+		roles[roleIndex] = role.Name
+	}
+
+	sanitizedPermissions := make([]*SanitizedPermission, len(permissionHelper.DefaultPermissionNodes))
+
+	for permissionIndex, node := range permissionHelper.DefaultPermissionNodes {
+		isOverride := false
+
+		for _, permission := range targetUser.Permissions {
+			if permission.PermissionNode == node {
+				isOverride = true
+				break
+			}
+		}
+
+		sanitizedPermissions[permissionIndex] = &SanitizedPermission{
+			PermissionNode: node,
+			HasPermission:  permissionHelper.UserHasPermission(targetUser, node),
+			IsOverride:     isOverride,
+		}
+	}
+
+	c.JSON(http.StatusOK, &PermissionListResponse{
+		Success: true,
+		Roles:   roles,
+		Data:    sanitizedPermissions,
+	})
+}
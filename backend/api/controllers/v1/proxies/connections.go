@@ -106,7 +106,15 @@ func GetConnections(c *gin.Context) {
 		return
 	}
 
-	backendRuntime, ok := backendruntime.RunningBackends[proxy.BackendID]
+	if !permissions.UserCanAccessProxy(user, &proxy, "routes.visibleConn") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	backendRuntime, ok := backendruntime.GetRunningBackend(proxy.BackendID)
 
 	if !ok {
 		log.Warnf("Couldn't fetch backend runtime from backend ID #%d", proxy.BackendID)
@@ -118,6 +126,14 @@ func GetConnections(c *gin.Context) {
 		return
 	}
 
+	if !backendRuntime.HasCapability(commonbackend.CapabilityConnectionListing) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend doesn't support listing connections",
+		})
+
+		return
+	}
+
 	backendResponse, err := backendRuntime.ProcessCommand(&commonbackend.ProxyConnectionsRequest{})
 
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"git.terah.dev/imterah/hermes/backend/api/audit"
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
@@ -64,6 +65,9 @@ func CreateProxy(c *gin.Context) {
 		}
 	}
 
+	auditEntry := audit.Start(c, user, "forward.create")
+	defer auditEntry.Finish()
+
 	if !permissions.UserHasPermission(user, "routes.add") {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Missing permissions",
@@ -81,7 +85,7 @@ func CreateProxy(c *gin.Context) {
 	}
 
 	var backend dbcore.Backend
-	backendRequest := dbcore.DB.Where("id = ?", req.ProviderID).First(&backend)
+	backendRequest := dbcore.DB.Preload("Shares").Where("id = ?", req.ProviderID).Find(&backend)
 
 	if backendRequest.Error != nil {
 		log.Warnf("failed to find if backend exists or not: %s", backendRequest.Error.Error())
@@ -89,6 +93,8 @@ func CreateProxy(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to find if backend exists",
 		})
+
+		return
 	}
 
 	backendExists := backendRequest.RowsAffected > 0
@@ -97,6 +103,27 @@ func CreateProxy(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Could not find backend",
 		})
+
+		return
+	}
+
+	// Forward rules can only be added to backends the user owns, or that have been shared with them
+	if !permissions.UserCanAccessBackend(user, &backend, "routes.add") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if backendRuntime, ok := backendruntime.GetRunningBackend(backend.ID); ok {
+		if err := backendRuntime.CheckProtocol(req.Protocol); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		}
 	}
 
 	autoStart := false
@@ -127,8 +154,11 @@ func CreateProxy(c *gin.Context) {
 		return
 	}
 
+	auditEntry.SetTarget(proxy.ID)
+	auditEntry.SetAfter(audit.ProxySnapshot(proxy))
+
 	if autoStart {
-		backend, ok := backendruntime.RunningBackends[proxy.BackendID]
+		backend, ok := backendruntime.GetRunningBackend(proxy.BackendID)
 
 		if !ok {
 			log.Warnf("Couldn't fetch backend runtime from backend ID #%d", proxy.BackendID)
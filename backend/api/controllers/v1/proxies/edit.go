@@ -0,0 +1,264 @@
+package proxies
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"git.terah.dev/imterah/hermes/backend/commonbackend"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type ProxyEditRequest struct {
+	Token           string  `validate:"required" json:"token"`
+	ID              uint    `validate:"required" json:"id"`
+	Name            *string `json:"name"`
+	Description     *string `json:"description"`
+	Protocol        *string `json:"protocol"`
+	SourceIP        *string `json:"sourceIP"`
+	SourcePort      *uint16 `json:"sourcePort"`
+	DestinationPort *uint16 `json:"destinationPort"`
+	AutoStart       *bool   `json:"autoStart"`
+}
+
+// Sends a proxy start/stop command to the backend, and returns true if the backend says it went through.
+func sendProxyCommand(backend *backendruntime.Runtime, backendID uint, command interface{}) bool {
+	backendResponse, err := backend.ProcessCommand(command)
+
+	if err != nil {
+		log.Warnf("Failed to get response for backend #%d: %s", backendID, err.Error())
+		return false
+	}
+
+	switch responseMessage := backendResponse.(type) {
+	case *commonbackend.ProxyStatusResponse:
+		switch command.(type) {
+		case *commonbackend.RemoveProxy:
+			return !responseMessage.IsActive
+		default:
+			return responseMessage.IsActive
+		}
+	default:
+		log.Errorf("Got illegal response type for backend #%d: %T", backendID, responseMessage)
+		return false
+	}
+}
+
+func EditProxy(c *gin.Context) {
+	var req ProxyEditRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "forward.edit")
+	auditEntry.SetTarget(req.ID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "routes.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if req.Protocol != nil && *req.Protocol != "tcp" && *req.Protocol != "udp" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Protocol must be either 'tcp' or 'udp'",
+		})
+
+		return
+	}
+
+	var proxy *dbcore.Proxy
+	proxyRequest := dbcore.DB.Where("id = ?", req.ID).Find(&proxy)
+
+	if proxyRequest.Error != nil {
+		log.Warnf("failed to find if proxy exists or not: %s", proxyRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if forward rule exists",
+		})
+
+		return
+	}
+
+	proxyExists := proxyRequest.RowsAffected > 0
+
+	if !proxyExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Forward rule doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetBefore(audit.ProxySnapshot(proxy))
+
+	if !permissions.UserCanAccessProxy(user, proxy, "routes.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if req.Protocol != nil {
+		if backendRuntime, ok := backendruntime.GetRunningBackend(proxy.BackendID); ok {
+			if err := backendRuntime.CheckProtocol(*req.Protocol); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+
+				return
+			}
+		}
+	}
+
+	oldProxy := *proxy
+
+	if req.Name != nil {
+		proxy.Name = *req.Name
+	}
+
+	if req.Description != nil {
+		proxy.Description = req.Description
+	}
+
+	if req.Protocol != nil {
+		proxy.Protocol = *req.Protocol
+	}
+
+	if req.SourceIP != nil {
+		proxy.SourceIP = *req.SourceIP
+	}
+
+	if req.SourcePort != nil {
+		proxy.SourcePort = *req.SourcePort
+	}
+
+	if req.DestinationPort != nil {
+		proxy.DestinationPort = *req.DestinationPort
+	}
+
+	if req.AutoStart != nil {
+		proxy.AutoStart = *req.AutoStart
+	}
+
+	// The proxy gets restarted before it is saved, as restarting it can take a while, and keeping a transaction open for
+	// that long would lock the database (on SQLite). If saving fails, the restart gets undone instead.
+	networkingChanged := proxy.Protocol != oldProxy.Protocol || proxy.SourceIP != oldProxy.SourceIP || proxy.SourcePort != oldProxy.SourcePort || proxy.DestinationPort != oldProxy.DestinationPort
+	backend, ok := backendruntime.GetRunningBackend(proxy.BackendID)
+	hasRestarted := false
+
+	if networkingChanged && ok {
+		// The backend is the only one who knows if the proxy is running, so we just try to stop it. If that fails,
+		// it wasn't running in the first place, and there's nothing to restart.
+		wasRunning := sendProxyCommand(backend, proxy.BackendID, &commonbackend.RemoveProxy{
+			SourceIP:   oldProxy.SourceIP,
+			SourcePort: oldProxy.SourcePort,
+			DestPort:   oldProxy.DestinationPort,
+			Protocol:   oldProxy.Protocol,
+		})
+
+		if wasRunning {
+			hasStarted := sendProxyCommand(backend, proxy.BackendID, &commonbackend.AddProxy{
+				SourceIP:   proxy.SourceIP,
+				SourcePort: proxy.SourcePort,
+				DestPort:   proxy.DestinationPort,
+				Protocol:   proxy.Protocol,
+			})
+
+			if !hasStarted {
+				if !sendProxyCommand(backend, proxy.BackendID, &commonbackend.AddProxy{
+					SourceIP:   oldProxy.SourceIP,
+					SourcePort: oldProxy.SourcePort,
+					DestPort:   oldProxy.DestinationPort,
+					Protocol:   oldProxy.Protocol,
+				}) {
+					log.Errorf("Failed to restore forward rule #%d after a failed edit. It is no longer running", proxy.ID)
+				}
+
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to start the edited forward rule. Changes have been rolled back",
+				})
+
+				return
+			}
+
+			hasRestarted = true
+		}
+	}
+
+	if err := dbcore.DB.Save(proxy).Error; err != nil {
+		log.Warnf("failed to update proxy: %s", err.Error())
+
+		if hasRestarted {
+			sendProxyCommand(backend, proxy.BackendID, &commonbackend.RemoveProxy{
+				SourceIP:   proxy.SourceIP,
+				SourcePort: proxy.SourcePort,
+				DestPort:   proxy.DestinationPort,
+				Protocol:   proxy.Protocol,
+			})
+
+			if !sendProxyCommand(backend, proxy.BackendID, &commonbackend.AddProxy{
+				SourceIP:   oldProxy.SourceIP,
+				SourcePort: oldProxy.SourcePort,
+				DestPort:   oldProxy.DestinationPort,
+				Protocol:   oldProxy.Protocol,
+			}) {
+				log.Errorf("Failed to restore forward rule #%d after a failed edit. It is no longer running", proxy.ID)
+			}
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update forward rule",
+		})
+
+		return
+	}
+
+	auditEntry.SetAfter(audit.ProxySnapshot(proxy))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
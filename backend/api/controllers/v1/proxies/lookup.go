@@ -150,6 +150,11 @@ func LookupProxy(c *gin.Context) {
 		queryParameters = append(queryParameters, req.Protocol)
 	}
 
+	if !permissions.UserHasPermission(user, "routes.global") {
+		queryString = append(queryString, "user_id = ?")
+		queryParameters = append(queryParameters, user.ID)
+	}
+
 	if err := dbcore.DB.Where(strings.Join(queryString, " AND "), queryParameters...).Find(&proxies).Error; err != nil {
 		log.Warnf("failed to get proxies: %s", err.Error())
 
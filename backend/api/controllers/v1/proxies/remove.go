@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"git.terah.dev/imterah/hermes/backend/api/audit"
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
@@ -57,6 +58,10 @@ func RemoveProxy(c *gin.Context) {
 		}
 	}
 
+	auditEntry := audit.Start(c, user, "forward.remove")
+	auditEntry.SetTarget(req.ID)
+	defer auditEntry.Finish()
+
 	if !permissions.UserHasPermission(user, "routes.remove") {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Missing permissions",
@@ -88,6 +93,16 @@ func RemoveProxy(c *gin.Context) {
 		return
 	}
 
+	auditEntry.SetBefore(audit.ProxySnapshot(proxy))
+
+	if !permissions.UserCanAccessProxy(user, proxy, "routes.remove") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
 	if err := dbcore.DB.Delete(proxy).Error; err != nil {
 		log.Warnf("failed to delete proxy: %s", err.Error())
 
@@ -98,7 +113,7 @@ func RemoveProxy(c *gin.Context) {
 		return
 	}
 
-	backend, ok := backendruntime.RunningBackends[proxy.BackendID]
+	backend, ok := backendruntime.GetRunningBackend(proxy.BackendID)
 
 	if !ok {
 		log.Warnf("Couldn't fetch backend runtime from backend ID #%d", proxy.BackendID)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"git.terah.dev/imterah/hermes/backend/api/audit"
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
@@ -57,6 +58,10 @@ func StartProxy(c *gin.Context) {
 		}
 	}
 
+	auditEntry := audit.Start(c, user, "forward.start")
+	auditEntry.SetTarget(req.ID)
+	defer auditEntry.Finish()
+
 	if !permissions.UserHasPermission(user, "routes.start") {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Missing permissions",
@@ -88,7 +93,15 @@ func StartProxy(c *gin.Context) {
 		return
 	}
 
-	backend, ok := backendruntime.RunningBackends[proxy.BackendID]
+	if !permissions.UserCanAccessProxy(user, proxy, "routes.start") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	backend, ok := backendruntime.GetRunningBackend(proxy.BackendID)
 
 	if !ok {
 		log.Warnf("Couldn't fetch backend runtime from backend ID #%d", proxy.BackendID)
@@ -100,6 +113,14 @@ func StartProxy(c *gin.Context) {
 		return
 	}
 
+	if err := backend.CheckProtocol(proxy.Protocol); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+
+		return
+	}
+
 	backendResponse, err := backend.ProcessCommand(&commonbackend.AddProxy{
 		SourceIP:   proxy.SourceIP,
 		SourcePort: proxy.SourcePort,
@@ -0,0 +1,187 @@
+package roles
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type RoleAssignmentRequest struct {
+	Token  string `validate:"required" json:"token"`
+	RoleID uint   `validate:"required" json:"roleID"`
+	UID    uint   `validate:"required" json:"id"`
+}
+
+func AssignRole(c *gin.Context) {
+	changeRoleAssignment(c, "roles.assign", true)
+}
+
+func UnassignRole(c *gin.Context) {
+	changeRoleAssignment(c, "roles.unassign", false)
+}
+
+func changeRoleAssignment(c *gin.Context, action string, isAssigned bool) {
+	var req RoleAssignmentRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, action)
+	auditEntry.SetTarget(req.RoleID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "permissions.see") || !permissions.UserHasPermission(user, "permissions.manage") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var role *dbcore.Role
+	roleRequest := dbcore.DB.Where("id = ?", req.RoleID).Find(&role)
+
+	if roleRequest.Error != nil {
+		log.Warnf("failed to find if role exists or not: %s", roleRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if role exists",
+		})
+
+		return
+	}
+
+	roleExists := roleRequest.RowsAffected > 0
+
+	if !roleExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Role doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetAfter(map[string]interface{}{
+		"userID": req.UID,
+	})
+
+	var targetUser *dbcore.User
+	userRequest := dbcore.DB.Where("id = ?", req.UID).Find(&targetUser)
+
+	if userRequest.Error != nil {
+		log.Warnf("failed to find if user exists or not: %s", userRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if user exists",
+		})
+
+		return
+	}
+
+	userExists := userRequest.RowsAffected > 0
+
+	if !userExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User doesn't exist",
+		})
+
+		return
+	}
+
+	tx := dbcore.DB.Begin()
+	association := tx.Model(targetUser).Association("Roles")
+
+	if isAssigned {
+		err = association.Append(role)
+	} else {
+		err = association.Delete(role)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to update role assignment: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	canManage, err := canStillManagePermissions(tx, user.ID)
+
+	if err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to get updated permissions: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	if !canManage {
+		tx.Rollback()
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "You can't remove permissions.manage from yourself",
+		})
+
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Warnf("Failed to update role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
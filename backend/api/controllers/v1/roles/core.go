@@ -0,0 +1,43 @@
+package roles
+
+import (
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"gorm.io/gorm"
+)
+
+type SanitizedRole struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+	Users       []uint   `json:"users"`
+}
+
+// Changing roles can take permissions.manage away from the user doing it, which would make it way too easy to lock
+// everyone out of managing permissions. This checks for that before the transaction gets committed.
+func canStillManagePermissions(tx *gorm.DB, userID uint) (bool, error) {
+	user, err := permissions.GetUserWithPermissions(tx, userID)
+
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.UserHasPermission(user, "permissions.manage"), nil
+}
+
+func rolePermissionsFromNodes(nodes []string) ([]dbcore.RolePermission, bool) {
+	rolePermissions := make([]dbcore.RolePermission, len(nodes))
+
+	for nodeIndex, node := range nodes {
+		if !permissions.IsValidPermissionNode(node) {
+			return nil, false
+		}
+
+		rolePermissions[nodeIndex] = dbcore.RolePermission{
+			PermissionNode: node,
+		}
+	}
+
+	return rolePermissions, true
+}
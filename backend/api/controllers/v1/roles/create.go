@@ -0,0 +1,127 @@
+package roles
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type RoleCreationRequest struct {
+	Token           string   `validate:"required" json:"token"`
+	Name            string   `validate:"required" json:"name"`
+	Description     *string  `json:"description"`
+	PermissionNodes []string `json:"permissions"`
+}
+
+func CreateRole(c *gin.Context) {
+	var req RoleCreationRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "roles.create")
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "permissions.see") || !permissions.UserHasPermission(user, "permissions.manage") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	rolePermissions, ok := rolePermissionsFromNodes(req.PermissionNodes)
+
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown permission node",
+		})
+
+		return
+	}
+
+	var existingRole *dbcore.Role
+	roleRequest := dbcore.DB.Where("name = ?", req.Name).Find(&existingRole)
+
+	if roleRequest.Error != nil {
+		log.Warnf("failed to find if role exists or not: %s", roleRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if role exists",
+		})
+
+		return
+	}
+
+	if roleRequest.RowsAffected > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Role already exists",
+		})
+
+		return
+	}
+
+	role := &dbcore.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: rolePermissions,
+	}
+
+	if err := dbcore.DB.Create(role).Error; err != nil {
+		log.Warnf("Failed to create role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add role into database",
+		})
+
+		return
+	}
+
+	auditEntry.SetTarget(role.ID)
+	auditEntry.SetAfter(audit.RoleSnapshot(role))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"id":      role.ID,
+	})
+}
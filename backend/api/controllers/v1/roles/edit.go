@@ -0,0 +1,233 @@
+package roles
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type RoleEditRequest struct {
+	Token           string    `validate:"required" json:"token"`
+	ID              uint      `validate:"required" json:"id"`
+	Name            *string   `json:"name"`
+	Description     *string   `json:"description"`
+	PermissionNodes *[]string `json:"permissions"` // If set, replaces every permission of the role
+}
+
+func EditRole(c *gin.Context) {
+	var req RoleEditRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "roles.edit")
+	auditEntry.SetTarget(req.ID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "permissions.see") || !permissions.UserHasPermission(user, "permissions.manage") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var role *dbcore.Role
+	roleRequest := dbcore.DB.Preload("Permissions").Where("id = ?", req.ID).Find(&role)
+
+	if roleRequest.Error != nil {
+		log.Warnf("failed to find if role exists or not: %s", roleRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if role exists",
+		})
+
+		return
+	}
+
+	roleExists := roleRequest.RowsAffected > 0
+
+	if !roleExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Role doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetBefore(audit.RoleSnapshot(role))
+
+	if req.Name != nil && *req.Name != role.Name {
+		// New users get assigned default roles by name
+		if permissions.IsDefaultRole(role.Name) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Default roles can't be renamed",
+			})
+
+			return
+		}
+
+		var existingRole *dbcore.Role
+		existingRoleRequest := dbcore.DB.Where("name = ?", *req.Name).Find(&existingRole)
+
+		if existingRoleRequest.Error != nil {
+			log.Warnf("failed to find if role exists or not: %s", existingRoleRequest.Error.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to find if role exists",
+			})
+
+			return
+		}
+
+		if existingRoleRequest.RowsAffected > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Role already exists",
+			})
+
+			return
+		}
+
+		role.Name = *req.Name
+	}
+
+	if req.Description != nil {
+		role.Description = req.Description
+	}
+
+	tx := dbcore.DB.Begin()
+
+	if err := tx.Save(role).Error; err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to update role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	if req.PermissionNodes != nil {
+		rolePermissions, ok := rolePermissionsFromNodes(*req.PermissionNodes)
+
+		if !ok {
+			tx.Rollback()
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unknown permission node",
+			})
+
+			return
+		}
+
+		if err := tx.Where("role_id = ?", role.ID).Delete(&dbcore.RolePermission{}).Error; err != nil {
+			tx.Rollback()
+			log.Warnf("Failed to remove old role permissions: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update role",
+			})
+
+			return
+		}
+
+		if len(rolePermissions) != 0 {
+			for permissionIndex := range rolePermissions {
+				rolePermissions[permissionIndex].RoleID = role.ID
+			}
+
+			if err := tx.Create(&rolePermissions).Error; err != nil {
+				tx.Rollback()
+				log.Warnf("Failed to add role permissions: %s", err.Error())
+
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to update role",
+				})
+
+				return
+			}
+		}
+
+		role.Permissions = rolePermissions
+	}
+
+	auditEntry.SetAfter(audit.RoleSnapshot(role))
+
+	canManage, err := canStillManagePermissions(tx, user.ID)
+
+	if err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to get updated permissions: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	if !canManage {
+		tx.Rollback()
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "You can't remove permissions.manage from yourself",
+		})
+
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Warnf("Failed to update role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
@@ -0,0 +1,120 @@
+package roles
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type RoleLookupRequest struct {
+	Token string  `validate:"required" json:"token"`
+	ID    *uint   `json:"id"`
+	Name  *string `json:"name"`
+}
+
+type RoleLookupResponse struct {
+	Success bool             `json:"success"`
+	Data    []*SanitizedRole `json:"data"`
+}
+
+func LookupRole(c *gin.Context) {
+	var req RoleLookupRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	if !permissions.UserHasPermission(user, "permissions.see") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	roles := []dbcore.Role{}
+	query := dbcore.DB.Preload("Permissions").Preload("Users")
+
+	if req.ID != nil {
+		query = query.Where("id = ?", *req.ID)
+	}
+
+	if req.Name != nil {
+		query = query.Where("name = ?", *req.Name)
+	}
+
+	if err := query.Find(&roles).Error; err != nil {
+		log.Warnf("Failed to get roles: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get roles",
+		})
+
+		return
+	}
+
+	sanitizedRoles := make([]*SanitizedRole, len(roles))
+
+	for roleIndex, role := range roles {
+		sanitizedRole := &SanitizedRole{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: make([]string, len(role.Permissions)),
+			Users:       make([]uint, len(role.Users)),
+		}
+
+		for permissionIndex, permission := range role.Permissions {
+			sanitizedRole.Permissions[permissionIndex] = permission.PermissionNode
+		}
+
+		for userIndex, roleUser := range role.Users {
+			sanitizedRole.Users[userIndex] = roleUser.ID
+		}
+
+		sanitizedRoles[roleIndex] = sanitizedRole
+	}
+
+	c.JSON(http.StatusOK, &RoleLookupResponse{
+		Success: true,
+		Data:    sanitizedRoles,
+	})
+}
@@ -0,0 +1,166 @@
+package roles
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type RoleRemovalRequest struct {
+	Token string `validate:"required" json:"token"`
+	ID    uint   `validate:"required" json:"id"`
+}
+
+func RemoveRole(c *gin.Context) {
+	var req RoleRemovalRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "roles.remove")
+	auditEntry.SetTarget(req.ID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "permissions.see") || !permissions.UserHasPermission(user, "permissions.manage") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var role *dbcore.Role
+	roleRequest := dbcore.DB.Preload("Permissions").Where("id = ?", req.ID).Find(&role)
+
+	if roleRequest.Error != nil {
+		log.Warnf("failed to find if role exists or not: %s", roleRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if role exists",
+		})
+
+		return
+	}
+
+	roleExists := roleRequest.RowsAffected > 0
+
+	if !roleExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Role doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetBefore(audit.RoleSnapshot(role))
+
+	// New users get assigned default roles by name
+	if permissions.IsDefaultRole(role.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Default roles can't be removed",
+		})
+
+		return
+	}
+
+	tx := dbcore.DB.Begin()
+
+	if err := tx.Select("Permissions", "Users").Delete(role).Error; err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to remove role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove role",
+		})
+
+		return
+	}
+
+	if err := tx.Where("role_id = ?", role.ID).Delete(&dbcore.BackendShare{}).Error; err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to remove backend shares of role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove role",
+		})
+
+		return
+	}
+
+	canManage, err := canStillManagePermissions(tx, user.ID)
+
+	if err != nil {
+		tx.Rollback()
+		log.Warnf("Failed to get updated permissions: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	if !canManage {
+		tx.Rollback()
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "You can't remove permissions.manage from yourself",
+		})
+
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Warnf("Failed to update role: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update role",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
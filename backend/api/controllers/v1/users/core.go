@@ -1,15 +1,65 @@
 package users
 
-import "os"
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"github.com/gin-gonic/gin"
+)
 
 var (
 	signupEnabled       bool
 	unsafeSignup        bool
 	forceNoExpiryTokens bool
+	totpRequired        bool
 )
 
 func init() {
 	signupEnabled = os.Getenv("HERMES_SIGNUP_ENABLED") != ""
 	unsafeSignup = os.Getenv("HERMES_UNSAFE_ADMIN_SIGNUP_ENABLED") != ""
 	forceNoExpiryTokens = os.Getenv("HERMES_FORCE_DISABLE_REFRESH_TOKEN_EXPIRY") != ""
+	totpRequired = os.Getenv("HERMES_REQUIRE_TOTP") != ""
+}
+
+func generateRefreshToken() (string, error) {
+	tokenRandomData := make([]byte, 80)
+
+	if _, err := rand.Read(tokenRandomData); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(tokenRandomData), nil
+}
+
+// Creates a new session for a user that has logged in. Returns the refresh token of the session, and a JWT token
+// made from it.
+func createSession(c *gin.Context, userID uint) (string, string, error) {
+	refreshToken, err := generateRefreshToken()
+
+	if err != nil {
+		return "", "", err
+	}
+
+	token := &dbcore.Token{
+		UserID: userID,
+
+		Token:          refreshToken,
+		DisableExpiry:  forceNoExpiryTokens,
+		CreationIPAddr: c.ClientIP(),
+	}
+
+	if err := dbcore.DB.Create(&token).Error; err != nil {
+		return "", "", err
+	}
+
+	jwt, err := jwtcore.Generate(userID, token.ID)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return token.Token, jwt, nil
 }
@@ -5,16 +5,14 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/go-playground/validator/v10"
 
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
-	permissionHelper "git.terah.dev/imterah/hermes/backend/api/permissions"
+	"git.terah.dev/imterah/hermes/backend/api/passwordpolicy"
 	"github.com/charmbracelet/log"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserCreationRequest struct {
@@ -78,7 +76,15 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	passwordHashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := passwordpolicy.Check(req.Password, req.Username, req.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+
+		return
+	}
+
+	passwordHashed, err := hashPassword(req.Password)
 
 	if err != nil {
 		log.Warnf("Failed to generate password for client upon signup: %s", err.Error())
@@ -90,19 +96,27 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	permissions := []dbcore.Permission{}
+	roleName := "operator"
+
+	if unsafeSignup {
+		roleName = "admin"
+	}
 
-	for _, permission := range permissionHelper.DefaultPermissionNodes {
-		permissionEnabledState := false
+	var role *dbcore.Role
+	roleRequest := dbcore.DB.Where("name = ?", roleName).Find(&role)
 
-		if unsafeSignup || strings.HasPrefix(permission, "routes.") || permission == "permissions.see" {
-			permissionEnabledState = true
+	if roleRequest.Error != nil || roleRequest.RowsAffected == 0 {
+		if roleRequest.Error != nil {
+			log.Warnf("Failed to find role '%s': %s", roleName, roleRequest.Error.Error())
+		} else {
+			log.Warnf("Failed to find role '%s'", roleName)
 		}
 
-		permissions = append(permissions, dbcore.Permission{
-			PermissionNode: permission,
-			HasPermission:  permissionEnabledState,
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find the role for new users",
 		})
+
+		return
 	}
 
 	tokenRandomData := make([]byte, 80)
@@ -118,19 +132,23 @@ func CreateUser(c *gin.Context) {
 	}
 
 	user = &dbcore.User{
-		Email:       req.Email,
-		Username:    req.Username,
-		Name:        req.Name,
-		IsBot:       &req.IsBot,
-		Password:    base64.StdEncoding.EncodeToString(passwordHashed),
-		Permissions: permissions,
-		Tokens: []dbcore.Token{
+		Email:    req.Email,
+		Username: req.Username,
+		Name:     req.Name,
+		IsBot:    &req.IsBot,
+		Password: passwordHashed,
+		Roles:    []dbcore.Role{*role},
+	}
+
+	// Users that need TOTP get their tokens once they've enrolled into it, when logging in
+	if !userNeedsTOTP(user) {
+		user.Tokens = []dbcore.Token{
 			{
 				Token:          base64.StdEncoding.EncodeToString(tokenRandomData),
 				DisableExpiry:  forceNoExpiryTokens,
 				CreationIPAddr: c.ClientIP(),
 			},
-		},
+		}
 	}
 
 	if result := dbcore.DB.Create(&user); result.Error != nil {
@@ -143,7 +161,16 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	jwt, err := jwtcore.Generate(user.ID)
+	if len(user.Tokens) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success":            true,
+			"totpEnrollRequired": true,
+		})
+
+		return
+	}
+
+	jwt, err := jwtcore.Generate(user.ID, user.Tokens[0].ID)
 
 	if err != nil {
 		log.Warnf("Failed to generate JWT: %s", err.Error())
@@ -0,0 +1,176 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type UserEditRequest struct {
+	Token    string  `validate:"required"`
+	UID      *uint   `json:"id"`
+	Name     *string `json:"name" validate:"omitempty,min=1"`
+	Email    *string `json:"email" validate:"omitempty,min=1"`
+	Username *string `json:"username" validate:"omitempty,min=1"`
+	Disabled *bool   `json:"disabled"`
+}
+
+// Edits a user. Users can edit their own name, email and username, while users with users.edit can edit anyone, and
+// disable or enable them. API keys always need users.edit, even to edit their own user.
+func EditUser(c *gin.Context) {
+	var req UserEditRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	uid := user.ID
+
+	if req.UID != nil {
+		uid = *req.UID
+	}
+
+	auditEntry := audit.Start(c, user, "users.edit")
+	auditEntry.SetTarget(uid)
+	defer auditEntry.Finish()
+
+	if (uid != user.ID || req.Disabled != nil || user.APIKey != nil) && !permissions.UserHasPermission(user, "users.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if uid == user.ID && req.Disabled != nil && *req.Disabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "You can't disable yourself",
+		})
+
+		return
+	}
+
+	var targetUser *dbcore.User
+	userRequest := dbcore.DB.Where("id = ?", uid).Find(&targetUser)
+
+	if userRequest.Error != nil {
+		log.Warnf("failed to find if user exists or not: %s", userRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if user exists",
+		})
+
+		return
+	}
+
+	userExists := userRequest.RowsAffected > 0
+
+	if !userExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User doesn't exist",
+		})
+
+		return
+	}
+
+	auditEntry.SetBefore(audit.UserSnapshot(targetUser))
+
+	if (req.Email != nil && *req.Email != targetUser.Email) || (req.Username != nil && *req.Username != targetUser.Username) {
+		email, username := targetUser.Email, targetUser.Username
+
+		if req.Email != nil {
+			email = *req.Email
+		}
+
+		if req.Username != nil {
+			username = *req.Username
+		}
+
+		var existingUser *dbcore.User
+		existingUserRequest := dbcore.DB.Where("id != ? AND (email = ? OR username = ?)", targetUser.ID, email, username).Find(&existingUser)
+
+		if existingUserRequest.Error != nil {
+			log.Warnf("failed to find if user exists or not: %s", existingUserRequest.Error.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to find if user exists",
+			})
+
+			return
+		}
+
+		if existingUserRequest.RowsAffected > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "User already exists",
+			})
+
+			return
+		}
+
+		targetUser.Email = email
+		targetUser.Username = username
+	}
+
+	if req.Name != nil {
+		targetUser.Name = *req.Name
+	}
+
+	if req.Disabled != nil {
+		targetUser.Disabled = *req.Disabled
+	}
+
+	if err := dbcore.DB.Save(targetUser).Error; err != nil {
+		log.Warnf("Failed to update user: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update user",
+		})
+
+		return
+	}
+
+	auditEntry.SetAfter(audit.UserSnapshot(targetUser))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
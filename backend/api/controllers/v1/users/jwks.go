@@ -0,0 +1,20 @@
+package users
+
+import (
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSResponse struct {
+	Keys []*jwtcore.JSONWebKey `json:"keys"`
+}
+
+// Publishes the public keys JWT tokens are signed with, so that other services can verify them. There's nothing in here
+// if tokens are signed with a secret.
+func GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, &JWKSResponse{
+		Keys: jwtcore.GetJSONWebKeys(),
+	})
+}
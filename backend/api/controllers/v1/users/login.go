@@ -1,17 +1,17 @@
 package users
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"fmt"
 	"net/http"
+	"time"
 
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
-	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/ratelimit"
+	"git.terah.dev/imterah/hermes/backend/api/totp"
 	"github.com/charmbracelet/log"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserLoginRequest struct {
@@ -19,9 +19,18 @@ type UserLoginRequest struct {
 	Email    *string
 
 	Password string `validate:"required"`
+
+	// Needed if the user has TOTP enabled, or has to enroll into it
+	TOTPCode     *string `json:"totpCode"`
+	RecoveryCode *string `json:"recoveryCode"`
 }
 
 func LoginUser(c *gin.Context) {
+	if wait := ratelimit.LoginPerIP.Take(c.ClientIP()); wait > 0 {
+		ratelimit.AbortWithRetryAfter(c, wait, "Too many login attempts, try again later")
+		return
+	}
+
 	var req UserLoginRequest
 
 	if err := c.BindJSON(&req); err != nil {
@@ -84,8 +93,17 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	decodedPassword := make([]byte, base64.StdEncoding.DecodedLen(len(user.Password)))
-	_, err := base64.StdEncoding.Decode(decodedPassword, []byte(user.Password))
+	if wait := ratelimit.LoginPerAccount.Take(fmt.Sprint(user.ID)); wait > 0 {
+		ratelimit.AbortWithRetryAfter(c, wait, "Too many login attempts, try again later")
+		return
+	}
+
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		ratelimit.AbortWithRetryAfter(c, time.Until(*user.LockedUntil), "Account is temporarily locked because of too many failed logins")
+		return
+	}
+
+	isValidPassword, err := checkPassword(user, req.Password)
 
 	if err != nil {
 		log.Warnf("failed to decode password in database: %s", err.Error())
@@ -97,9 +115,9 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword(decodedPassword, []byte(req.Password))
+	if !isValidPassword {
+		recordFailedLogin(user)
 
-	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Invalid password",
 		})
@@ -107,40 +125,123 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	tokenRandomData := make([]byte, 80)
-
-	if _, err := rand.Read(tokenRandomData); err != nil {
-		log.Warnf("Failed to read random data to use as token: %s", err.Error())
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate refresh token",
+	// Only checked once the password is right, so that it can't be used to find out which accounts are disabled
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "User is disabled",
 		})
 
 		return
 	}
 
-	token := &dbcore.Token{
-		UserID: user.ID,
+	var recoveryCodes []string
 
-		Token:          base64.StdEncoding.EncodeToString(tokenRandomData),
-		DisableExpiry:  forceNoExpiryTokens,
-		CreationIPAddr: c.ClientIP(),
-	}
+	if user.TOTPEnabled {
+		if req.TOTPCode == nil && req.RecoveryCode == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":        "Two-factor authentication code required",
+				"totpRequired": true,
+			})
 
-	if result := dbcore.DB.Create(&token); result.Error != nil {
-		log.Warnf("Failed to create user: %s", result.Error.Error())
+			return
+		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to add refresh token into database",
-		})
+		isValid, err := verifySecondFactor(user, req.TOTPCode, req.RecoveryCode)
 
-		return
+		if err != nil {
+			log.Warnf("Failed to verify second factor: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to verify two-factor authentication code",
+			})
+
+			return
+		}
+
+		if !isValid {
+			recordFailedLogin(user)
+
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid two-factor authentication code",
+			})
+
+			return
+		}
+	} else if userNeedsTOTP(user) {
+		// Users that have to use TOTP can't get a token to enroll with, so they enroll while logging in instead
+		if user.TOTPSecret == nil {
+			secret, err := totp.GenerateSecret()
+
+			if err != nil {
+				log.Warnf("Failed to generate TOTP secret: %s", err.Error())
+
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to generate TOTP secret",
+				})
+
+				return
+			}
+
+			if err := dbcore.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+				log.Warnf("Failed to save TOTP secret: %s", err.Error())
+
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to save TOTP secret",
+				})
+
+				return
+			}
+		}
+
+		if req.TOTPCode == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":              "Two-factor authentication enrollment required",
+				"totpEnrollRequired": true,
+				"totpSecret":         *user.TOTPSecret,
+				"totpURL":            totp.GetURL(user.Username, *user.TOTPSecret),
+			})
+
+			return
+		}
+
+		recoveryCodes, err = enableTOTP(user, *req.TOTPCode)
+
+		if err != nil {
+			log.Warnf("Failed to enable TOTP: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to enable two-factor authentication",
+			})
+
+			return
+		}
+
+		if recoveryCodes == nil {
+			recordFailedLogin(user)
+
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid two-factor authentication code",
+			})
+
+			return
+		}
 	}
 
-	jwt, err := jwtcore.Generate(user.ID)
+	if user.FailedLogins != 0 {
+		err := dbcore.DB.Model(user).Updates(map[string]interface{}{
+			"failed_logins": 0,
+			"locked_until":  nil,
+		}).Error
+
+		if err != nil {
+			log.Warnf("Failed to reset failed logins of user #%d: %s", user.ID, err.Error())
+		}
+	}
+
+	refreshToken, jwt, err := createSession(c, user.ID)
 
 	if err != nil {
-		log.Warnf("Failed to generate JWT: %s", err.Error())
+		log.Warnf("Failed to create session: %s", err.Error())
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to generate refresh token",
@@ -149,9 +250,34 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success":      true,
 		"token":        jwt,
-		"refreshToken": base64.StdEncoding.EncodeToString(tokenRandomData),
-	})
+		"refreshToken": refreshToken,
+	}
+
+	// Only set if the user just enrolled into TOTP
+	if recoveryCodes != nil {
+		response["recoveryCodes"] = recoveryCodes
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Counts a failed login against the user, locking their account if they've failed too many times in a row
+func recordFailedLogin(user *dbcore.User) {
+	user.FailedLogins++
+
+	updates := map[string]interface{}{
+		"failed_logins": gorm.Expr("failed_logins + 1"),
+	}
+
+	if delay := ratelimit.GetLockoutDelay(user.FailedLogins); delay > 0 {
+		updates["locked_until"] = time.Now().Add(delay)
+		log.Infof("Locking user #%d for %s after %d failed logins", user.ID, delay, user.FailedLogins)
+	}
+
+	if err := dbcore.DB.Model(user).Updates(updates).Error; err != nil {
+		log.Warnf("Failed to record failed login of user #%d: %s", user.ID, err.Error())
+	}
 }
@@ -20,6 +20,7 @@ type UserLookupRequest struct {
 	Email    *string `json:"email"`
 	Username *string `json:"username"`
 	IsBot    *bool   `json:"isServiceAccount"`
+	Disabled *bool   `json:"disabled"`
 }
 
 type SanitizedUsers struct {
@@ -28,6 +29,9 @@ type SanitizedUsers struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	IsBot    bool   `json:"isServiceAccount"`
+	Disabled bool   `json:"disabled"`
+
+	TOTPEnabled bool `json:"totpEnabled"`
 }
 
 type LookupResponse struct {
@@ -101,6 +105,11 @@ func LookupUser(c *gin.Context) {
 		queryParameters = append(queryParameters, req.IsBot)
 	}
 
+	if req.Disabled != nil {
+		queryString = append(queryString, "disabled = ?")
+		queryParameters = append(queryParameters, req.Disabled)
+	}
+
 	if err := dbcore.DB.Where(strings.Join(queryString, " AND "), queryParameters...).Find(&users).Error; err != nil {
 		log.Warnf("Failed to get users: %s", err.Error())
 
@@ -126,6 +135,9 @@ func LookupUser(c *gin.Context) {
 			Email:    user.Email,
 			Username: user.Username,
 			IsBot:    isBot,
+			Disabled: user.Disabled,
+
+			TOTPEnabled: user.TOTPEnabled,
 		}
 	}
 
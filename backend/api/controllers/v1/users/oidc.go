@@ -0,0 +1,305 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/oidc"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Sends the user to the identity provider to log in. They come back to OIDCCallback once they're done.
+func OIDCLogin(c *gin.Context) {
+	if !oidc.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Single sign-on is not enabled",
+		})
+
+		return
+	}
+
+	login, err := oidc.StartLogin()
+
+	if err != nil {
+		log.Warnf("Failed to start OIDC login: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start logging in",
+		})
+
+		return
+	}
+
+	authURL, err := oidc.GetAuthURL(login)
+
+	if err != nil {
+		log.Warnf("Failed to get OIDC authorization URL: %s", err.Error())
+
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Failed to contact the identity provider",
+		})
+
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Finishes logging in with the identity provider. Users are created the first time they log in, and their roles are
+// updated from their groups every time they do. Gives back the same tokens as LoginUser.
+func OIDCCallback(c *gin.Context) {
+	if !oidc.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Single sign-on is not enabled",
+		})
+
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Identity provider refused the login: %s %s", providerError, c.Query("error_description")),
+		})
+
+		return
+	}
+
+	login := oidc.FinishLogin(c.Query("state"))
+
+	if login == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Login has expired or doesn't exist",
+		})
+
+		return
+	}
+
+	claims, err := oidc.Exchange(login, c.Query("code"))
+
+	if err != nil {
+		log.Warnf("Failed to finish OIDC login: %s", err.Error())
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Failed to verify the login with the identity provider",
+		})
+
+		return
+	}
+
+	user, err := getOrCreateOIDCUser(claims)
+
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+
+		return
+	}
+
+	if user == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get user",
+		})
+
+		return
+	}
+
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "User is disabled",
+		})
+
+		return
+	}
+
+	refreshToken, jwt, err := createSession(c, user.ID)
+
+	if err != nil {
+		log.Warnf("Failed to create session: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to generate refresh token",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"token":        jwt,
+		"refreshToken": refreshToken,
+	})
+}
+
+func getStringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+func getGroupsClaim(claims jwt.MapClaims, name string) []string {
+	groups := []string{}
+
+	switch value := claims[name].(type) {
+	case []interface{}:
+		for _, group := range value {
+			if groupName, ok := group.(string); ok {
+				groups = append(groups, groupName)
+			}
+		}
+	case string:
+		groups = append(groups, value)
+	}
+
+	return groups
+}
+
+// Gets the user for an identity, creating or linking them if needed, and syncs their roles. Errors are meant to be
+// shown to the user, and a nil user without an error means something went wrong on our side (which gets logged).
+func getOrCreateOIDCUser(claims jwt.MapClaims) (*dbcore.User, error) {
+	config := oidc.GetConfig()
+
+	issuer := getStringClaim(claims, "iss")
+	subject := getStringClaim(claims, "sub")
+	username := getStringClaim(claims, config.UsernameClaim)
+	email := getStringClaim(claims, "email")
+	name := getStringClaim(claims, "name")
+	emailVerified, _ := claims["email_verified"].(bool)
+
+	if subject == "" {
+		return nil, fmt.Errorf("Identity provider didn't give a subject")
+	}
+
+	roleNames, managedRoleNames := oidc.GetRolesForGroups(getGroupsClaim(claims, config.GroupsClaim))
+
+	var user *dbcore.User
+
+	err := dbcore.DB.Transaction(func(tx *gorm.DB) error {
+		userRequest := tx.Preload("Roles").Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).Find(&user)
+
+		if userRequest.Error != nil {
+			return userRequest.Error
+		}
+
+		if userRequest.RowsAffected == 0 {
+			user = nil
+
+			if config.LinkByEmail && emailVerified && email != "" {
+				linkRequest := tx.Preload("Roles").Where("email = ? AND oidc_subject IS NULL", email).Find(&user)
+
+				if linkRequest.Error != nil {
+					return linkRequest.Error
+				}
+
+				if linkRequest.RowsAffected == 0 {
+					user = nil
+				} else {
+					err := tx.Model(user).Updates(map[string]interface{}{
+						"oidc_issuer":  issuer,
+						"oidc_subject": subject,
+					}).Error
+
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		// Just in time creation, for users logging in for the first time
+		if user == nil {
+			if username == "" || email == "" {
+				return fmt.Errorf("Identity provider didn't give a username or email")
+			}
+
+			var existingUser *dbcore.User
+			existingUserRequest := tx.Where("email = ? OR username = ?", email, username).Find(&existingUser)
+
+			if existingUserRequest.Error != nil {
+				return existingUserRequest.Error
+			}
+
+			if existingUserRequest.RowsAffected > 0 {
+				return fmt.Errorf("User already exists")
+			}
+
+			if name == "" {
+				name = username
+			}
+
+			isBot := false
+
+			// Users created here don't have a password, so they can only log in through the identity provider
+			user = &dbcore.User{
+				Email:       email,
+				Username:    username,
+				Name:        name,
+				IsBot:       &isBot,
+				OIDCIssuer:  &issuer,
+				OIDCSubject: &subject,
+			}
+
+			if config.DefaultRole != "" && !slices.Contains(roleNames, config.DefaultRole) {
+				roleNames = append(roleNames, config.DefaultRole)
+			}
+
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+		}
+
+		return syncOIDCRoles(tx, user, roleNames, managedRoleNames)
+	})
+
+	if err != nil {
+		// Errors made by us are meant for the user, while database errors aren't
+		if err.Error() == "User already exists" || err.Error() == "Identity provider didn't give a username or email" {
+			return nil, err
+		}
+
+		log.Warnf("Failed to get user for OIDC login: %s", err.Error())
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// Adds the roles the user should have, and removes the roles managed by the identity provider they shouldn't have
+func syncOIDCRoles(tx *gorm.DB, user *dbcore.User, roleNames, managedRoleNames []string) error {
+	roles := []dbcore.Role{}
+
+	if len(roleNames) != 0 {
+		if err := tx.Where("name IN ?", roleNames).Find(&roles).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(roles) != len(roleNames) {
+		log.Warnf("Some of the roles for OIDC user #%d don't exist (wanted %v)", user.ID, roleNames)
+	}
+
+	// Copied, as changing the association changes the roles of the user
+	currentRoles := slices.Clone(user.Roles)
+
+	for _, role := range roles {
+		if !slices.ContainsFunc(currentRoles, func(userRole dbcore.Role) bool { return userRole.ID == role.ID }) {
+			if err := tx.Model(user).Association("Roles").Append(&role); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, userRole := range currentRoles {
+		if slices.Contains(managedRoleNames, userRole.Name) && !slices.Contains(roleNames, userRole.Name) {
+			if err := tx.Model(user).Association("Roles").Delete(&userRole); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
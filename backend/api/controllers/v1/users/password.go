@@ -0,0 +1,290 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/passwordpolicy"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type PasswordChangeRequest struct {
+	Token       string `validate:"required"`
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `validate:"required"`
+	UID         uint   `json:"id" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+// Sets the password of a user, and revokes every session of theirs except for the given one (if any)
+func setPassword(uid uint, password string, currentSessionID uint) (int64, error) {
+	passwordHashed, err := hashPassword(password)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var revokedSessions int64
+
+	err = dbcore.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&dbcore.User{}).Where("id = ?", uid).Updates(map[string]interface{}{
+			"password":      passwordHashed,
+			"failed_logins": 0,
+			"locked_until":  nil,
+		}).Error
+
+		if err != nil {
+			return err
+		}
+
+		revokeRequest := tx.Where("user_id = ? AND id != ?", uid, currentSessionID).Delete(&dbcore.Token{})
+
+		if revokeRequest.Error != nil {
+			return revokeRequest.Error
+		}
+
+		revokedSessions = revokeRequest.RowsAffected
+		return nil
+	})
+
+	return revokedSessions, err
+}
+
+// Changes the password of the current user. The old password is needed, and every other session gets logged out.
+func ChangePassword(c *gin.Context) {
+	var req PasswordChangeRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, currentSession, err := jwtcore.GetUserAndSessionFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "users.password.change")
+	auditEntry.SetTarget(user.ID)
+	defer auditEntry.Finish()
+
+	// Otherwise, a leaked API key could be used to take over the account
+	if currentSession == nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "API keys can't be used to change passwords",
+		})
+
+		return
+	}
+
+	if user.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User logs in with single sign-on, and doesn't have a password",
+		})
+
+		return
+	}
+
+	isValidPassword, err := checkPassword(user, req.OldPassword)
+
+	if err != nil {
+		log.Warnf("failed to decode password in database: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to parse database result for password",
+		})
+
+		return
+	}
+
+	if !isValidPassword {
+		recordFailedLogin(user)
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Invalid password",
+		})
+
+		return
+	}
+
+	if err := passwordpolicy.Check(req.NewPassword, user.Username, user.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+
+		return
+	}
+
+	revokedSessions, err := setPassword(user.ID, req.NewPassword, currentSession.ID)
+
+	if err != nil {
+		log.Warnf("Failed to change password: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to change password",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"revoked": revokedSessions,
+	})
+}
+
+// Sets a new password for another user, for users with users.edit. Every session of the user gets logged out, and
+// their account gets unlocked.
+func ResetPassword(c *gin.Context) {
+	var req PasswordResetRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, currentSession, err := jwtcore.GetUserAndSessionFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "users.password.reset")
+	auditEntry.SetTarget(req.UID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "users.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	var targetUser *dbcore.User
+	userRequest := dbcore.DB.Where("id = ?", req.UID).Find(&targetUser)
+
+	if userRequest.Error != nil {
+		log.Warnf("failed to find if user exists or not: %s", userRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if user exists",
+		})
+
+		return
+	}
+
+	userExists := userRequest.RowsAffected > 0
+
+	if !userExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User doesn't exist",
+		})
+
+		return
+	}
+
+	// Giving users created through single sign-on a password would let them skip the identity provider, which may have
+	// disabled them
+	if targetUser.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User logs in with single sign-on, so their password can't be set",
+		})
+
+		return
+	}
+
+	if err := passwordpolicy.Check(req.NewPassword, targetUser.Username, targetUser.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+
+		return
+	}
+
+	// There's no current session when using an API key, so everything gets revoked
+	var currentSessionID uint
+
+	if currentSession != nil {
+		currentSessionID = currentSession.ID
+	}
+
+	revokedSessions, err := setPassword(targetUser.ID, req.NewPassword, currentSessionID)
+
+	if err != nil {
+		log.Warnf("Failed to reset password: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset password",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"revoked": revokedSessions,
+	})
+}
@@ -0,0 +1,35 @@
+package users
+
+import (
+	"encoding/base64"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hashes a password in the format stored in the database
+func hashPassword(password string) (string, error) {
+	passwordHashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(passwordHashed), nil
+}
+
+// Checks if a password is the password of a user. Users created through single sign-on don't have a password, so
+// nothing matches it.
+func checkPassword(user *dbcore.User, password string) (bool, error) {
+	if user.Password == "" {
+		return false, nil
+	}
+
+	decodedPassword, err := base64.StdEncoding.DecodeString(user.Password)
+
+	if err != nil {
+		return false, err
+	}
+
+	return bcrypt.CompareHashAndPassword(decodedPassword, []byte(password)) == nil, nil
+}
@@ -1,22 +1,34 @@
 package users
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/ratelimit"
+	"git.terah.dev/imterah/hermes/backend/api/refreshtokens"
 	"github.com/charmbracelet/log"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
+// Returned when the same token gets used several times at once, and another request replaced it first
+var errTokenAlreadyReplaced = errors.New("token has already been replaced")
+
 type UserRefreshRequest struct {
 	Token string `validate:"required"`
 }
 
 func RefreshUserToken(c *gin.Context) {
+	if wait := ratelimit.RefreshPerIP.Take(c.ClientIP()); wait > 0 {
+		ratelimit.AbortWithRetryAfter(c, wait, "Too many requests, try again later")
+		return
+	}
+
 	var req UserRefreshRequest
 
 	if err := c.BindJSON(&req); err != nil {
@@ -50,17 +62,58 @@ func RefreshUserToken(c *gin.Context) {
 
 	tokenExists := tokenRequest.RowsAffected > 0
 
+	// Set if a replaced token was used within the grace period, in which case tokenInDatabase is the session it belongs to
+	isReplacedToken := false
+
 	if !tokenExists {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Token not found",
-		})
+		var retiredToken *dbcore.RetiredToken
+		retiredTokenRequest := dbcore.DB.Where("token_hash = ?", refreshtokens.HashToken(req.Token)).Find(&retiredToken)
+
+		if retiredTokenRequest.Error != nil {
+			log.Warnf("failed to find if token has been replaced or not: %s", retiredTokenRequest.Error.Error())
+		} else if retiredTokenRequest.RowsAffected > 0 {
+			if refreshtokens.IsWithinReuseGracePeriod(retiredToken) {
+				sessionRequest := dbcore.DB.Where("id = ?", retiredToken.TokenID).Find(&tokenInDatabase)
+
+				if sessionRequest.Error != nil {
+					log.Warnf("failed to find session of replaced token: %s", sessionRequest.Error.Error())
+				}
+
+				isReplacedToken = sessionRequest.Error == nil && sessionRequest.RowsAffected > 0
+			}
 
+			if !isReplacedToken {
+				// Replaced tokens being used again means that someone else has a copy of the session, so we don't know
+				// which one of them is the real client anymore
+				log.Warnf("Replaced refresh token of session #%d was used again, revoking the session", retiredToken.TokenID)
+
+				if err := dbcore.DB.Delete(&dbcore.Token{}, retiredToken.TokenID).Error; err != nil {
+					log.Warnf("Failed to revoke session: %s", err.Error())
+				}
+
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "Token has already been used, so the session has been revoked",
+				})
+
+				return
+			}
+		}
+
+		if !isReplacedToken {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Token not found",
+			})
+
+			return
+		}
+	}
+
+	if wait := ratelimit.RefreshPerAccount.Take(fmt.Sprint(tokenInDatabase.UserID)); wait > 0 {
+		ratelimit.AbortWithRetryAfter(c, wait, "Too many requests, try again later")
 		return
 	}
 
-	// First, we check to make sure that the key expiry is disabled before checking if the key is expired.
-	// Then, we check if the IP addresses differ, or if it has been 7 days since the token has been created.
-	if !tokenInDatabase.DisableExpiry && (c.ClientIP() != tokenInDatabase.CreationIPAddr || time.Now().Before(tokenInDatabase.CreatedAt.Add((24*7)*time.Hour))) {
+	if !refreshtokens.IsValid(tokenInDatabase, c.ClientIP()) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Token has expired",
 		})
@@ -98,7 +151,80 @@ func RefreshUserToken(c *gin.Context) {
 		return
 	}
 
-	jwt, err := jwtcore.Generate(user.ID)
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "User is disabled",
+		})
+
+		return
+	}
+
+	now := time.Now()
+	tokenInDatabase.LastUsedAt = &now
+	tokenInDatabase.LastUsedIPAddr = c.ClientIP()
+
+	// Replaced tokens that are within the grace period get the current token of their session, which was just made
+	if refreshtokens.ShouldRotate(tokenInDatabase) && !isReplacedToken {
+		newToken, err := generateRefreshToken()
+
+		if err != nil {
+			log.Warnf("Failed to generate refresh token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to generate refresh token",
+			})
+
+			return
+		}
+
+		oldToken := tokenInDatabase.Token
+		tokenInDatabase.Token = newToken
+
+		err = dbcore.DB.Transaction(func(tx *gorm.DB) error {
+			// Only succeeds for one of the requests if the same token is used several times at once
+			tokenUpdate := tx.Model(tokenInDatabase).Where("token = ?", oldToken).Updates(map[string]interface{}{
+				"token":             newToken,
+				"last_used_at":      now,
+				"last_used_ip_addr": c.ClientIP(),
+			})
+
+			if tokenUpdate.Error != nil {
+				return tokenUpdate.Error
+			}
+
+			if tokenUpdate.RowsAffected == 0 {
+				return errTokenAlreadyReplaced
+			}
+
+			return tx.Create(&dbcore.RetiredToken{
+				TokenID:   tokenInDatabase.ID,
+				TokenHash: refreshtokens.HashToken(oldToken),
+			}).Error
+		})
+
+		if errors.Is(err, errTokenAlreadyReplaced) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Token has already been used",
+			})
+
+			return
+		} else if err != nil {
+			log.Warnf("Failed to replace refresh token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to generate refresh token",
+			})
+
+			return
+		}
+	} else if err := dbcore.DB.Model(tokenInDatabase).Updates(map[string]interface{}{
+		"last_used_at":      now,
+		"last_used_ip_addr": c.ClientIP(),
+	}).Error; err != nil {
+		log.Warnf("Failed to update last use of token: %s", err.Error())
+	}
+
+	jwt, err := jwtcore.Generate(user.ID, tokenInDatabase.ID)
 
 	if err != nil {
 		log.Warnf("Failed to generate JWT: %s", err.Error())
@@ -111,7 +237,8 @@ func RefreshUserToken(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"token":   jwt,
+		"success":      true,
+		"token":        jwt,
+		"refreshToken": tokenInDatabase.Token,
 	})
 }
@@ -0,0 +1,236 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/ratelimit"
+	"git.terah.dev/imterah/hermes/backend/api/refreshtokens"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type refreshResponse struct {
+	Error        string `json:"error"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+func setupRefreshTest(t *testing.T) {
+	t.Setenv("HERMES_JWT_SECRET", "test-secret")
+
+	if err := jwtcore.SetupJWT(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := ratelimit.SetupRateLimits(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := refreshtokens.SetupRefreshTokens(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "hermes.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := dbcore.DoDatabaseMigrations(db); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	oldDB := dbcore.DB
+	dbcore.DB = db
+
+	t.Cleanup(func() {
+		dbcore.DB = oldDB
+	})
+
+	gin.SetMode(gin.TestMode)
+}
+
+// Creates a user with a session, and returns the session
+func createTestSession(t *testing.T, name string) *dbcore.Token {
+	user := &dbcore.User{
+		Email:    name + "@example.com",
+		Username: name,
+	}
+
+	if err := dbcore.DB.Create(user).Error; err != nil {
+		t.Fatal(err.Error())
+	}
+
+	refreshToken, err := generateRefreshToken()
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	session := &dbcore.Token{
+		UserID:         user.ID,
+		Token:          refreshToken,
+		CreationIPAddr: "192.0.2.1",
+	}
+
+	if err := dbcore.DB.Create(session).Error; err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return session
+}
+
+func refreshTestToken(t *testing.T, token string) (int, *refreshResponse) {
+	body, err := json.Marshal(&UserRefreshRequest{
+		Token: token,
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/refresh", bytes.NewReader(body))
+	c.Request.RemoteAddr = "192.0.2.1:1234"
+
+	RefreshUserToken(c)
+
+	response := &refreshResponse{}
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return recorder.Code, response
+}
+
+func TestRefreshUserToken(t *testing.T) {
+	setupRefreshTest(t)
+
+	tests := []struct {
+		name           string
+		disableExpiry  bool
+		reuseOldToken  bool          // Uses the token again after it has been replaced
+		replacedAgo    time.Duration // How long ago the token was replaced, when reusing it
+		status         int
+		isRotated      bool
+		sessionRevoked bool
+	}{
+		{
+			name:      "rotated",
+			status:    http.StatusOK,
+			isRotated: true,
+		},
+		{
+			name:          "expiry disabled",
+			disableExpiry: true,
+			status:        http.StatusOK,
+		},
+		{
+			// Gets the token it was replaced with, instead of revoking the session
+			name:          "reused within the grace period",
+			reuseOldToken: true,
+			status:        http.StatusOK,
+			isRotated:     true,
+		},
+		{
+			name:           "reused after the grace period",
+			reuseOldToken:  true,
+			replacedAgo:    time.Minute,
+			status:         http.StatusForbidden,
+			sessionRevoked: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			session := createTestSession(t, test.name)
+			token := session.Token
+
+			if test.disableExpiry {
+				if err := dbcore.DB.Model(session).Update("disable_expiry", true).Error; err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+
+			if test.reuseOldToken {
+				status, response := refreshTestToken(t, token)
+
+				if status != http.StatusOK {
+					t.Fatalf("failed to refresh the token: %s", response.Error)
+				}
+
+				replacedAt := time.Now().Add(-test.replacedAgo)
+
+				if err := dbcore.DB.Model(&dbcore.RetiredToken{}).Where("token_id = ?", session.ID).Update("created_at", replacedAt).Error; err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+
+			status, response := refreshTestToken(t, token)
+
+			if status != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, status, response.Error)
+			}
+
+			currentSession := &dbcore.Token{}
+			sessionRequest := dbcore.DB.Where("id = ?", session.ID).Find(currentSession)
+
+			if sessionRequest.Error != nil {
+				t.Fatal(sessionRequest.Error.Error())
+			}
+
+			if test.sessionRevoked {
+				if sessionRequest.RowsAffected != 0 {
+					t.Fatal("session wasn't revoked")
+				}
+
+				return
+			}
+
+			if sessionRequest.RowsAffected == 0 {
+				t.Fatal("session was revoked")
+			}
+
+			if response.Token == "" {
+				t.Fatal("didn't get a JWT token")
+			}
+
+			if response.RefreshToken != currentSession.Token {
+				t.Fatal("didn't get the current refresh token of the session")
+			}
+
+			if isRotated := currentSession.Token != token; isRotated != test.isRotated {
+				t.Fatalf("expected the token being replaced to be %t", test.isRotated)
+			}
+		})
+	}
+}
+
+func TestRefreshUnknownToken(t *testing.T) {
+	setupRefreshTest(t)
+
+	refreshToken, err := generateRefreshToken()
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if status, _ := refreshTestToken(t, refreshToken); status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"git.terah.dev/imterah/hermes/backend/api/audit"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
 	"git.terah.dev/imterah/hermes/backend/api/permissions"
@@ -56,11 +57,18 @@ func RemoveUser(c *gin.Context) {
 		}
 	}
 
+	auditEntry := audit.Start(c, user, "users.remove")
+	defer auditEntry.Finish()
+
 	uid := user.ID
 
 	if req.UID != nil {
 		uid = *req.UID
+	}
 
+	auditEntry.SetTarget(uid)
+
+	if req.UID != nil {
 		if uid != user.ID && !permissions.UserHasPermission(user, "users.remove") {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Missing permissions",
@@ -97,7 +105,8 @@ func RemoveUser(c *gin.Context) {
 		}
 	}
 
-	dbcore.DB.Select("Tokens", "Permissions", "Proxys", "Backends").Where("id = ?", uid).Delete(user)
+	dbcore.DB.Select("Tokens", "APIKeys", "RecoveryCodes", "Permissions", "Roles", "Proxys", "Backends").Where("id = ?", uid).Delete(user)
+	dbcore.DB.Where("user_id = ?", uid).Delete(&dbcore.BackendShare{})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
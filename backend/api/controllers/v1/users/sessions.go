@@ -0,0 +1,309 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"git.terah.dev/imterah/hermes/backend/api/refreshtokens"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type SessionListRequest struct {
+	Token string `validate:"required"`
+	UID   *uint  `json:"id"`
+}
+
+type SessionRevokeRequest struct {
+	Token     string `validate:"required"`
+	SessionID uint   `json:"id" validate:"required"`
+}
+
+type SessionRevokeOthersRequest struct {
+	Token string `validate:"required"`
+	UID   *uint  `json:"id"`
+}
+
+type SanitizedSession struct {
+	SessionID      uint       `json:"id"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	CreationIPAddr string     `json:"creationIP"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIPAddr string     `json:"lastUsedIP,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	NeverExpires   bool       `json:"neverExpires"`
+	IsCurrent      bool       `json:"isCurrent"`
+}
+
+type SessionListResponse struct {
+	Success bool                `json:"success"`
+	Data    []*SanitizedSession `json:"data"`
+}
+
+func ListSessions(c *gin.Context) {
+	var req SessionListRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, currentSession, err := jwtcore.GetUserAndSessionFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	uid := user.ID
+
+	if req.UID != nil {
+		uid = *req.UID
+	}
+
+	// API keys always need the permission, as they aren't supposed to be able to do anything they weren't scoped to
+	if (uid != user.ID || user.APIKey != nil) && !permissions.UserHasPermission(user, "users.lookup") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	sessions := []dbcore.Token{}
+
+	if err := dbcore.DB.Where("user_id = ?", uid).Find(&sessions).Error; err != nil {
+		log.Warnf("Failed to get sessions: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get sessions",
+		})
+
+		return
+	}
+
+	sanitizedSessions := make([]*SanitizedSession, len(sessions))
+
+	for sessionIndex, session := range sessions {
+		sanitizedSessions[sessionIndex] = &SanitizedSession{
+			SessionID:      session.ID,
+			CreatedAt:      session.CreatedAt,
+			CreationIPAddr: session.CreationIPAddr,
+			LastUsedAt:     session.LastUsedAt,
+			LastUsedIPAddr: session.LastUsedIPAddr,
+			ExpiresAt:      refreshtokens.GetExpiry(&session),
+			NeverExpires:   session.DisableExpiry,
+			IsCurrent:      currentSession != nil && session.ID == currentSession.ID,
+		}
+	}
+
+	c.JSON(http.StatusOK, &SessionListResponse{
+		Success: true,
+		Data:    sanitizedSessions,
+	})
+}
+
+func RevokeSession(c *gin.Context) {
+	var req SessionRevokeRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, _, err := jwtcore.GetUserAndSessionFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "users.sessions.revoke")
+	auditEntry.SetTarget(req.SessionID)
+	defer auditEntry.Finish()
+
+	var session *dbcore.Token
+	sessionRequest := dbcore.DB.Where("id = ?", req.SessionID).Find(&session)
+
+	if sessionRequest.Error != nil {
+		log.Warnf("failed to find if session exists or not: %s", sessionRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find if session exists",
+		})
+
+		return
+	}
+
+	sessionExists := sessionRequest.RowsAffected > 0
+
+	if !sessionExists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Session doesn't exist",
+		})
+
+		return
+	}
+
+	if (session.UserID != user.ID || user.APIKey != nil) && !permissions.UserHasPermission(user, "users.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	// JWT tokens made from this session stop working as soon as it is gone
+	if err := dbcore.DB.Delete(session).Error; err != nil {
+		log.Warnf("Failed to revoke session: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke session",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// Revokes every session of the user except the one making the request. If another user is given, every one of their
+// sessions gets revoked.
+func RevokeOtherSessions(c *gin.Context) {
+	var req SessionRevokeOthersRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, currentSession, err := jwtcore.GetUserAndSessionFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "users.sessions.revokeOthers")
+	defer auditEntry.Finish()
+
+	uid := user.ID
+
+	if req.UID != nil {
+		uid = *req.UID
+	}
+
+	auditEntry.SetTarget(uid)
+
+	if (uid != user.ID || user.APIKey != nil) && !permissions.UserHasPermission(user, "users.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	// There's no current session when using an API key, so everything gets revoked
+	var currentSessionID uint
+
+	if currentSession != nil {
+		currentSessionID = currentSession.ID
+	}
+
+	revokeRequest := dbcore.DB.Where("user_id = ? AND id != ?", uid, currentSessionID).Delete(&dbcore.Token{})
+
+	if revokeRequest.Error != nil {
+		log.Warnf("Failed to revoke sessions: %s", revokeRequest.Error.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke sessions",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"revoked": revokeRequest.RowsAffected,
+	})
+}
@@ -0,0 +1,424 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"git.terah.dev/imterah/hermes/backend/api/totp"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type TOTPEnrollRequest struct {
+	Token string `validate:"required" json:"token"`
+}
+
+type TOTPVerifyRequest struct {
+	Token string `validate:"required" json:"token"`
+	Code  string `validate:"required" json:"code"`
+}
+
+type TOTPDisableRequest struct {
+	Token        string  `validate:"required" json:"token"`
+	UID          *uint   `json:"userID"`
+	Code         *string `json:"code"`
+	RecoveryCode *string `json:"recoveryCode"`
+}
+
+type TOTPRecoveryCodesRequest struct {
+	Token string `validate:"required" json:"token"`
+	Code  string `validate:"required" json:"code"`
+}
+
+// Gets the user for a request managing their own TOTP settings. Writes the response and returns nil if the user
+// can't do that.
+func getUserForTOTP(c *gin.Context, token string) *dbcore.User {
+	user, err := jwtcore.GetUserFromJWT(token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return nil
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return nil
+		}
+	}
+
+	// Otherwise, a leaked API key could be used to lock the user out of their account
+	if user.APIKey != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "API keys can't be used to manage two-factor authentication",
+		})
+
+		return nil
+	}
+
+	return user
+}
+
+// Starts enrolling the user into TOTP. The new secret only gets used once it has been confirmed with VerifyTOTP.
+func EnrollTOTP(c *gin.Context) {
+	var req TOTPEnrollRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user := getUserForTOTP(c, req.Token)
+
+	if user == nil {
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Two-factor authentication is already enabled",
+		})
+
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		log.Warnf("Failed to generate TOTP secret: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to generate TOTP secret",
+		})
+
+		return
+	}
+
+	if err := dbcore.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+		log.Warnf("Failed to save TOTP secret: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save TOTP secret",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"secret":  secret,
+		"url":     totp.GetURL(user.Username, secret),
+	})
+}
+
+// Finishes enrolling the user into TOTP, and gives them their recovery codes
+func VerifyTOTP(c *gin.Context) {
+	var req TOTPVerifyRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user := getUserForTOTP(c, req.Token)
+
+	if user == nil {
+		return
+	}
+
+	auditEntry := audit.Start(c, user, "users.totp.enable")
+	auditEntry.SetTarget(user.ID)
+	defer auditEntry.Finish()
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Two-factor authentication is already enabled",
+		})
+
+		return
+	}
+
+	if user.TOTPSecret == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Two-factor authentication enrollment hasn't been started",
+		})
+
+		return
+	}
+
+	recoveryCodes, err := enableTOTP(user, req.Code)
+
+	if err != nil {
+		log.Warnf("Failed to enable TOTP: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to enable two-factor authentication",
+		})
+
+		return
+	}
+
+	if recoveryCodes == nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Invalid two-factor authentication code",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// Turns off TOTP. Users turning it off for themselves need to give a code, while users with users.edit can turn it off
+// for other users that lost access to their authenticator and recovery codes.
+func DisableTOTP(c *gin.Context) {
+	var req TOTPDisableRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user := getUserForTOTP(c, req.Token)
+
+	if user == nil {
+		return
+	}
+
+	uid := user.ID
+
+	if req.UID != nil {
+		uid = *req.UID
+	}
+
+	auditEntry := audit.Start(c, user, "users.totp.disable")
+	auditEntry.SetTarget(uid)
+	defer auditEntry.Finish()
+
+	var targetUser *dbcore.User
+
+	if uid == user.ID {
+		targetUser = user
+	} else {
+		if !permissions.UserHasPermission(user, "users.edit") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Missing permissions",
+			})
+
+			return
+		}
+
+		userRequest := dbcore.DB.Where("id = ?", uid).Find(&targetUser)
+
+		if userRequest.Error != nil {
+			log.Warnf("failed to find if user exists or not: %s", userRequest.Error.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to find if user exists",
+			})
+
+			return
+		}
+
+		userExists := userRequest.RowsAffected > 0
+
+		if !userExists {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "User doesn't exist",
+			})
+
+			return
+		}
+	}
+
+	if !targetUser.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Two-factor authentication isn't enabled",
+		})
+
+		return
+	}
+
+	if uid == user.ID {
+		if userNeedsTOTP(user) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Two-factor authentication is required on this server",
+			})
+
+			return
+		}
+
+		isValid, err := verifySecondFactor(user, req.Code, req.RecoveryCode)
+
+		if err != nil {
+			log.Warnf("Failed to verify second factor: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to verify two-factor authentication code",
+			})
+
+			return
+		}
+
+		if !isValid {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid two-factor authentication code",
+			})
+
+			return
+		}
+	}
+
+	err := dbcore.DB.Model(&dbcore.User{}).Where("id = ?", uid).Updates(map[string]interface{}{
+		"totp_secret":    nil,
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error
+
+	if err != nil {
+		log.Warnf("Failed to disable TOTP: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to disable two-factor authentication",
+		})
+
+		return
+	}
+
+	if err := dbcore.DB.Unscoped().Where("user_id = ?", uid).Delete(&dbcore.RecoveryCode{}).Error; err != nil {
+		log.Warnf("Failed to remove recovery codes: %s", err.Error())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// Replaces the recovery codes of the user, for when they've used up or lost the old ones
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TOTPRecoveryCodesRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user := getUserForTOTP(c, req.Token)
+
+	if user == nil {
+		return
+	}
+
+	auditEntry := audit.Start(c, user, "users.totp.recoveryCodes")
+	auditEntry.SetTarget(user.ID)
+	defer auditEntry.Finish()
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Two-factor authentication isn't enabled",
+		})
+
+		return
+	}
+
+	isValid, err := verifySecondFactor(user, &req.Code, nil)
+
+	if err != nil {
+		log.Warnf("Failed to verify second factor: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to verify two-factor authentication code",
+		})
+
+		return
+	}
+
+	if !isValid {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Invalid two-factor authentication code",
+		})
+
+		return
+	}
+
+	var recoveryCodes []string
+
+	err = dbcore.DB.Transaction(func(tx *gorm.DB) error {
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+
+	if err != nil {
+		log.Warnf("Failed to generate recovery codes: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to generate recovery codes",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"recoveryCodes": recoveryCodes,
+	})
+}
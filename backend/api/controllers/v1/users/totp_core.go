@@ -0,0 +1,108 @@
+package users
+
+import (
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/totp"
+	"gorm.io/gorm"
+)
+
+// Bots log in with passwords stored in scripts, so they can't be asked for a TOTP code
+func userNeedsTOTP(user *dbcore.User) bool {
+	return totpRequired && (user.IsBot == nil || !*user.IsBot)
+}
+
+// Checks the second factor given by a user that has TOTP enabled. Both TOTP codes and recovery codes can only be used
+// once.
+func verifySecondFactor(user *dbcore.User, totpCode, recoveryCode *string) (bool, error) {
+	if totpCode != nil {
+		step, isValid := totp.Validate(*user.TOTPSecret, *totpCode, user.TOTPLastStep)
+
+		if !isValid {
+			return false, nil
+		}
+
+		// Only succeeds for one of the requests if the same code is sent several times at once
+		stepRequest := dbcore.DB.Model(&dbcore.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).Update("totp_last_step", step)
+
+		if stepRequest.Error != nil {
+			return false, stepRequest.Error
+		}
+
+		return stepRequest.RowsAffected > 0, nil
+	}
+
+	if recoveryCode != nil {
+		codeRequest := dbcore.DB.Unscoped().Where("user_id = ? AND code_hash = ?", user.ID, totp.HashRecoveryCode(*recoveryCode)).Delete(&dbcore.RecoveryCode{})
+
+		if codeRequest.Error != nil {
+			return false, codeRequest.Error
+		}
+
+		return codeRequest.RowsAffected > 0, nil
+	}
+
+	return false, nil
+}
+
+// Turns on TOTP for a user that has started enrolling, if the code matches their new secret. Returns the user's
+// recovery codes, or nil if the code is wrong.
+func enableTOTP(user *dbcore.User, code string) ([]string, error) {
+	step, isValid := totp.Validate(*user.TOTPSecret, code, 0)
+
+	if !isValid {
+		return nil, nil
+	}
+
+	var recoveryCodes []string
+
+	err := dbcore.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&dbcore.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error
+
+		if err != nil {
+			return err
+		}
+
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+
+	return recoveryCodes, nil
+}
+
+// Replaces every recovery code of a user with new ones, and returns them
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&dbcore.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := totp.GenerateRecoveryCodes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]dbcore.RecoveryCode, len(hashes))
+
+	for hashIndex, hash := range hashes {
+		recoveryCodes[hashIndex] = dbcore.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hash,
+		}
+	}
+
+	if err := tx.Create(&recoveryCodes).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,167 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+
+	"git.terah.dev/imterah/hermes/backend/api/audit"
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type UserTransferRequest struct {
+	Token      string `validate:"required"`
+	UID        uint   `json:"id" validate:"required"`
+	NewOwnerID uint   `json:"newOwnerID" validate:"required"`
+}
+
+// Gives every backend and proxy of a user to another user, ex. before removing someone that has left. Running backends
+// and proxies keep running.
+func TransferOwnership(c *gin.Context) {
+	var req UserTransferRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to parse body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to validate body: %s", err.Error()),
+		})
+
+		return
+	}
+
+	user, err := jwtcore.GetUserFromJWT(req.Token)
+
+	if err != nil {
+		if err.Error() == "token is expired" || err.Error() == "user does not exist" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+
+			return
+		} else {
+			log.Warnf("Failed to get user from the provided JWT token: %s", err.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to parse token",
+			})
+
+			return
+		}
+	}
+
+	auditEntry := audit.Start(c, user, "users.transfer")
+	auditEntry.SetTarget(req.UID)
+	defer auditEntry.Finish()
+
+	if !permissions.UserHasPermission(user, "users.edit") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Missing permissions",
+		})
+
+		return
+	}
+
+	if req.UID == req.NewOwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User already owns everything of theirs",
+		})
+
+		return
+	}
+
+	for _, uid := range []uint{req.UID, req.NewOwnerID} {
+		var targetUser *dbcore.User
+		userRequest := dbcore.DB.Where("id = ?", uid).Find(&targetUser)
+
+		if userRequest.Error != nil {
+			log.Warnf("failed to find if user exists or not: %s", userRequest.Error.Error())
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to find if user exists",
+			})
+
+			return
+		}
+
+		userExists := userRequest.RowsAffected > 0
+
+		if !userExists {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("User #%d doesn't exist", uid),
+			})
+
+			return
+		}
+
+		if uid == req.NewOwnerID && targetUser.Disabled {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "New owner is disabled",
+			})
+
+			return
+		}
+	}
+
+	var backendsTransferred, proxiesTransferred int64
+
+	err = dbcore.DB.Transaction(func(tx *gorm.DB) error {
+		// The new owner doesn't need a share for backends they now own
+		ownedBackendIDs := tx.Model(&dbcore.Backend{}).Select("id").Where("user_id = ?", req.UID)
+		shareRequest := tx.Where("user_id = ? AND backend_id IN (?)", req.NewOwnerID, ownedBackendIDs).Delete(&dbcore.BackendShare{})
+
+		if shareRequest.Error != nil {
+			return shareRequest.Error
+		}
+
+		backendRequest := tx.Model(&dbcore.Backend{}).Where("user_id = ?", req.UID).Update("user_id", req.NewOwnerID)
+
+		if backendRequest.Error != nil {
+			return backendRequest.Error
+		}
+
+		proxyRequest := tx.Model(&dbcore.Proxy{}).Where("user_id = ?", req.UID).Update("user_id", req.NewOwnerID)
+
+		if proxyRequest.Error != nil {
+			return proxyRequest.Error
+		}
+
+		backendsTransferred = backendRequest.RowsAffected
+		proxiesTransferred = proxyRequest.RowsAffected
+
+		return nil
+	})
+
+	if err != nil {
+		log.Warnf("Failed to transfer ownership: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to transfer ownership",
+		})
+
+		return
+	}
+
+	auditEntry.SetAfter(map[string]interface{}{
+		"newOwnerID": req.NewOwnerID,
+		"backends":   backendsTransferred,
+		"proxies":    proxiesTransferred,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"backends": backendsTransferred,
+		"proxies":  proxiesTransferred,
+	})
+}
@@ -3,6 +3,7 @@ package dbcore
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -18,8 +19,19 @@ type Backend struct {
 	Description       *string
 	Backend           string
 	BackendParameters string
+	AutoStart         bool `gorm:"default:true"` // If false, the backend has been stopped, and isn't started with the API
 
 	Proxies []Proxy
+	Shares  []BackendShare
+}
+
+// Lets a user, or everyone with a role, use a backend they don't own. Only one of UserID and RoleID is set.
+type BackendShare struct {
+	gorm.Model
+
+	BackendID uint
+	UserID    *uint
+	RoleID    *uint
 }
 
 type Proxy struct {
@@ -37,6 +49,7 @@ type Proxy struct {
 	AutoStart       bool
 }
 
+// Permissions of a user override whatever their roles grant
 type Permission struct {
 	gorm.Model
 
@@ -45,6 +58,23 @@ type Permission struct {
 	UserID         uint
 }
 
+type RolePermission struct {
+	gorm.Model
+
+	PermissionNode string
+	RoleID         uint
+}
+
+type Role struct {
+	gorm.Model
+
+	Name        string `gorm:"unique"`
+	Description *string
+
+	Permissions []RolePermission
+	Users       []User `gorm:"many2many:user_roles;"`
+}
+
 type Token struct {
 	gorm.Model
 
@@ -53,6 +83,65 @@ type Token struct {
 	Token          string
 	DisableExpiry  bool
 	CreationIPAddr string
+	LastUsedAt     *time.Time // Last time the token was used to get a JWT token
+	LastUsedIPAddr string
+}
+
+// Refresh token that has been replaced by a new one for the same session. Only a hash of it is kept, to detect when
+// it gets used again.
+type RetiredToken struct {
+	gorm.Model
+
+	TokenID   uint   `gorm:"index"`
+	TokenHash string `gorm:"unique"`
+}
+
+// Long-lived keys for automation, which can be used in place of a JWT token. Only a hash of the key is stored.
+type APIKey struct {
+	gorm.Model
+
+	UserID uint
+
+	Name       string
+	KeyHash    string `gorm:"unique"`
+	KeyPrefix  string // Start of the key, so that users can tell their keys apart
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+
+	Permissions []APIKeyPermission
+}
+
+// Permission nodes an API key is limited to. The user still needs to have them for the key to be able to use them.
+type APIKeyPermission struct {
+	gorm.Model
+
+	PermissionNode string
+	APIKeyID       uint
+}
+
+// Single-use codes that can be used in place of a TOTP code, in case the user loses their authenticator
+type RecoveryCode struct {
+	gorm.Model
+
+	UserID   uint
+	CodeHash string
+}
+
+type AuditEntry struct {
+	gorm.Model
+
+	UserID    uint  `gorm:"index"`
+	SessionID *uint // Set if the user authenticated with a JWT token made from a session
+	APIKeyID  *uint // Set if the user authenticated with an API key
+	ClientIP  string
+
+	Action   string  `gorm:"index"` // ex. forward.remove
+	TargetID *uint   `gorm:"index"`
+	Before   *string // JSON of what changed, before and after the action
+	After    *string
+
+	StatusCode int
+	Success    bool
 }
 
 type User struct {
@@ -63,11 +152,29 @@ type User struct {
 	Name     string
 	Password string
 	IsBot    *bool
+	Disabled bool // Disabled users can't log in, and every token of theirs stops working
+
+	TOTPSecret   *string // Set once the user starts enrolling, but only used for logging in once TOTPEnabled is set
+	TOTPEnabled  bool
+	TOTPLastStep int64 // Last step a TOTP code was used from, so that codes can't be used twice
+
+	FailedLogins int // Failed logins in a row, which lock the account for longer and longer
+	LockedUntil  *time.Time
+
+	// Identity of the user at the single sign-on identity provider, if they've logged in with it
+	OIDCIssuer  *string `gorm:"column:oidc_issuer;uniqueIndex:idx_users_oidc_identity"`
+	OIDCSubject *string `gorm:"column:oidc_subject;uniqueIndex:idx_users_oidc_identity"`
 
 	Permissions   []Permission
+	Roles         []Role `gorm:"many2many:user_roles;"`
 	OwnedProxies  []Proxy
 	OwnedBackends []Backend
 	Tokens        []Token
+	APIKeys       []APIKey
+	RecoveryCodes []RecoveryCode
+
+	APIKey  *APIKey `gorm:"-"` // API key the user authenticated with, if any. Limits the permissions of the user
+	Session *Token  `gorm:"-"` // Session the user authenticated with, if any
 }
 
 var DB *gorm.DB
@@ -126,6 +233,10 @@ func DoDatabaseMigrations(db *gorm.DB) error {
 		return err
 	}
 
+	if err := db.AutoMigrate(&BackendShare{}); err != nil {
+		return err
+	}
+
 	if err := db.AutoMigrate(&Permission{}); err != nil {
 		return err
 	}
@@ -134,9 +245,46 @@ func DoDatabaseMigrations(db *gorm.DB) error {
 		return err
 	}
 
+	if err := db.AutoMigrate(&RetiredToken{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&APIKey{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&APIKeyPermission{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&RecoveryCode{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&AuditEntry{}); err != nil {
+		return err
+	}
+
+	// Checked before migrating, as databases from before roles existed need their permissions migrated
+	hasRoles := db.Migrator().HasTable(&Role{})
+
+	if err := db.AutoMigrate(&Role{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&RolePermission{}); err != nil {
+		return err
+	}
+
 	if err := db.AutoMigrate(&User{}); err != nil {
 		return err
 	}
 
+	if !hasRoles {
+		if err := migrateToRoles(db); err != nil {
+			return fmt.Errorf("failed to migrate permissions to roles: %s", err.Error())
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,152 @@
+package dbcore
+
+import (
+	"gorm.io/gorm"
+)
+
+type DefaultRole struct {
+	Name            string
+	Description     string
+	PermissionNodes []string // If nil, the role holds every permission node
+}
+
+var DefaultRoles []DefaultRole = []DefaultRole{
+	{
+		Name:        "viewer",
+		Description: "Can see their own forward rules and backends, but can't change anything",
+		PermissionNodes: []string{
+			"routes.visible",
+			"backends.visible",
+			"permissions.see",
+		},
+	},
+	{
+		Name:        "operator",
+		Description: "Can manage their own forward rules, on backends shared with them",
+		PermissionNodes: []string{
+			"routes.add",
+			"routes.remove",
+			"routes.start",
+			"routes.stop",
+			"routes.edit",
+			"routes.visible",
+			"routes.visibleConn",
+			"backends.visible",
+			"permissions.see",
+		},
+	},
+	{
+		Name:        "admin",
+		Description: "Can do everything",
+	},
+}
+
+// Databases from before roles existed store every permission node on every user. Each user gets the default role
+// that is the closest to what they have, and only the permissions that differ from it are kept, as overrides.
+func migrateToRoles(db *gorm.DB) error {
+	allNodes := []string{}
+
+	if err := db.Model(&Permission{}).Distinct().Pluck("permission_node", &allNodes).Error; err != nil {
+		return err
+	}
+
+	roles := make([]*Role, len(DefaultRoles))
+	roleNodes := make([]map[string]bool, len(DefaultRoles))
+
+	for roleIndex, defaultRole := range DefaultRoles {
+		nodes := defaultRole.PermissionNodes
+
+		if nodes == nil {
+			nodes = allNodes
+		}
+
+		description := defaultRole.Description
+
+		role := &Role{
+			Name:        defaultRole.Name,
+			Description: &description,
+			Permissions: make([]RolePermission, len(nodes)),
+		}
+
+		roleNodes[roleIndex] = map[string]bool{}
+
+		for nodeIndex, node := range nodes {
+			role.Permissions[nodeIndex] = RolePermission{
+				PermissionNode: node,
+			}
+
+			roleNodes[roleIndex][node] = true
+		}
+
+		if err := db.Create(role).Error; err != nil {
+			return err
+		}
+
+		roles[roleIndex] = role
+	}
+
+	users := []User{}
+
+	if err := db.Preload("Permissions").Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		// Start with no role at all, where every granted permission has to be an override
+		bestRoleIndex := -1
+		bestRoleOverrides := 0
+
+		for _, permission := range user.Permissions {
+			if permission.HasPermission {
+				bestRoleOverrides++
+			}
+		}
+
+		for roleIndex, nodes := range roleNodes {
+			overrides := 0
+
+			for _, permission := range user.Permissions {
+				if permission.HasPermission != nodes[permission.PermissionNode] {
+					overrides++
+				}
+			}
+
+			if overrides < bestRoleOverrides {
+				bestRoleIndex = roleIndex
+				bestRoleOverrides = overrides
+			}
+		}
+
+		grantedNodes := map[string]bool{}
+
+		if bestRoleIndex != -1 {
+			grantedNodes = roleNodes[bestRoleIndex]
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if bestRoleIndex != -1 {
+				if err := tx.Model(&user).Association("Roles").Append(roles[bestRoleIndex]); err != nil {
+					return err
+				}
+			}
+
+			for _, permission := range user.Permissions {
+				if permission.HasPermission != grantedNodes[permission.PermissionNode] {
+					continue
+				}
+
+				if err := tx.Delete(&permission).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
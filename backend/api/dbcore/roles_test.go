@@ -0,0 +1,200 @@
+package dbcore
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Users as they were stored before roles existed, with every permission node stored on the user
+type preRolesUser struct {
+	gorm.Model
+
+	Email       string       `gorm:"unique"`
+	Username    string       `gorm:"unique"`
+	Permissions []Permission `gorm:"foreignKey:UserID"`
+}
+
+func (preRolesUser) TableName() string {
+	return "users"
+}
+
+var testPermissionNodes = []string{
+	"routes.add",
+	"routes.remove",
+	"routes.start",
+	"routes.stop",
+	"routes.edit",
+	"routes.visible",
+	"routes.visibleConn",
+	"backends.add",
+	"backends.remove",
+	"backends.visible",
+	"permissions.see",
+	"users.add",
+	"users.lookup",
+	"users.edit",
+}
+
+// Creates a database from before roles existed, with a user that has (only) the given permission nodes, and migrates it
+func migrateTestUser(t *testing.T, grantedNodes []string) *User {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "hermes.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := db.AutoMigrate(&Permission{}, &preRolesUser{}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	user := &preRolesUser{
+		Email:    "test@example.com",
+		Username: "test",
+	}
+
+	for _, node := range testPermissionNodes {
+		user.Permissions = append(user.Permissions, Permission{
+			PermissionNode: node,
+			HasPermission:  containsNode(grantedNodes, node),
+		})
+	}
+
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := DoDatabaseMigrations(db); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	migratedUser := &User{}
+
+	if err := db.Preload("Permissions").Preload("Roles").First(migratedUser, user.ID).Error; err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return migratedUser
+}
+
+func containsNode(nodes []string, node string) bool {
+	for _, currentNode := range nodes {
+		if currentNode == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+func exceptNodes(nodes ...string) []string {
+	remainingNodes := []string{}
+
+	for _, node := range testPermissionNodes {
+		if !containsNode(nodes, node) {
+			remainingNodes = append(remainingNodes, node)
+		}
+	}
+
+	return remainingNodes
+}
+
+func TestMigrateToRoles(t *testing.T) {
+	viewerNodes := DefaultRoles[0].PermissionNodes
+	operatorNodes := DefaultRoles[1].PermissionNodes
+
+	tests := []struct {
+		name         string
+		grantedNodes []string
+		role         string   // Empty if the user shouldn't get a role
+		overrides    []string // Permissions kept on the user, as "+node" (granted) or "-node" (taken away)
+	}{
+		{
+			name:         "every node",
+			grantedNodes: testPermissionNodes,
+			role:         "admin",
+		},
+		{
+			name:         "viewer",
+			grantedNodes: viewerNodes,
+			role:         "viewer",
+		},
+		{
+			name:         "operator",
+			grantedNodes: operatorNodes,
+			role:         "operator",
+		},
+		{
+			name:         "operator with an extra node",
+			grantedNodes: append([]string{"users.lookup"}, operatorNodes...),
+			role:         "operator",
+			overrides:    []string{"+users.lookup"},
+		},
+		{
+			name:         "viewer missing a node",
+			grantedNodes: []string{"routes.visible", "backends.visible"},
+			role:         "viewer",
+			overrides:    []string{"-permissions.see"},
+		},
+		{
+			name:         "admin missing a node",
+			grantedNodes: exceptNodes("users.edit"),
+			role:         "admin",
+			overrides:    []string{"-users.edit"},
+		},
+		{
+			name:         "nothing",
+			grantedNodes: []string{},
+		},
+		{
+			// Any role would need more overrides than granting the one node directly
+			name:         "single unrelated node",
+			grantedNodes: []string{"users.add"},
+			overrides:    []string{"+users.add"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := migrateTestUser(t, test.grantedNodes)
+
+			roles := []string{}
+
+			for _, role := range user.Roles {
+				roles = append(roles, role.Name)
+			}
+
+			if test.role == "" && len(roles) != 0 {
+				t.Fatalf("expected no role, got: %s", strings.Join(roles, ", "))
+			} else if test.role != "" && (len(roles) != 1 || roles[0] != test.role) {
+				t.Fatalf("expected role %s, got: %s", test.role, strings.Join(roles, ", "))
+			}
+
+			overrides := []string{}
+
+			for _, permission := range user.Permissions {
+				if permission.HasPermission {
+					overrides = append(overrides, "+"+permission.PermissionNode)
+				} else {
+					overrides = append(overrides, "-"+permission.PermissionNode)
+				}
+			}
+
+			expectedOverrides := append([]string{}, test.overrides...)
+
+			sort.Strings(overrides)
+			sort.Strings(expectedOverrides)
+
+			if strings.Join(overrides, ",") != strings.Join(expectedOverrides, ",") {
+				t.Fatalf("expected overrides [%s], got [%s]", strings.Join(expectedOverrides, ", "), strings.Join(overrides, ", "))
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package jwtcore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"github.com/charmbracelet/log"
+)
+
+// API keys start with this, which is how they're told apart from JWT tokens
+const APIKeyPrefix = "hermes_"
+
+// How often the last use of an API key gets saved, so that busy keys don't write to the database on every request
+const apiKeyLastUsedResolution = time.Minute
+
+// API keys are random enough that a plain hash is enough to store them safely, which keeps lookups cheap
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// Generates a new API key. Returns the key, which should only be shown once, and its hash.
+func GenerateAPIKey() (string, string, error) {
+	keyRandomData := make([]byte, 32)
+
+	if _, err := rand.Read(keyRandomData); err != nil {
+		return "", "", err
+	}
+
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(keyRandomData)
+
+	return key, HashAPIKey(key), nil
+}
+
+func getUserFromAPIKey(key string) (*dbcore.User, error) {
+	apiKey := &dbcore.APIKey{}
+	apiKeyRequest := dbcore.DB.Preload("Permissions").Where("key_hash = ?", HashAPIKey(key)).Find(&apiKey)
+
+	if apiKeyRequest.Error != nil {
+		return nil, fmt.Errorf("failed to find if API key exists or not: %s", apiKeyRequest.Error.Error())
+	}
+
+	apiKeyExists := apiKeyRequest.RowsAffected > 0
+
+	// Revoked keys don't exist anymore, so they look the same as expired ones
+	if !apiKeyExists {
+		return nil, fmt.Errorf("token is expired")
+	}
+
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return nil, fmt.Errorf("token is expired")
+	}
+
+	user := &dbcore.User{}
+	userRequest := dbcore.DB.Preload("Permissions").Preload("Roles.Permissions").Where("id = ?", apiKey.UserID).Find(&user)
+
+	if userRequest.Error != nil {
+		return user, fmt.Errorf("failed to find if user exists or not: %s", userRequest.Error.Error())
+	}
+
+	userExists := userRequest.RowsAffected > 0
+
+	if !userExists {
+		return user, fmt.Errorf("user does not exist")
+	}
+
+	// Disabling a user is the same as revoking every token of theirs, without losing them if the user gets enabled again
+	if user.Disabled {
+		return user, fmt.Errorf("token is expired")
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > apiKeyLastUsedResolution {
+		if err := dbcore.DB.Model(apiKey).Update("last_used_at", time.Now()).Error; err != nil {
+			log.Warnf("Failed to update last use of API key #%d: %s", apiKey.ID, err.Error())
+		}
+	}
+
+	user.APIKey = apiKey
+
+	return user, nil
+}
@@ -0,0 +1,152 @@
+package jwtcore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDatabase(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "hermes.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := dbcore.DoDatabaseMigrations(db); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	oldDB := dbcore.DB
+	dbcore.DB = db
+
+	t.Cleanup(func() {
+		dbcore.DB = oldDB
+	})
+}
+
+func TestGetUserFromAPIKey(t *testing.T) {
+	setupTestDatabase(t)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		userDisabled bool
+		expiresAt    *time.Time
+		isRevoked    bool
+		err          string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name:      "not expired yet",
+			expiresAt: &future,
+		},
+		{
+			name:      "expired",
+			expiresAt: &past,
+			err:       "token is expired",
+		},
+		{
+			name:      "revoked",
+			isRevoked: true,
+			err:       "token is expired",
+		},
+		{
+			name:         "disabled user",
+			userDisabled: true,
+			err:          "token is expired",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &dbcore.User{
+				Email:    test.name + "@example.com",
+				Username: test.name,
+				Disabled: test.userDisabled,
+			}
+
+			if err := dbcore.DB.Create(user).Error; err != nil {
+				t.Fatal(err.Error())
+			}
+
+			key, keyHash, err := GenerateAPIKey()
+
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			apiKey := &dbcore.APIKey{
+				UserID:    user.ID,
+				Name:      test.name,
+				KeyHash:   keyHash,
+				ExpiresAt: test.expiresAt,
+				Permissions: []dbcore.APIKeyPermission{
+					{PermissionNode: "routes.visible"},
+				},
+			}
+
+			if err := dbcore.DB.Create(apiKey).Error; err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if test.isRevoked {
+				if err := dbcore.DB.Delete(apiKey).Error; err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+
+			keyUser, session, err := GetUserAndSessionFromJWT(key)
+
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error '%s', got: %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if keyUser.ID != user.ID {
+				t.Fatalf("got user #%d instead of #%d", keyUser.ID, user.ID)
+			}
+
+			// API keys don't have sessions, which is how the endpoints that API keys can't use tell them apart
+			if session != nil {
+				t.Fatal("got a session for an API key")
+			}
+
+			if keyUser.APIKey == nil || len(keyUser.APIKey.Permissions) != 1 || keyUser.APIKey.Permissions[0].PermissionNode != "routes.visible" {
+				t.Fatal("API key permissions weren't loaded onto the user")
+			}
+		})
+	}
+}
+
+func TestGetUserFromUnknownAPIKey(t *testing.T) {
+	setupTestDatabase(t)
+
+	key, _, err := GenerateAPIKey()
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := GetUserFromJWT(key); err == nil || err.Error() != "token is expired" {
+		t.Fatalf("expected the unknown key to be rejected, got: %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 package jwtcore
 
 import (
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
@@ -13,97 +14,225 @@ import (
 )
 
 var (
-	JWTKey          []byte
-	developmentMode bool
+	// How long JWT tokens last for
+	Lifetime time.Duration
+
+	currentKey       *key
+	verificationKeys []*key
+	validMethods     []string
 )
 
+// Sets up the keys used to sign and verify JWT tokens. Old keys (or secrets) can be kept around for verification only,
+// so that changing the key doesn't log everyone out.
 func SetupJWT() error {
 	var err error
-	jwtDataString := os.Getenv("HERMES_JWT_SECRET")
 
-	if jwtDataString == "" {
-		return fmt.Errorf("JWT secret isn't set (missing HERMES_JWT_SECRET)")
+	Lifetime = 3 * time.Minute
+
+	if os.Getenv("HERMES_DEVELOPMENT_MODE") != "" {
+		Lifetime = 24 * time.Hour
 	}
 
-	if os.Getenv("HERMES_JWT_BASE64_ENCODED") != "" {
-		JWTKey, err = base64.StdEncoding.DecodeString(jwtDataString)
+	if lifetimeString := os.Getenv("HERMES_JWT_LIFETIME"); lifetimeString != "" {
+		Lifetime, err = time.ParseDuration(lifetimeString)
+
+		if err != nil || Lifetime <= 0 {
+			return fmt.Errorf("invalid JWT lifetime (HERMES_JWT_LIFETIME): '%s'", lifetimeString)
+		}
+	}
+
+	algorithm := os.Getenv("HERMES_JWT_ALGORITHM")
+	jwtSecret := os.Getenv("HERMES_JWT_SECRET")
+	verificationKeys = []*key{}
+
+	switch algorithm {
+	case "", jwt.SigningMethodHS256.Alg():
+		if jwtSecret == "" {
+			return fmt.Errorf("JWT secret isn't set (missing HERMES_JWT_SECRET)")
+		}
+
+		if currentKey, err = newSecretKey(jwtSecret); err != nil {
+			return err
+		}
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+		privateKeyPath := os.Getenv("HERMES_JWT_PRIVATE_KEY_FILE")
+
+		if privateKeyPath == "" {
+			return fmt.Errorf("JWT private key isn't set (missing HERMES_JWT_PRIVATE_KEY_FILE)")
+		}
+
+		if currentKey, err = readKeyFile(privateKeyPath); err != nil {
+			return fmt.Errorf("failed to read JWT private key: %s", err.Error())
+		}
+
+		if currentKey.SigningKey == nil {
+			return fmt.Errorf("JWT private key is a public key")
+		}
+
+		if currentKey.Method.Alg() != algorithm {
+			return fmt.Errorf("JWT private key can't be used for %s, as it is meant for %s", algorithm, currentKey.Method.Alg())
+		}
+
+		// Tokens signed with the secret from before switching algorithms keep working until they expire
+		if jwtSecret != "" {
+			secretKey, err := newSecretKey(jwtSecret)
+
+			if err != nil {
+				return err
+			}
+
+			verificationKeys = append(verificationKeys, secretKey)
+		}
+	default:
+		return fmt.Errorf("unsupported JWT algorithm (HERMES_JWT_ALGORITHM): '%s'", algorithm)
+	}
+
+	verificationKeys = append(verificationKeys, currentKey)
+
+	for _, oldSecret := range splitList(os.Getenv("HERMES_JWT_OLD_SECRETS")) {
+		secretKey, err := newSecretKey(oldSecret)
 
 		if err != nil {
-			return fmt.Errorf("failed to decode base64 JWT: %s", err.Error())
+			return err
 		}
-	} else {
-		JWTKey = []byte(jwtDataString)
+
+		verificationKeys = append(verificationKeys, secretKey)
 	}
 
-	if os.Getenv("HERMES_DEVELOPMENT_MODE") != "" {
-		developmentMode = true
+	for _, oldKeyPath := range splitList(os.Getenv("HERMES_JWT_OLD_KEY_FILES")) {
+		oldKey, err := readKeyFile(oldKeyPath)
+
+		if err != nil {
+			return fmt.Errorf("failed to read old JWT key: %s", err.Error())
+		}
+
+		verificationKeys = append(verificationKeys, oldKey)
+	}
+
+	validMethods = []string{}
+
+	for _, verificationKey := range verificationKeys {
+		if !slices.Contains(validMethods, verificationKey.Method.Alg()) {
+			validMethods = append(validMethods, verificationKey.Method.Alg())
+		}
 	}
 
 	return nil
 }
 
 func Parse(tokenString string, options ...jwt.ParserOption) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, JWTKeyCallback, options...)
+	return jwt.Parse(tokenString, JWTKeyCallback, append([]jwt.ParserOption{jwt.WithValidMethods(validMethods)}, options...)...)
 }
 
 func GetUserFromJWT(token string) (*dbcore.User, error) {
+	user, _, err := GetUserAndSessionFromJWT(token)
+	return user, err
+}
+
+// Gets the user of a JWT token, and the session (refresh token) it was made from. If the session has been revoked, the
+// token is treated like it is expired. API keys are accepted too, in which case there is no session.
+func GetUserAndSessionFromJWT(token string) (*dbcore.User, *dbcore.Token, error) {
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		user, err := getUserFromAPIKey(token)
+		return user, nil, err
+	}
+
 	parsedJWT, err := Parse(token)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, fmt.Errorf("token is expired")
+			return nil, nil, fmt.Errorf("token is expired")
 		} else {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	audience, err := parsedJWT.Claims.GetAudience()
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(audience) < 1 {
-		return nil, fmt.Errorf("audience is too small")
+		return nil, nil, fmt.Errorf("audience is too small")
 	}
 
 	uid, err := strconv.Atoi(audience[0])
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	claims, ok := parsedJWT.Claims.(jwt.MapClaims)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("failed to get claims")
+	}
+
+	sessionIDString, ok := claims["jti"].(string)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("token is expired")
+	}
+
+	sessionID, err := strconv.Atoi(sessionIDString)
+
+	if err != nil {
+		return nil, nil, err
 	}
 
 	user := &dbcore.User{}
-	userRequest := dbcore.DB.Preload("Permissions").Where("id = ?", uint(uid)).Find(&user)
+	userRequest := dbcore.DB.Preload("Permissions").Preload("Roles.Permissions").Where("id = ?", uint(uid)).Find(&user)
 
 	if userRequest.Error != nil {
-		return user, fmt.Errorf("failed to find if user exists or not: %s", userRequest.Error.Error())
+		return user, nil, fmt.Errorf("failed to find if user exists or not: %s", userRequest.Error.Error())
 	}
 
 	userExists := userRequest.RowsAffected > 0
 
 	if !userExists {
-		return user, fmt.Errorf("user does not exist")
+		return user, nil, fmt.Errorf("user does not exist")
 	}
 
-	return user, nil
-}
+	// Disabling a user is the same as revoking every token of theirs, without losing them if the user gets enabled again
+	if user.Disabled {
+		return user, nil, fmt.Errorf("token is expired")
+	}
 
-func Generate(uid uint) (string, error) {
-	timeMultiplier := 3
+	session := &dbcore.Token{}
+	sessionRequest := dbcore.DB.Where("id = ? AND user_id = ?", uint(sessionID), user.ID).Find(&session)
 
-	if developmentMode {
-		timeMultiplier = 60 * 24
+	if sessionRequest.Error != nil {
+		return user, nil, fmt.Errorf("failed to find if session exists or not: %s", sessionRequest.Error.Error())
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(timeMultiplier) * time.Minute)),
+	sessionExists := sessionRequest.RowsAffected > 0
+
+	if !sessionExists {
+		return user, nil, fmt.Errorf("token is expired")
+	}
+
+	user.Session = session
+
+	return user, session, nil
+}
+
+// Generates a JWT token for a user. The session ID is the ID of the refresh token used to get it, so that revoking the
+// refresh token also revokes the JWT tokens made from it.
+func Generate(uid, sessionID uint) (string, error) {
+	token := jwt.NewWithClaims(currentKey.Method, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(Lifetime)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 		NotBefore: jwt.NewNumericDate(time.Now()),
 		Audience:  []string{strconv.Itoa(int(uid))},
+		ID:        strconv.Itoa(int(sessionID)),
 	})
 
-	signedToken, err := token.SignedString(JWTKey)
+	if currentKey.KeyID != "" {
+		token.Header["kid"] = currentKey.KeyID
+	}
+
+	signedToken, err := token.SignedString(currentKey.SigningKey)
 
 	if err != nil {
 		return "", err
@@ -112,6 +241,29 @@ func Generate(uid uint) (string, error) {
 	return signedToken, nil
 }
 
-func JWTKeyCallback(*jwt.Token) (interface{}, error) {
-	return JWTKey, nil
+// Gets the keys a token could have been signed with. Tokens without a key ID (ex. ones signed with a secret) get checked
+// against every key using the same algorithm.
+func JWTKeyCallback(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	keys := []jwt.VerificationKey{}
+
+	for _, verificationKey := range verificationKeys {
+		if verificationKey.Method.Alg() != token.Method.Alg() {
+			continue
+		}
+
+		if keyID != "" && verificationKey.KeyID != "" && verificationKey.KeyID != keyID {
+			continue
+		}
+
+		keys = append(keys, verificationKey.VerificationKey)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key found to verify token with")
+	}
+
+	return jwt.VerificationKeySet{
+		Keys: keys,
+	}, nil
 }
@@ -0,0 +1,219 @@
+package jwtcore
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type key struct {
+	// Only set for asymmetric keys, as those are the only ones that get published
+	KeyID  string
+	Method jwt.SigningMethod
+
+	// Private key (or secret) used to sign tokens. Only set for the current key.
+	SigningKey interface{}
+	// Public key (or secret) used to verify tokens
+	VerificationKey interface{}
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	if os.Getenv("HERMES_JWT_BASE64_ENCODED") == "" {
+		return []byte(secret), nil
+	}
+
+	decodedSecret, err := base64.StdEncoding.DecodeString(secret)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 JWT: %s", err.Error())
+	}
+
+	return decodedSecret, nil
+}
+
+func newSecretKey(secret string) (*key, error) {
+	decodedSecret, err := decodeSecret(secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key{
+		Method:          jwt.SigningMethodHS256,
+		SigningKey:      decodedSecret,
+		VerificationKey: decodedSecret,
+	}, nil
+}
+
+// Reads a PEM encoded key, which can either be a private key or a public key. Private keys are only needed to sign
+// tokens, so old keys can be given as public keys.
+func readKeyFile(path string) (*key, error) {
+	fileContents, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(fileContents)
+
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var parsedKey interface{}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsedKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block type: %s", path, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	parsedKeyData := &key{}
+
+	if privateKey, ok := parsedKey.(crypto.Signer); ok {
+		parsedKeyData.SigningKey = privateKey
+		parsedKeyData.VerificationKey = privateKey.Public()
+	} else {
+		parsedKeyData.VerificationKey = parsedKey
+	}
+
+	switch publicKey := parsedKeyData.VerificationKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+		}
+
+		parsedKeyData.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		parsedKeyData.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type (only RSA and Ed25519 keys are supported)", path)
+	}
+
+	parsedKeyData.KeyID, err = getKeyThumbprint(parsedKeyData.toJSONWebKey())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parsedKeyData, nil
+}
+
+func (key *key) toJSONWebKey() *JSONWebKey {
+	jsonWebKey := &JSONWebKey{
+		KeyID:     key.KeyID,
+		Use:       "sig",
+		Algorithm: key.Method.Alg(),
+	}
+
+	switch publicKey := key.VerificationKey.(type) {
+	case *rsa.PublicKey:
+		jsonWebKey.KeyType = "RSA"
+		jsonWebKey.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jsonWebKey.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jsonWebKey.KeyType = "OKP"
+		jsonWebKey.Curve = "Ed25519"
+		jsonWebKey.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return nil
+	}
+
+	return jsonWebKey
+}
+
+// Key IDs are JWK thumbprints (RFC 7638), so that they stay the same for a key without having to be configured
+func getKeyThumbprint(jsonWebKey *JSONWebKey) (string, error) {
+	var requiredMembers interface{}
+
+	// The members have to be in lexicographic order, which is what encoding/json does for maps
+	switch jsonWebKey.KeyType {
+	case "RSA":
+		requiredMembers = map[string]string{
+			"e":   jsonWebKey.E,
+			"kty": jsonWebKey.KeyType,
+			"n":   jsonWebKey.N,
+		}
+	case "OKP":
+		requiredMembers = map[string]string{
+			"crv": jsonWebKey.Curve,
+			"kty": jsonWebKey.KeyType,
+			"x":   jsonWebKey.X,
+		}
+	default:
+		return "", fmt.Errorf("unsupported key type: %s", jsonWebKey.KeyType)
+	}
+
+	encodedMembers, err := json.Marshal(requiredMembers)
+
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(encodedMembers)
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+func splitList(list string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Gets the public keys that tokens can be verified with, for other services to use. Secrets are never included.
+func GetJSONWebKeys() []*JSONWebKey {
+	jsonWebKeys := []*JSONWebKey{}
+
+	for _, verificationKey := range verificationKeys {
+		if jsonWebKey := verificationKey.toJSONWebKey(); jsonWebKey != nil {
+			jsonWebKeys = append(jsonWebKeys, jsonWebKey)
+		}
+	}
+
+	return jsonWebKeys
+}
@@ -1,22 +1,28 @@
 package main
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"git.terah.dev/imterah/hermes/backend/api/backendruntime"
+	"git.terah.dev/imterah/hermes/backend/api/controllers/v1/apikeys"
+	auditController "git.terah.dev/imterah/hermes/backend/api/controllers/v1/audit"
 	"git.terah.dev/imterah/hermes/backend/api/controllers/v1/backends"
+	permissionsController "git.terah.dev/imterah/hermes/backend/api/controllers/v1/permissions"
 	"git.terah.dev/imterah/hermes/backend/api/controllers/v1/proxies"
+	"git.terah.dev/imterah/hermes/backend/api/controllers/v1/roles"
 	"git.terah.dev/imterah/hermes/backend/api/controllers/v1/users"
 	"git.terah.dev/imterah/hermes/backend/api/dbcore"
 	"git.terah.dev/imterah/hermes/backend/api/jwtcore"
-	"git.terah.dev/imterah/hermes/backend/commonbackend"
+	"git.terah.dev/imterah/hermes/backend/api/oidc"
+	"git.terah.dev/imterah/hermes/backend/api/passwordpolicy"
+	"git.terah.dev/imterah/hermes/backend/api/permissions"
+	"git.terah.dev/imterah/hermes/backend/api/ratelimit"
+	"git.terah.dev/imterah/hermes/backend/api/refreshtokens"
 	"github.com/charmbracelet/log"
 	"github.com/gin-gonic/gin"
 	"github.com/urfave/cli/v2"
@@ -46,12 +52,34 @@ func apiEntrypoint(cCtx *cli.Context) error {
 		return fmt.Errorf("Failed to run database migrations: %s", err)
 	}
 
+	if err := permissions.SyncAdminRole(); err != nil {
+		return fmt.Errorf("Failed to add missing permission nodes to the admin role: %s", err)
+	}
+
 	log.Debug("Initializing the JWT subsystem...")
 
 	if err := jwtcore.SetupJWT(); err != nil {
 		return fmt.Errorf("Failed to initialize the JWT subsystem: %s", err.Error())
 	}
 
+	if err := refreshtokens.SetupRefreshTokens(); err != nil {
+		return fmt.Errorf("Failed to initialize refresh tokens: %s", err.Error())
+	}
+
+	refreshtokens.StartCleanupJob()
+
+	if err := passwordpolicy.SetupPasswordPolicy(); err != nil {
+		return fmt.Errorf("Failed to initialize the password policy: %s", err.Error())
+	}
+
+	if err := ratelimit.SetupRateLimits(); err != nil {
+		return fmt.Errorf("Failed to initialize rate limits: %s", err.Error())
+	}
+
+	if err := oidc.SetupOIDC(); err != nil {
+		return fmt.Errorf("Failed to initialize single sign-on: %s", err.Error())
+	}
+
 	log.Debug("Initializing the backend subsystem...")
 
 	backendMetadataPath := cCtx.String("backends-path")
@@ -72,7 +100,9 @@ func apiEntrypoint(cCtx *cli.Context) error {
 		backend.Path = path.Join(filepath.Dir(backendMetadataPath), backend.Path)
 	}
 
-	backendruntime.Init(availableBackends)
+	if err := backendruntime.Init(availableBackends); err != nil {
+		return fmt.Errorf("Failed to initialize backend runtime: %s", err.Error())
+	}
 
 	log.Debug("Enumerating backends...")
 
@@ -83,199 +113,27 @@ func apiEntrypoint(cCtx *cli.Context) error {
 	}
 
 	for _, backend := range backendList {
-		log.Infof("Starting up backend #%d: %s", backend.ID, backend.Name)
-
-		var backendRuntimeFilePath string
-
-		for _, runtime := range backendruntime.AvailableBackends {
-			if runtime.Name == backend.Backend {
-				backendRuntimeFilePath = runtime.Path
-			}
-		}
-
-		if backendRuntimeFilePath == "" {
-			log.Errorf("Unsupported backend recieved for ID %d: %s", backend.ID, backend.Backend)
+		if !backend.AutoStart {
+			log.Infof("Not starting backend #%d: %s, as it has been stopped", backend.ID, backend.Name)
 			continue
 		}
 
-		backendInstance := backendruntime.NewBackend(backendRuntimeFilePath)
-
-		backendInstance.OnCrashCallback = func(conn net.Conn) {
-			backendParameters, err := base64.StdEncoding.DecodeString(backend.BackendParameters)
-
-			if err != nil {
-				log.Errorf("Failed to decode backend parameters for backend #%d: %s", backend.ID, err.Error())
-				return
-			}
-
-			marshalledStartCommand, err := commonbackend.Marshal(&commonbackend.Start{
-				Arguments: backendParameters,
-			})
-
-			if err != nil {
-				log.Errorf("Failed to marshal start command for backend #%d: %s", backend.ID, err.Error())
-				return
-			}
-
-			if _, err := conn.Write(marshalledStartCommand); err != nil {
-				log.Errorf("Failed to send start command for backend #%d: %s", backend.ID, err.Error())
-				return
-			}
-
-			backendResponse, err := commonbackend.Unmarshal(conn)
-
-			if err != nil {
-				log.Errorf("Failed to get start command response for backend #%d: %s", backend.ID, err.Error())
-				return
-			}
-
-			switch responseMessage := backendResponse.(type) {
-			case *commonbackend.BackendStatusResponse:
-				if !responseMessage.IsRunning {
-					log.Errorf("Failed to start backend #%d: %s", backend.ID, responseMessage.Message)
-					return
-				}
-
-				log.Infof("Backend #%d has been reinitialized successfully", backend.ID)
-			}
-
-			log.Warnf("Backend #%d has reinitialized! Starting up auto-starting proxies...", backend.ID)
-
-			autoStartProxies := []dbcore.Proxy{}
-
-			if err := dbcore.DB.Where("backend_id = ? AND auto_start = true", backend.ID).Find(&autoStartProxies).Error; err != nil {
-				log.Errorf("Failed to query proxies to autostart: %s", err.Error())
-				return
-			}
-
-			for _, proxy := range autoStartProxies {
-				log.Infof("Starting up route #%d for backend #%d: %s", proxy.ID, backend.ID, proxy.Name)
-
-				marhalledCommand, err := commonbackend.Marshal(&commonbackend.AddProxy{
-					SourceIP:   proxy.SourceIP,
-					SourcePort: proxy.SourcePort,
-					DestPort:   proxy.DestinationPort,
-					Protocol:   proxy.Protocol,
-				})
-
-				if err != nil {
-					log.Errorf("Failed to marshal proxy adding request for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
-					continue
-				}
-
-				if _, err := conn.Write(marhalledCommand); err != nil {
-					log.Errorf("Failed to send proxy adding request for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
-					continue
-				}
-
-				backendResponse, err := commonbackend.Unmarshal(conn)
-
-				if err != nil {
-					log.Errorf("Failed to get response for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
-					continue
-				}
-
-				switch responseMessage := backendResponse.(type) {
-				case *commonbackend.ProxyStatusResponse:
-					if !responseMessage.IsActive {
-						log.Warnf("Failed to start proxy for backend #%d and route #%d", proxy.BackendID, proxy.ID)
-					}
-				default:
-					log.Errorf("Got illegal response type for backend #%d and proxy #%d: %T", proxy.BackendID, proxy.ID, responseMessage)
-					continue
-				}
-			}
-		}
+		log.Infof("Starting up backend #%d: %s", backend.ID, backend.Name)
 
-		err = backendInstance.Start()
+		backendInstance, err := backends.StartBackendRuntime(&backend)
 
 		if err != nil {
 			log.Errorf("Failed to start backend #%d: %s", backend.ID, err.Error())
 			continue
 		}
 
-		backendParameters, err := base64.StdEncoding.DecodeString(backend.BackendParameters)
-
-		if err != nil {
-			log.Errorf("Failed to decode backend parameters for backend #%d: %s", backend.ID, err.Error())
-			continue
-		}
-
-		backendStartResponse, err := backendInstance.ProcessCommand(&commonbackend.Start{
-			Arguments: backendParameters,
-		})
-
-		if err != nil {
-			log.Warnf("Failed to get response for backend #%d: %s", backend.ID, err.Error())
-
-			err = backendInstance.Stop()
-
-			if err != nil {
-				log.Warnf("Failed to stop backend: %s", err.Error())
-			}
-
-			continue
-		}
-
-		switch responseMessage := backendStartResponse.(type) {
-		case *commonbackend.BackendStatusResponse:
-			if !responseMessage.IsRunning {
-				err = backendInstance.Stop()
-
-				if err != nil {
-					log.Warnf("Failed to start backend: %s", err.Error())
-				}
-
-				if responseMessage.Message == "" {
-					log.Errorf("Unkown error while trying to start the backend #%d", backend.ID)
-				} else {
-					log.Errorf("Failed to start backend: %s", responseMessage.Message)
-				}
-
-				continue
-			}
-		default:
-			log.Errorf("Got illegal response type for backend #%d: %T", backend.ID, responseMessage)
-			continue
-		}
-
-		backendruntime.RunningBackends[backend.ID] = backendInstance
-
 		log.Infof("Successfully initialized backend #%d", backend.ID)
 
-		autoStartProxies := []dbcore.Proxy{}
-
-		if err := dbcore.DB.Where("backend_id = ? AND auto_start = true", backend.ID).Find(&autoStartProxies).Error; err != nil {
-			log.Errorf("Failed to query proxies to autostart: %s", err.Error())
+		if _, err := backends.StartAutoStartProxies(backend.ID, backendInstance); err != nil {
+			log.Errorf("Failed to start proxies for backend #%d: %s", backend.ID, err.Error())
 			continue
 		}
 
-		for _, proxy := range autoStartProxies {
-			log.Infof("Starting up route #%d for backend #%d: %s", proxy.ID, backend.ID, proxy.Name)
-
-			backendResponse, err := backendInstance.ProcessCommand(&commonbackend.AddProxy{
-				SourceIP:   proxy.SourceIP,
-				SourcePort: proxy.SourcePort,
-				DestPort:   proxy.DestinationPort,
-				Protocol:   proxy.Protocol,
-			})
-
-			if err != nil {
-				log.Errorf("Failed to get response for backend #%d and route #%d: %s", proxy.BackendID, proxy.ID, err.Error())
-				continue
-			}
-
-			switch responseMessage := backendResponse.(type) {
-			case *commonbackend.ProxyStatusResponse:
-				if !responseMessage.IsActive {
-					log.Warnf("Failed to start proxy for backend #%d and route #%d", proxy.BackendID, proxy.ID)
-				}
-			default:
-				log.Errorf("Got illegal response type for backend #%d and proxy #%d: %T", proxy.BackendID, proxy.ID, responseMessage)
-				continue
-			}
-		}
-
 		log.Infof("Successfully started backend #%d", backend.ID)
 	}
 
@@ -302,27 +160,77 @@ func apiEntrypoint(cCtx *cli.Context) error {
 	if trustedProxiesString != "" {
 		trustedProxies := strings.Split(trustedProxiesString, ",")
 
+		for trustedProxyIndex, trustedProxy := range trustedProxies {
+			trustedProxies[trustedProxyIndex] = strings.TrimSpace(trustedProxy)
+		}
+
+		// Rate limits go by the client IP, so anyone could get around them with a spoofed header if this was wrong
 		engine.ForwardedByClientIP = true
-		engine.SetTrustedProxies(trustedProxies)
+
+		if err := engine.SetTrustedProxies(trustedProxies); err != nil {
+			return fmt.Errorf("Failed to parse trusted HTTP proxies: %s", err.Error())
+		}
 	} else {
 		engine.ForwardedByClientIP = false
 		engine.SetTrustedProxies(nil)
 	}
 
+	engine.Use(ratelimit.Middleware())
+
 	// Initialize routes
 	engine.POST("/api/v1/users/create", users.CreateUser)
 	engine.POST("/api/v1/users/login", users.LoginUser)
+	engine.GET("/api/v1/users/oidc/login", users.OIDCLogin)
+	engine.GET("/api/v1/users/oidc/callback", users.OIDCCallback)
 	engine.POST("/api/v1/users/refresh", users.RefreshUserToken)
+	engine.GET("/api/v1/users/jwks", users.GetJWKS)
 	engine.POST("/api/v1/users/remove", users.RemoveUser)
 	engine.POST("/api/v1/users/lookup", users.LookupUser)
+	engine.POST("/api/v1/users/edit", users.EditUser)
+	engine.POST("/api/v1/users/transfer", users.TransferOwnership)
+	engine.POST("/api/v1/users/sessions/list", users.ListSessions)
+	engine.POST("/api/v1/users/sessions/revoke", users.RevokeSession)
+	engine.POST("/api/v1/users/sessions/revokeOthers", users.RevokeOtherSessions)
+	engine.POST("/api/v1/users/password/change", users.ChangePassword)
+	engine.POST("/api/v1/users/password/reset", users.ResetPassword)
+	engine.POST("/api/v1/users/totp/enroll", users.EnrollTOTP)
+	engine.POST("/api/v1/users/totp/verify", users.VerifyTOTP)
+	engine.POST("/api/v1/users/totp/disable", users.DisableTOTP)
+	engine.POST("/api/v1/users/totp/recoveryCodes", users.RegenerateRecoveryCodes)
+
+	engine.POST("/api/v1/apikeys/create", apikeys.CreateAPIKey)
+	engine.POST("/api/v1/apikeys/lookup", apikeys.LookupAPIKey)
+	engine.POST("/api/v1/apikeys/revoke", apikeys.RevokeAPIKey)
+
+	engine.POST("/api/v1/audit/lookup", auditController.LookupAuditLog)
+	engine.POST("/api/v1/audit/export", auditController.ExportAuditLog)
 
 	engine.POST("/api/v1/backends/create", backends.CreateBackend)
 	engine.POST("/api/v1/backends/remove", backends.RemoveBackend)
+	engine.POST("/api/v1/backends/edit", backends.EditBackend)
+	engine.POST("/api/v1/backends/start", backends.StartBackend)
+	engine.POST("/api/v1/backends/stop", backends.StopBackend)
 	engine.POST("/api/v1/backends/lookup", backends.LookupBackend)
+	engine.POST("/api/v1/backends/logs", backends.GetBackendLogs)
+	engine.POST("/api/v1/backends/share", backends.ShareBackend)
+	engine.POST("/api/v1/backends/unshare", backends.UnshareBackend)
+
+	engine.POST("/api/v1/permissions/list", permissionsController.ListPermissions)
+	engine.POST("/api/v1/permissions/grant", permissionsController.GrantPermission)
+	engine.POST("/api/v1/permissions/revoke", permissionsController.RevokePermission)
+	engine.POST("/api/v1/permissions/reset", permissionsController.ResetPermission)
+
+	engine.POST("/api/v1/roles/create", roles.CreateRole)
+	engine.POST("/api/v1/roles/remove", roles.RemoveRole)
+	engine.POST("/api/v1/roles/edit", roles.EditRole)
+	engine.POST("/api/v1/roles/lookup", roles.LookupRole)
+	engine.POST("/api/v1/roles/assign", roles.AssignRole)
+	engine.POST("/api/v1/roles/unassign", roles.UnassignRole)
 
 	engine.POST("/api/v1/forward/create", proxies.CreateProxy)
 	engine.POST("/api/v1/forward/lookup", proxies.LookupProxy)
 	engine.POST("/api/v1/forward/remove", proxies.RemoveProxy)
+	engine.POST("/api/v1/forward/edit", proxies.EditProxy)
 	engine.POST("/api/v1/forward/start", proxies.StartProxy)
 	engine.POST("/api/v1/forward/stop", proxies.StopProxy)
 	engine.POST("/api/v1/forward/connections", proxies.GetConnections)
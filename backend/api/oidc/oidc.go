@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // Where the identity provider sends users back to, which is the callback route of the API
+	Scopes       []string
+
+	UsernameClaim string
+	GroupsClaim   string
+
+	// Groups from the identity provider, and the roles they give. The roles in here are managed by the identity
+	// provider, and get added or removed on every login.
+	RoleMapping map[string][]string
+	DefaultRole string // Given to users when they are created, if set
+
+	// If set, users logging in for the first time get linked to an existing user with the same (verified) email
+	LinkByEmail bool
+}
+
+var (
+	Enabled bool
+	config  *Config
+)
+
+// Sets up single sign-on, if it has been configured (HERMES_OIDC_ISSUER is set)
+func SetupOIDC() error {
+	issuer := os.Getenv("HERMES_OIDC_ISSUER")
+
+	if issuer == "" {
+		return nil
+	}
+
+	config = &Config{
+		Issuer:        strings.TrimSuffix(issuer, "/"),
+		ClientID:      os.Getenv("HERMES_OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("HERMES_OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("HERMES_OIDC_REDIRECT_URL"),
+		Scopes:        []string{"openid", "profile", "email"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+		RoleMapping:   map[string][]string{},
+		DefaultRole:   os.Getenv("HERMES_OIDC_DEFAULT_ROLE"),
+		LinkByEmail:   os.Getenv("HERMES_OIDC_LINK_BY_EMAIL") != "",
+	}
+
+	if config.ClientID == "" {
+		return fmt.Errorf("OIDC client ID isn't set (missing HERMES_OIDC_CLIENT_ID)")
+	}
+
+	if config.RedirectURL == "" {
+		return fmt.Errorf("OIDC redirect URL isn't set (missing HERMES_OIDC_REDIRECT_URL)")
+	}
+
+	if scopes := os.Getenv("HERMES_OIDC_SCOPES"); scopes != "" {
+		config.Scopes = strings.Fields(scopes)
+	}
+
+	if usernameClaim := os.Getenv("HERMES_OIDC_USERNAME_CLAIM"); usernameClaim != "" {
+		config.UsernameClaim = usernameClaim
+	}
+
+	if groupsClaim := os.Getenv("HERMES_OIDC_GROUPS_CLAIM"); groupsClaim != "" {
+		config.GroupsClaim = groupsClaim
+	}
+
+	// ex. "hermes-admins=admin,network-team=operator"
+	if roleMapping := os.Getenv("HERMES_OIDC_ROLE_MAPPING"); roleMapping != "" {
+		for _, mapping := range strings.Split(roleMapping, ",") {
+			group, role, found := strings.Cut(mapping, "=")
+
+			if !found || group == "" || role == "" {
+				return fmt.Errorf("invalid OIDC role mapping '%s' (expected group=role)", mapping)
+			}
+
+			config.RoleMapping[group] = append(config.RoleMapping[group], role)
+		}
+	}
+
+	Enabled = true
+	return nil
+}
+
+func GetConfig() *Config {
+	return config
+}
+
+// Gets the roles a user should have from their groups, and every role managed by the identity provider
+func GetRolesForGroups(groups []string) ([]string, []string) {
+	roles := []string{}
+	managedRoles := []string{}
+
+	for _, mappedRoles := range config.RoleMapping {
+		managedRoles = append(managedRoles, mappedRoles...)
+	}
+
+	for _, group := range groups {
+		for _, role := range config.RoleMapping[group] {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return roles, managedRoles
+}
@@ -0,0 +1,339 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// How often the signing keys can be fetched again when a token is signed with a key we don't know about
+const keyRefreshInterval = 10 * time.Second
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC keys
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+var (
+	providerLock    sync.Mutex
+	provider        *providerMetadata
+	keys            map[string]interface{}
+	keysLastFetched time.Time
+)
+
+func getJSON(requestURL string, response interface{}) error {
+	res, err := httpClient.Get(requestURL)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status code %d from %s", res.StatusCode, requestURL)
+	}
+
+	return json.NewDecoder(res.Body).Decode(response)
+}
+
+// Gets the endpoints of the identity provider. They're fetched on first use, so that the API can still start if the
+// identity provider is down.
+func getProvider() (*providerMetadata, error) {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+
+	if provider != nil {
+		return provider, nil
+	}
+
+	metadata := &providerMetadata{}
+
+	if err := getJSON(config.Issuer+"/.well-known/openid-configuration", metadata); err != nil {
+		return nil, fmt.Errorf("failed to get identity provider configuration: %s", err.Error())
+	}
+
+	if strings.TrimSuffix(metadata.Issuer, "/") != config.Issuer {
+		return nil, fmt.Errorf("identity provider issuer '%s' doesn't match the configured issuer", metadata.Issuer)
+	}
+
+	provider = metadata
+	return provider, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decodedValue, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decodedValue), nil
+}
+
+func parseKey(key *jsonWebKey) (interface{}, error) {
+	switch key.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(key.E)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: n,
+			E: int(e.Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch key.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", key.Curve)
+		}
+
+		x, err := decodeBigInt(key.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(key.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     x,
+			Y:     y,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", key.KeyType)
+	}
+}
+
+// Gets a signing key of the identity provider. The keys get fetched again if the key isn't known, as identity
+// providers rotate them.
+func getKey(keyID string) (interface{}, error) {
+	metadata, err := getProvider()
+
+	if err != nil {
+		return nil, err
+	}
+
+	providerLock.Lock()
+	defer providerLock.Unlock()
+
+	if key, ok := keys[keyID]; ok {
+		return key, nil
+	}
+
+	if time.Since(keysLastFetched) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key: %s", keyID)
+	}
+
+	keySet := &struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}{}
+
+	if err := getJSON(metadata.JWKSURI, keySet); err != nil {
+		return nil, fmt.Errorf("failed to get identity provider signing keys: %s", err.Error())
+	}
+
+	keysLastFetched = time.Now()
+	keys = map[string]interface{}{}
+
+	for _, jsonKey := range keySet.Keys {
+		if jsonKey.Use != "" && jsonKey.Use != "sig" {
+			continue
+		}
+
+		key, err := parseKey(jsonKey)
+
+		if err != nil {
+			continue
+		}
+
+		keys[jsonKey.KeyID] = key
+	}
+
+	key, ok := keys[keyID]
+
+	// Tokens don't need a key ID if the identity provider only has one key
+	if !ok && keyID == "" && len(keys) == 1 {
+		for _, onlyKey := range keys {
+			key, ok = onlyKey, true
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", keyID)
+	}
+
+	return key, nil
+}
+
+// Gets the URL to send users to, to log in with the identity provider
+func GetAuthURL(login *PendingLogin) (string, error) {
+	metadata, err := getProvider()
+
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.ClientID)
+	query.Set("redirect_uri", config.RedirectURL)
+	query.Set("scope", strings.Join(config.Scopes, " "))
+	query.Set("state", login.State)
+	query.Set("nonce", login.Nonce)
+	query.Set("code_challenge", login.codeChallenge())
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchanges the code the identity provider gave back for an ID token, and verifies it. Returns the claims of the
+// ID token.
+func Exchange(login *PendingLogin, code string) (jwt.MapClaims, error) {
+	metadata, err := getProvider()
+
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", config.RedirectURL)
+	form.Set("code_verifier", login.CodeVerifier)
+	form.Set("client_id", config.ClientID)
+
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	res, err := httpClient.Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact token endpoint: %s", err.Error())
+	}
+
+	defer res.Body.Close()
+
+	bodyContents, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %s", err.Error())
+	}
+
+	response := &tokenResponse{}
+
+	if err := json.Unmarshal(bodyContents, response); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %s", err.Error())
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned an error: %s %s", response.Error, response.ErrorDescription)
+	}
+
+	if response.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint didn't return an ID token")
+	}
+
+	return verifyIDToken(response.IDToken, metadata.Issuer, login.Nonce)
+}
+
+func verifyIDToken(idToken, issuer, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return getKey(keyID)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %s", err.Error())
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce doesn't match")
+	}
+
+	return claims, nil
+}
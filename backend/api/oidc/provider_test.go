@@ -0,0 +1,216 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testClientID = "hermes"
+	testNonce    = "test-nonce"
+	testKeyID    = "test-key"
+)
+
+// Sets up a provider with one known signing key, without fetching anything
+func setupTestProvider(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	config = &Config{
+		Issuer:   testIssuer,
+		ClientID: testClientID,
+	}
+
+	provider = &providerMetadata{
+		Issuer: testIssuer,
+	}
+
+	keys = map[string]interface{}{
+		testKeyID: &privateKey.PublicKey,
+	}
+
+	// Keeps unknown keys from being fetched
+	keysLastFetched = time.Now()
+
+	t.Cleanup(func() {
+		config = nil
+		provider = nil
+		keys = nil
+		keysLastFetched = time.Time{}
+	})
+
+	return privateKey
+}
+
+func validTestClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testClientID,
+		"sub":   "user",
+		"nonce": testNonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	privateKey := setupTestProvider(t)
+
+	otherPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		changeClaims func(claims jwt.MapClaims)
+		method       jwt.SigningMethod
+		keyID        string
+		signingKey   interface{} // Defaults to the key of the provider
+		isValid      bool
+	}{
+		{
+			name:    "valid",
+			isValid: true,
+		},
+		{
+			name: "valid, with several audiences",
+			changeClaims: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"someone-else", testClientID}
+			},
+			isValid: true,
+		},
+		{
+			name: "valid, within the leeway",
+			changeClaims: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+			},
+			isValid: true,
+		},
+		{
+			name: "wrong nonce",
+			changeClaims: func(claims jwt.MapClaims) {
+				claims["nonce"] = "other-nonce"
+			},
+		},
+		{
+			name: "missing nonce",
+			changeClaims: func(claims jwt.MapClaims) {
+				delete(claims, "nonce")
+			},
+		},
+		{
+			name: "wrong audience",
+			changeClaims: func(claims jwt.MapClaims) {
+				claims["aud"] = "someone-else"
+			},
+		},
+		{
+			name: "missing audience",
+			changeClaims: func(claims jwt.MapClaims) {
+				delete(claims, "aud")
+			},
+		},
+		{
+			name: "wrong issuer",
+			changeClaims: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://evil.example.com"
+			},
+		},
+		{
+			name: "missing issuer",
+			changeClaims: func(claims jwt.MapClaims) {
+				delete(claims, "iss")
+			},
+		},
+		{
+			name: "expired",
+			changeClaims: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+		},
+		{
+			name: "missing expiry",
+			changeClaims: func(claims jwt.MapClaims) {
+				delete(claims, "exp")
+			},
+		},
+		{
+			name:       "signed by another key",
+			signingKey: otherPrivateKey,
+		},
+		{
+			name:  "unknown key ID",
+			keyID: "other-key",
+		},
+		{
+			// The public key of the provider must not be usable as an HMAC secret
+			name:   "HMAC signature",
+			method: jwt.SigningMethodHS256,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := validTestClaims()
+
+			if test.changeClaims != nil {
+				test.changeClaims(claims)
+			}
+
+			method := test.method
+
+			if method == nil {
+				method = jwt.SigningMethodRS256
+			}
+
+			signingKey := test.signingKey
+
+			if signingKey == nil {
+				signingKey = privateKey
+			}
+
+			if method == jwt.SigningMethodHS256 {
+				signingKey = privateKey.PublicKey.N.Bytes()
+			}
+
+			keyID := test.keyID
+
+			if keyID == "" {
+				keyID = testKeyID
+			}
+
+			token := jwt.NewWithClaims(method, claims)
+			token.Header["kid"] = keyID
+
+			idToken, err := token.SignedString(signingKey)
+
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			verifiedClaims, err := verifyIDToken(idToken, testIssuer, testNonce)
+
+			if test.isValid {
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+
+				if subject, _ := verifiedClaims["sub"].(string); subject != "user" {
+					t.Fatalf("got wrong subject: %s", subject)
+				}
+			} else if err == nil {
+				t.Fatal("invalid ID token was accepted")
+			}
+		})
+	}
+}
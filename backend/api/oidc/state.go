@@ -0,0 +1,93 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// How long users have to log in with the identity provider
+const loginTimeout = 10 * time.Minute
+
+// A login that has been sent to the identity provider, but hasn't come back yet
+type PendingLogin struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+
+	expiresAt time.Time
+}
+
+var (
+	pendingLoginsLock sync.Mutex
+	pendingLogins     = map[string]*PendingLogin{}
+)
+
+func randomString() (string, error) {
+	randomData := make([]byte, 32)
+
+	if _, err := rand.Read(randomData); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomData), nil
+}
+
+func (login *PendingLogin) codeChallenge() string {
+	hash := sha256.Sum256([]byte(login.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func StartLogin() (*PendingLogin, error) {
+	login := &PendingLogin{
+		expiresAt: time.Now().Add(loginTimeout),
+	}
+
+	var err error
+
+	if login.State, err = randomString(); err != nil {
+		return nil, err
+	}
+
+	if login.Nonce, err = randomString(); err != nil {
+		return nil, err
+	}
+
+	if login.CodeVerifier, err = randomString(); err != nil {
+		return nil, err
+	}
+
+	pendingLoginsLock.Lock()
+	defer pendingLoginsLock.Unlock()
+
+	for state, pendingLogin := range pendingLogins {
+		if time.Now().After(pendingLogin.expiresAt) {
+			delete(pendingLogins, state)
+		}
+	}
+
+	pendingLogins[login.State] = login
+	return login, nil
+}
+
+// Gets the login a callback is for. Each login can only be finished once.
+func FinishLogin(state string) *PendingLogin {
+	pendingLoginsLock.Lock()
+	defer pendingLoginsLock.Unlock()
+
+	login, ok := pendingLogins[state]
+
+	if !ok {
+		return nil
+	}
+
+	delete(pendingLogins, state)
+
+	if time.Now().After(login.expiresAt) {
+		return nil
+	}
+
+	return login
+}
@@ -0,0 +1,103 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bcrypt only looks at the first 72 bytes of a password, so anything longer is refused instead of silently cut off
+const MaxLength = 72
+
+var (
+	MinLength int
+
+	// SHA-1 hashes (in uppercase hex) of passwords known to be in data breaches
+	breachedPasswords map[string]struct{}
+)
+
+func SetupPasswordPolicy() error {
+	MinLength = 8
+
+	if minLengthString := os.Getenv("HERMES_PASSWORD_MIN_LENGTH"); minLengthString != "" {
+		minLength, err := strconv.Atoi(minLengthString)
+
+		if err != nil || minLength < 1 || minLength > MaxLength {
+			return fmt.Errorf("invalid minimum password length (HERMES_PASSWORD_MIN_LENGTH): '%s'", minLengthString)
+		}
+
+		MinLength = minLength
+	}
+
+	breachedPasswords = map[string]struct{}{}
+
+	if breachedPasswordsPath := os.Getenv("HERMES_BREACHED_PASSWORDS_FILE"); breachedPasswordsPath != "" {
+		if err := loadBreachedPasswords(breachedPasswordsPath); err != nil {
+			return fmt.Errorf("failed to load breached passwords: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func hashPassword(password string) string {
+	hash := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// Loads a list of breached passwords, with one password per line. Lines can also be SHA-1 hashes, optionally followed
+// by a count (ex. "HASH:123"), which is the format of the Have I Been Pwned password lists.
+func loadBreachedPasswords(path string) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+
+		if _, err := hex.DecodeString(hash); err == nil && len(hash) == sha1.Size*2 {
+			breachedPasswords[strings.ToUpper(hash)] = struct{}{}
+		} else {
+			breachedPasswords[hashPassword(line)] = struct{}{}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Checks if a password can be used. The error is meant to be shown to the user.
+func Check(password string, username, email string) error {
+	if len(password) < MinLength {
+		return fmt.Errorf("Password must be at least %d characters long", MinLength)
+	}
+
+	if len(password) > MaxLength {
+		return fmt.Errorf("Password can't be longer than %d bytes", MaxLength)
+	}
+
+	if strings.EqualFold(password, username) || strings.EqualFold(password, email) {
+		return fmt.Errorf("Password can't be the same as the username or email")
+	}
+
+	if _, isBreached := breachedPasswords[hashPassword(password)]; isBreached {
+		return fmt.Errorf("Password has appeared in a data breach, and can't be used")
+	}
+
+	return nil
+}
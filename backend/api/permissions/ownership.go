@@ -0,0 +1,56 @@
+package permissions
+
+import (
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+)
+
+// Checks if a user can do something (ex. routes.remove) with a forward rule. Users can only touch the forward rules
+// they own, unless they have routes.global.
+func UserCanAccessProxy(user *dbcore.User, proxy *dbcore.Proxy, node string) bool {
+	if !UserHasPermission(user, node) {
+		return false
+	}
+
+	return proxy.UserID == user.ID || UserHasPermission(user, "routes.global")
+}
+
+// Checks if a user can do something (ex. backends.start, or routes.add to add a forward rule to it) with a backend.
+// Users can only touch the backends they own, unless they have backends.global. Shared backends count as owned for
+// using them, but not for changing or removing them. The shares of the backend need to be loaded.
+func UserCanAccessBackend(user *dbcore.User, backend *dbcore.Backend, node string) bool {
+	if !UserHasPermission(user, node) {
+		return false
+	}
+
+	if backend.UserID == user.ID || UserHasPermission(user, "backends.global") {
+		return true
+	}
+
+	switch node {
+	case "backends.edit", "backends.remove", "backends.secretVis":
+		return false
+	default:
+		return IsBackendSharedWithUser(user, backend)
+	}
+}
+
+// Checks if a backend is shared with a user, either directly, or through one of their roles
+func IsBackendSharedWithUser(user *dbcore.User, backend *dbcore.Backend) bool {
+	for _, share := range backend.Shares {
+		if share.UserID != nil && *share.UserID == user.ID {
+			return true
+		}
+
+		if share.RoleID == nil {
+			continue
+		}
+
+		for _, role := range user.Roles {
+			if role.ID == *share.RoleID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,12 @@
 package permissions
 
-import "git.terah.dev/imterah/hermes/backend/api/dbcore"
+import (
+	"fmt"
+	"slices"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"gorm.io/gorm"
+)
 
 var DefaultPermissionNodes []string = []string{
 	"routes.add",
@@ -10,6 +16,7 @@ var DefaultPermissionNodes []string = []string{
 	"routes.edit",
 	"routes.visible",
 	"routes.visibleConn",
+	"routes.global",
 
 	"backends.add",
 	"backends.remove",
@@ -18,21 +25,126 @@ var DefaultPermissionNodes []string = []string{
 	"backends.edit",
 	"backends.visible",
 	"backends.secretVis",
+	"backends.global",
 
 	"permissions.see",
+	"permissions.manage",
 
 	"users.add",
 	"users.remove",
 	"users.lookup",
 	"users.edit",
+
+	"audit.visible",
 }
 
+// Checks if a user has a permission. Permissions set on the user itself override whatever their roles grant. The user
+// needs to be loaded with its permissions and roles (see GetUserWithPermissions).
 func UserHasPermission(user *dbcore.User, node string) bool {
+	// API keys can only use the permissions they were given
+	if user.APIKey != nil && !APIKeyHasPermission(user.APIKey, node) {
+		return false
+	}
+
 	for _, permission := range user.Permissions {
-		if permission.PermissionNode == node && permission.HasPermission {
+		if permission.PermissionNode == node {
+			return permission.HasPermission
+		}
+	}
+
+	return UserRolesHavePermission(user, node)
+}
+
+func APIKeyHasPermission(apiKey *dbcore.APIKey, node string) bool {
+	for _, permission := range apiKey.Permissions {
+		if permission.PermissionNode == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks if any of the roles of a user grant a permission, ignoring the overrides of the user
+func UserRolesHavePermission(user *dbcore.User, node string) bool {
+	for _, role := range user.Roles {
+		for _, permission := range role.Permissions {
+			if permission.PermissionNode == node {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Loads a user with everything UserHasPermission needs. As it takes the database to use, it can be used to check if a
+// change done in a transaction would lock someone out before committing it.
+func GetUserWithPermissions(db *gorm.DB, userID uint) (*dbcore.User, error) {
+	var user *dbcore.User
+	userRequest := db.Preload("Permissions").Preload("Roles.Permissions").Where("id = ?", userID).Find(&user)
+
+	if userRequest.Error != nil {
+		return nil, userRequest.Error
+	}
+
+	if userRequest.RowsAffected == 0 {
+		return nil, fmt.Errorf("user does not exist")
+	}
+
+	return user, nil
+}
+
+func IsValidPermissionNode(node string) bool {
+	return slices.Contains(DefaultPermissionNodes, node)
+}
+
+func IsDefaultRole(name string) bool {
+	for _, role := range dbcore.DefaultRoles {
+		if role.Name == name {
 			return true
 		}
 	}
 
 	return false
 }
+
+// Makes sure the admin role holds every permission node, including ones that were introduced after it was created
+func SyncAdminRole() error {
+	var role *dbcore.Role
+	roleRequest := dbcore.DB.Preload("Permissions").Where("name = ?", "admin").Find(&role)
+
+	if roleRequest.Error != nil {
+		return roleRequest.Error
+	}
+
+	if roleRequest.RowsAffected == 0 {
+		return nil
+	}
+
+	for _, node := range DefaultPermissionNodes {
+		hasNode := false
+
+		for _, permission := range role.Permissions {
+			if permission.PermissionNode == node {
+				hasNode = true
+				break
+			}
+		}
+
+		if hasNode {
+			continue
+		}
+
+		permission := &dbcore.RolePermission{
+			PermissionNode: node,
+			RoleID:         role.ID,
+		}
+
+		if err := dbcore.DB.Create(permission).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package permissions
+
+import (
+	"testing"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+)
+
+func newTestRole(id uint, nodes ...string) dbcore.Role {
+	role := dbcore.Role{}
+	role.ID = id
+
+	for _, node := range nodes {
+		role.Permissions = append(role.Permissions, dbcore.RolePermission{
+			PermissionNode: node,
+		})
+	}
+
+	return role
+}
+
+func newTestAPIKey(nodes ...string) *dbcore.APIKey {
+	apiKey := &dbcore.APIKey{}
+
+	for _, node := range nodes {
+		apiKey.Permissions = append(apiKey.Permissions, dbcore.APIKeyPermission{
+			PermissionNode: node,
+		})
+	}
+
+	return apiKey
+}
+
+func TestUserHasPermission(t *testing.T) {
+	operatorRole := newTestRole(1, "routes.add", "routes.visible")
+
+	tests := []struct {
+		name        string
+		roles       []dbcore.Role
+		overrides   []dbcore.Permission
+		apiKey      *dbcore.APIKey
+		node        string
+		expectation bool
+	}{
+		{
+			name:        "granted by role",
+			roles:       []dbcore.Role{operatorRole},
+			node:        "routes.add",
+			expectation: true,
+		},
+		{
+			name:        "not granted by role",
+			roles:       []dbcore.Role{operatorRole},
+			node:        "routes.remove",
+			expectation: false,
+		},
+		{
+			name:        "granted by any role",
+			roles:       []dbcore.Role{newTestRole(2, "users.lookup"), operatorRole},
+			node:        "routes.add",
+			expectation: true,
+		},
+		{
+			name:        "granted by override",
+			roles:       []dbcore.Role{operatorRole},
+			overrides:   []dbcore.Permission{{PermissionNode: "routes.remove", HasPermission: true}},
+			node:        "routes.remove",
+			expectation: true,
+		},
+		{
+			name:        "taken away by override",
+			roles:       []dbcore.Role{operatorRole},
+			overrides:   []dbcore.Permission{{PermissionNode: "routes.add", HasPermission: false}},
+			node:        "routes.add",
+			expectation: false,
+		},
+		{
+			name:        "API key with the node",
+			roles:       []dbcore.Role{operatorRole},
+			apiKey:      newTestAPIKey("routes.add"),
+			node:        "routes.add",
+			expectation: true,
+		},
+		{
+			name:        "API key without the node",
+			roles:       []dbcore.Role{operatorRole},
+			apiKey:      newTestAPIKey("routes.visible"),
+			node:        "routes.add",
+			expectation: false,
+		},
+		{
+			name:        "API key without any nodes",
+			roles:       []dbcore.Role{operatorRole},
+			apiKey:      newTestAPIKey(),
+			node:        "routes.visible",
+			expectation: false,
+		},
+		{
+			// Keys can't grant more than the user has
+			name:        "API key with a node the user doesn't have",
+			roles:       []dbcore.Role{operatorRole},
+			apiKey:      newTestAPIKey("users.edit"),
+			node:        "users.edit",
+			expectation: false,
+		},
+		{
+			name:        "API key with a node taken away by override",
+			roles:       []dbcore.Role{operatorRole},
+			overrides:   []dbcore.Permission{{PermissionNode: "routes.add", HasPermission: false}},
+			apiKey:      newTestAPIKey("routes.add"),
+			node:        "routes.add",
+			expectation: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &dbcore.User{
+				Roles:       test.roles,
+				Permissions: test.overrides,
+				APIKey:      test.apiKey,
+			}
+
+			if UserHasPermission(user, test.node) != test.expectation {
+				t.Fatalf("expected UserHasPermission(%s) to be %t", test.node, test.expectation)
+			}
+		})
+	}
+}
+
+func TestUserCanAccessBackend(t *testing.T) {
+	const ownerID, otherUserID = 1, 2
+
+	roleID := uint(3)
+	otherUserIDPointer := uint(otherUserID)
+
+	operatorRole := newTestRole(roleID, "backends.visible", "backends.edit", "routes.add")
+	globalRole := newTestRole(4, "backends.visible", "backends.edit", "backends.global")
+
+	tests := []struct {
+		name        string
+		userID      uint
+		roles       []dbcore.Role
+		apiKey      *dbcore.APIKey
+		shares      []dbcore.BackendShare
+		node        string
+		expectation bool
+	}{
+		{
+			name:        "owner",
+			userID:      ownerID,
+			roles:       []dbcore.Role{operatorRole},
+			node:        "backends.edit",
+			expectation: true,
+		},
+		{
+			name:        "not shared",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{operatorRole},
+			node:        "backends.visible",
+			expectation: false,
+		},
+		{
+			name:        "shared with the user",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{operatorRole},
+			shares:      []dbcore.BackendShare{{UserID: &otherUserIDPointer}},
+			node:        "routes.add",
+			expectation: true,
+		},
+		{
+			name:        "shared with a role of the user",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{operatorRole},
+			shares:      []dbcore.BackendShare{{RoleID: &roleID}},
+			node:        "backends.visible",
+			expectation: true,
+		},
+		{
+			name:        "shared, but editing",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{operatorRole},
+			shares:      []dbcore.BackendShare{{UserID: &otherUserIDPointer}},
+			node:        "backends.edit",
+			expectation: false,
+		},
+		{
+			name:        "global",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{globalRole},
+			node:        "backends.edit",
+			expectation: true,
+		},
+		{
+			// The key isn't scoped to backends.global, so it can only touch the backends the user owns
+			name:        "global, with an API key without backends.global",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{globalRole},
+			apiKey:      newTestAPIKey("backends.edit"),
+			node:        "backends.edit",
+			expectation: false,
+		},
+		{
+			name:        "global, with an API key with backends.global",
+			userID:      otherUserID,
+			roles:       []dbcore.Role{globalRole},
+			apiKey:      newTestAPIKey("backends.edit", "backends.global"),
+			node:        "backends.edit",
+			expectation: true,
+		},
+		{
+			name:        "owner, with an API key without the node",
+			userID:      ownerID,
+			roles:       []dbcore.Role{operatorRole},
+			apiKey:      newTestAPIKey("backends.visible"),
+			node:        "backends.edit",
+			expectation: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &dbcore.User{
+				Roles:  test.roles,
+				APIKey: test.apiKey,
+			}
+
+			user.ID = test.userID
+
+			backend := &dbcore.Backend{
+				UserID: ownerID,
+				Shares: test.shares,
+			}
+
+			if UserCanAccessBackend(user, backend, test.node) != test.expectation {
+				t.Fatalf("expected UserCanAccessBackend(%s) to be %t", test.node, test.expectation)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits how often something can be done, per key (ex. an IP address or a username). Requests are taken out of a
+// bucket that slowly refills, so short bursts are allowed as long as the average stays under the limit. A nil limiter
+// allows everything.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	lock        sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Creates a limiter allowing limit requests per window, per key
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:       limit,
+		window:      window,
+		buckets:     map[string]*bucket{},
+		lastCleanup: time.Now(),
+	}
+}
+
+// Parses a limit in the "requests/window" format (ex. "20/1m"). Returns nil if the limit is turned off ("off" or "0").
+func ParseLimiter(value string) (*Limiter, error) {
+	if value == "off" || value == "0" {
+		return nil, nil
+	}
+
+	limitString, windowString, found := strings.Cut(value, "/")
+
+	if !found {
+		return nil, fmt.Errorf("invalid rate limit '%s' (expected requests/window, ex. 20/1m)", value)
+	}
+
+	limit, err := strconv.Atoi(limitString)
+
+	if err != nil || limit < 1 {
+		return nil, fmt.Errorf("invalid request count in rate limit '%s'", value)
+	}
+
+	window, err := time.ParseDuration(windowString)
+
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("invalid window in rate limit '%s'", value)
+	}
+
+	return NewLimiter(limit, window), nil
+}
+
+func (limiter *Limiter) refillRate() float64 {
+	return float64(limiter.limit) / float64(limiter.window)
+}
+
+// Takes a request out of the bucket of a key. Returns 0 if the request is allowed, or how long to wait until it would
+// be otherwise.
+func (limiter *Limiter) Take(key string) time.Duration {
+	return limiter.takeAt(key, time.Now())
+}
+
+func (limiter *Limiter) takeAt(key string, now time.Time) time.Duration {
+	if limiter == nil {
+		return 0
+	}
+
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	// Buckets that have fully refilled are the same as new ones, so they don't need to be kept around
+	if now.Sub(limiter.lastCleanup) > limiter.window {
+		for key, bucket := range limiter.buckets {
+			if now.Sub(bucket.updatedAt) >= limiter.window {
+				delete(limiter.buckets, key)
+			}
+		}
+
+		limiter.lastCleanup = now
+	}
+
+	keyBucket, ok := limiter.buckets[key]
+
+	if !ok {
+		keyBucket = &bucket{
+			tokens:    float64(limiter.limit),
+			updatedAt: now,
+		}
+
+		limiter.buckets[key] = keyBucket
+	}
+
+	keyBucket.tokens = math.Min(float64(limiter.limit), keyBucket.tokens+float64(now.Sub(keyBucket.updatedAt))*limiter.refillRate())
+	keyBucket.updatedAt = now
+
+	if keyBucket.tokens < 1 {
+		return time.Duration((1 - keyBucket.tokens) / limiter.refillRate())
+	}
+
+	keyBucket.tokens--
+	return 0
+}
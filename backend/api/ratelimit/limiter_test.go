@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBurst(t *testing.T) {
+	limiter := NewLimiter(5, time.Minute)
+	now := time.Now()
+
+	for request := 0; request < 5; request++ {
+		if wait := limiter.takeAt("key", now); wait != 0 {
+			t.Fatalf("request %d was limited, even though it is within the burst", request+1)
+		}
+	}
+
+	wait := limiter.takeAt("key", now)
+
+	if wait == 0 {
+		t.Fatal("request past the burst wasn't limited")
+	}
+
+	// One request gets refilled every 12 seconds
+	if wait != 12*time.Second {
+		t.Fatalf("expected to wait 12s, got %s", wait)
+	}
+}
+
+func TestRefill(t *testing.T) {
+	limiter := NewLimiter(5, time.Minute)
+	now := time.Now()
+
+	for request := 0; request < 5; request++ {
+		limiter.takeAt("key", now)
+	}
+
+	if wait := limiter.takeAt("key", now.Add(6*time.Second)); wait != 6*time.Second {
+		t.Fatalf("expected to wait 6s with half a request refilled, got %s", wait)
+	}
+
+	if wait := limiter.takeAt("key", now.Add(12*time.Second)); wait != 0 {
+		t.Fatal("request was limited, even though one has been refilled")
+	}
+
+	if wait := limiter.takeAt("key", now.Add(12*time.Second)); wait == 0 {
+		t.Fatal("request wasn't limited, even though only one has been refilled")
+	}
+
+	// The bucket doesn't fill past the limit, no matter how long it has been
+	later := now.Add(time.Hour)
+
+	for request := 0; request < 5; request++ {
+		if wait := limiter.takeAt("key", later); wait != 0 {
+			t.Fatalf("request %d was limited after the bucket refilled", request+1)
+		}
+	}
+
+	if wait := limiter.takeAt("key", later); wait == 0 {
+		t.Fatal("bucket refilled past the limit")
+	}
+}
+
+func TestKeysAreSeparate(t *testing.T) {
+	limiter := NewLimiter(1, time.Minute)
+	now := time.Now()
+
+	if wait := limiter.takeAt("first", now); wait != 0 {
+		t.Fatal("first request of a key was limited")
+	}
+
+	if wait := limiter.takeAt("second", now); wait != 0 {
+		t.Fatal("requests of one key limited another key")
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *Limiter
+
+	if wait := limiter.Take("key"); wait != 0 {
+		t.Fatal("nil limiter limited a request")
+	}
+}
+
+func TestParseLimiter(t *testing.T) {
+	limiter, err := ParseLimiter("20/1m")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if limiter.limit != 20 || limiter.window != time.Minute {
+		t.Fatalf("parsed limit wrong (got %d/%s)", limiter.limit, limiter.window)
+	}
+
+	for _, value := range []string{"off", "0"} {
+		if limiter, err := ParseLimiter(value); err != nil || limiter != nil {
+			t.Errorf("'%s' didn't turn the limiter off", value)
+		}
+	}
+
+	for _, value := range []string{"20", "0/1m", "-1/1m", "20/0s", "20/forever", "a/1m"} {
+		if _, err := ParseLimiter(value); err == nil {
+			t.Errorf("invalid limit '%s' was accepted", value)
+		}
+	}
+}
+
+func TestLockoutDelay(t *testing.T) {
+	LockoutThreshold = 5
+	LockoutBaseDelay = 30 * time.Second
+	LockoutMaxDelay = 15 * time.Minute
+
+	testCases := []struct {
+		failedLogins int
+		delay        time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{7, 2 * time.Minute},
+		{9, 8 * time.Minute},
+		{10, 15 * time.Minute},
+		{1000, 15 * time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		if delay := GetLockoutDelay(testCase.failedLogins); delay != testCase.delay {
+			t.Errorf("expected a delay of %s after %d failed logins, got %s", testCase.delay, testCase.failedLogins, delay)
+		}
+	}
+
+	LockoutMaxDelay = 10 * time.Second
+
+	if delay := GetLockoutDelay(5); delay != 10*time.Second {
+		t.Errorf("delay went past the maximum, even though the base delay is longer (got %s)", delay)
+	}
+
+	LockoutThreshold = 0
+
+	if delay := GetLockoutDelay(1000); delay != 0 {
+		t.Errorf("account got locked, even though lockouts are turned off (got %s)", delay)
+	}
+}
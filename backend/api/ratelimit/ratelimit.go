@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	LoginPerIP        *Limiter
+	LoginPerAccount   *Limiter
+	RefreshPerIP      *Limiter
+	RefreshPerAccount *Limiter
+	APIPerIP          *Limiter
+
+	// Accounts get locked after this many failed logins in a row, for LockoutBaseDelay. Every failed login after that
+	// doubles the delay, up to LockoutMaxDelay. Lockouts are turned off if the threshold is 0.
+	LockoutThreshold int
+	LockoutBaseDelay time.Duration
+	LockoutMaxDelay  time.Duration
+)
+
+func getLimiter(name, defaultValue string) (*Limiter, error) {
+	value := os.Getenv(name)
+
+	if value == "" {
+		value = defaultValue
+	}
+
+	limiter, err := ParseLimiter(value)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	return limiter, nil
+}
+
+func getDuration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	return duration, nil
+}
+
+func SetupRateLimits() error {
+	var err error
+
+	if LoginPerIP, err = getLimiter("HERMES_RATE_LIMIT_LOGIN_IP", "20/1m"); err != nil {
+		return err
+	}
+
+	if LoginPerAccount, err = getLimiter("HERMES_RATE_LIMIT_LOGIN_ACCOUNT", "10/1m"); err != nil {
+		return err
+	}
+
+	if RefreshPerIP, err = getLimiter("HERMES_RATE_LIMIT_REFRESH_IP", "60/1m"); err != nil {
+		return err
+	}
+
+	if RefreshPerAccount, err = getLimiter("HERMES_RATE_LIMIT_REFRESH_ACCOUNT", "30/1m"); err != nil {
+		return err
+	}
+
+	if APIPerIP, err = getLimiter("HERMES_RATE_LIMIT_API_IP", "600/1m"); err != nil {
+		return err
+	}
+
+	LockoutThreshold = 5
+
+	if value := os.Getenv("HERMES_LOCKOUT_THRESHOLD"); value != "" {
+		if LockoutThreshold, err = strconv.Atoi(value); err != nil || LockoutThreshold < 0 {
+			return fmt.Errorf("HERMES_LOCKOUT_THRESHOLD: invalid number of failed logins '%s'", value)
+		}
+	}
+
+	if LockoutBaseDelay, err = getDuration("HERMES_LOCKOUT_BASE_DELAY", 30*time.Second); err != nil {
+		return err
+	}
+
+	if LockoutMaxDelay, err = getDuration("HERMES_LOCKOUT_MAX_DELAY", 15*time.Minute); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Gets how long an account gets locked for after a number of failed logins in a row. Returns 0 if it shouldn't be.
+func GetLockoutDelay(failedLogins int) time.Duration {
+	if LockoutThreshold == 0 || failedLogins < LockoutThreshold {
+		return 0
+	}
+
+	delay := min(LockoutBaseDelay, LockoutMaxDelay)
+
+	for range failedLogins - LockoutThreshold {
+		delay *= 2
+
+		if delay >= LockoutMaxDelay {
+			return LockoutMaxDelay
+		}
+	}
+
+	return delay
+}
+
+// Responds with 429 Too Many Requests, telling the client how long to wait for
+func AbortWithRetryAfter(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": message,
+	})
+}
+
+// Limits how often clients can use the API, per IP address
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if wait := APIPerIP.Take(c.ClientIP()); wait > 0 {
+			AbortWithRetryAfter(c, wait, "Too many requests, try again later")
+		}
+	}
+}
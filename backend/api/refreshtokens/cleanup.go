@@ -0,0 +1,57 @@
+package refreshtokens
+
+import (
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+	"github.com/charmbracelet/log"
+	"gorm.io/gorm"
+)
+
+// Removes expired and revoked sessions, along with the replaced refresh tokens of removed sessions
+func Cleanup() error {
+	return dbcore.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		expiredSessions := tx.Unscoped().Where("deleted_at IS NOT NULL")
+
+		if Lifetime != 0 {
+			expiredSessions = expiredSessions.Or("disable_expiry = ? AND created_at < ?", false, now.Add(-Lifetime))
+		}
+
+		if IdleTimeout != 0 {
+			expiredSessions = expiredSessions.Or("disable_expiry = ? AND COALESCE(last_used_at, created_at) < ?", false, now.Add(-IdleTimeout))
+		}
+
+		sessionRequest := expiredSessions.Delete(&dbcore.Token{})
+
+		if sessionRequest.Error != nil {
+			return sessionRequest.Error
+		}
+
+		retiredTokenRequest := tx.Unscoped().Where("token_id NOT IN (?)", tx.Model(&dbcore.Token{}).Select("id")).Delete(&dbcore.RetiredToken{})
+
+		if retiredTokenRequest.Error != nil {
+			return retiredTokenRequest.Error
+		}
+
+		if sessionRequest.RowsAffected != 0 {
+			log.Debugf("Removed %d expired or revoked session(s)", sessionRequest.RowsAffected)
+		}
+
+		return nil
+	})
+}
+
+// Runs Cleanup every CleanupInterval, forever
+func StartCleanupJob() {
+	go func() {
+		for {
+			if err := Cleanup(); err != nil {
+				log.Warnf("Failed to clean up expired sessions: %s", err.Error())
+			}
+
+			time.Sleep(CleanupInterval)
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+package refreshtokens
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+)
+
+var (
+	// How long refresh tokens last for in total, and without being used. Either can be 0 to turn it off.
+	Lifetime    time.Duration
+	IdleTimeout time.Duration
+
+	// If set, refresh tokens get replaced every time they're used. Using a replaced token again revokes the session, as
+	// it means the token has been stolen (or the client is broken).
+	Rotation bool
+
+	// How long a replaced token keeps working after it gets replaced, so that clients which refresh the same token
+	// several times at once (ex. two hermcli commands) don't get their session revoked. 0 turns it off.
+	ReuseGracePeriod time.Duration
+
+	// If set, refresh tokens only work from the IP address they were created from
+	BindIP bool
+
+	CleanupInterval time.Duration
+)
+
+func getDuration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%s: invalid duration '%s'", name, value)
+	}
+
+	return duration, nil
+}
+
+func SetupRefreshTokens() error {
+	var err error
+
+	if Lifetime, err = getDuration("HERMES_REFRESH_TOKEN_LIFETIME", 30*24*time.Hour); err != nil {
+		return err
+	}
+
+	if IdleTimeout, err = getDuration("HERMES_REFRESH_TOKEN_IDLE_TIMEOUT", 7*24*time.Hour); err != nil {
+		return err
+	}
+
+	if CleanupInterval, err = getDuration("HERMES_REFRESH_TOKEN_CLEANUP_INTERVAL", time.Hour); err != nil {
+		return err
+	}
+
+	if ReuseGracePeriod, err = getDuration("HERMES_REFRESH_TOKEN_REUSE_GRACE_PERIOD", 30*time.Second); err != nil {
+		return err
+	}
+
+	if CleanupInterval == 0 {
+		return fmt.Errorf("HERMES_REFRESH_TOKEN_CLEANUP_INTERVAL: can't be 0")
+	}
+
+	Rotation = os.Getenv("HERMES_DISABLE_REFRESH_TOKEN_ROTATION") == ""
+	BindIP = os.Getenv("HERMES_REFRESH_TOKEN_BIND_IP") != ""
+
+	return nil
+}
+
+// Gets when a refresh token expires, if it does. Using the token pushes back the idle timeout.
+func GetExpiry(token *dbcore.Token) *time.Time {
+	if token.DisableExpiry {
+		return nil
+	}
+
+	var expiry *time.Time
+
+	if Lifetime != 0 {
+		absoluteExpiry := token.CreatedAt.Add(Lifetime)
+		expiry = &absoluteExpiry
+	}
+
+	if IdleTimeout != 0 {
+		lastUsedAt := token.CreatedAt
+
+		if token.LastUsedAt != nil {
+			lastUsedAt = *token.LastUsedAt
+		}
+
+		idleExpiry := lastUsedAt.Add(IdleTimeout)
+
+		if expiry == nil || idleExpiry.Before(*expiry) {
+			expiry = &idleExpiry
+		}
+	}
+
+	return expiry
+}
+
+// Checks if a refresh token can still be used by a client. Tokens with expiry disabled (ex. ones migrated from NextNet)
+// don't expire, and aren't bound to an IP address.
+func IsValid(token *dbcore.Token, clientIP string) bool {
+	if token.DisableExpiry {
+		return true
+	}
+
+	if BindIP && clientIP != token.CreationIPAddr {
+		return false
+	}
+
+	expiry := GetExpiry(token)
+	return expiry == nil || time.Now().Before(*expiry)
+}
+
+// Checks if a refresh token should be replaced when it gets used. Tokens with expiry disabled are often kept fixed by
+// their clients (ex. bots, or ones migrated from NextNet), so they never get replaced.
+func ShouldRotate(token *dbcore.Token) bool {
+	return Rotation && !token.DisableExpiry
+}
+
+// Checks if a replaced refresh token is still within the grace period, and can be treated like the token it got
+// replaced with
+func IsWithinReuseGracePeriod(retiredToken *dbcore.RetiredToken) bool {
+	return ReuseGracePeriod != 0 && time.Since(retiredToken.CreatedAt) < ReuseGracePeriod
+}
+
+// Refresh tokens that have been replaced are only kept as a hash, as they're only needed to detect reuse
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,170 @@
+package refreshtokens
+
+import (
+	"testing"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/api/dbcore"
+)
+
+func TestShouldRotate(t *testing.T) {
+	tests := []struct {
+		name          string
+		rotation      bool
+		disableExpiry bool
+		expectation   bool
+	}{
+		{
+			name:        "rotation on",
+			rotation:    true,
+			expectation: true,
+		},
+		{
+			name:     "rotation off",
+			rotation: false,
+		},
+		{
+			name:          "expiry disabled",
+			rotation:      true,
+			disableExpiry: true,
+		},
+	}
+
+	oldRotation := Rotation
+	defer func() { Rotation = oldRotation }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Rotation = test.rotation
+
+			if ShouldRotate(&dbcore.Token{DisableExpiry: test.disableExpiry}) != test.expectation {
+				t.Fatalf("expected ShouldRotate to be %t", test.expectation)
+			}
+		})
+	}
+}
+
+func TestIsWithinReuseGracePeriod(t *testing.T) {
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		replacedAgo time.Duration
+		expectation bool
+	}{
+		{
+			name:        "just replaced",
+			gracePeriod: 30 * time.Second,
+			replacedAgo: time.Second,
+			expectation: true,
+		},
+		{
+			name:        "after the grace period",
+			gracePeriod: 30 * time.Second,
+			replacedAgo: time.Minute,
+		},
+		{
+			name:        "grace period turned off",
+			replacedAgo: 0,
+		},
+	}
+
+	oldReuseGracePeriod := ReuseGracePeriod
+	defer func() { ReuseGracePeriod = oldReuseGracePeriod }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ReuseGracePeriod = test.gracePeriod
+
+			retiredToken := &dbcore.RetiredToken{}
+			retiredToken.CreatedAt = time.Now().Add(-test.replacedAgo)
+
+			if IsWithinReuseGracePeriod(retiredToken) != test.expectation {
+				t.Fatalf("expected IsWithinReuseGracePeriod to be %t", test.expectation)
+			}
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	const clientIP = "192.0.2.1"
+
+	lastUsedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name           string
+		createdAgo     time.Duration
+		lastUsedAt     *time.Time
+		creationIPAddr string
+		disableExpiry  bool
+		bindIP         bool
+		expectation    bool
+	}{
+		{
+			name:        "new",
+			expectation: true,
+		},
+		{
+			name:        "past the lifetime",
+			createdAgo:  31 * 24 * time.Hour,
+			lastUsedAt:  &lastUsedAt,
+			expectation: false,
+		},
+		{
+			name:        "past the idle timeout",
+			createdAgo:  8 * 24 * time.Hour,
+			expectation: false,
+		},
+		{
+			name:        "used within the idle timeout",
+			createdAgo:  8 * 24 * time.Hour,
+			lastUsedAt:  &lastUsedAt,
+			expectation: true,
+		},
+		{
+			name:          "expiry disabled",
+			createdAgo:    365 * 24 * time.Hour,
+			disableExpiry: true,
+			bindIP:        true,
+			expectation:   true,
+		},
+		{
+			name:           "bound to another IP address",
+			creationIPAddr: "192.0.2.2",
+			bindIP:         true,
+			expectation:    false,
+		},
+		{
+			name:           "bound to the same IP address",
+			creationIPAddr: clientIP,
+			bindIP:         true,
+			expectation:    true,
+		},
+	}
+
+	oldLifetime, oldIdleTimeout, oldBindIP := Lifetime, IdleTimeout, BindIP
+
+	defer func() {
+		Lifetime, IdleTimeout, BindIP = oldLifetime, oldIdleTimeout, oldBindIP
+	}()
+
+	Lifetime = 30 * 24 * time.Hour
+	IdleTimeout = 7 * 24 * time.Hour
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			BindIP = test.bindIP
+
+			token := &dbcore.Token{
+				CreationIPAddr: test.creationIPAddr,
+				DisableExpiry:  test.disableExpiry,
+				LastUsedAt:     test.lastUsedAt,
+			}
+
+			token.CreatedAt = time.Now().Add(-test.createdAgo)
+
+			if IsValid(token, clientIP) != test.expectation {
+				t.Fatalf("expected IsValid to be %t", test.expectation)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// How many recovery codes users get each time they're generated
+const RecoveryCodeCount = 10
+
+// Recovery codes are random enough that a plain hash is enough to store them safely
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// Generates a set of recovery codes. Returns the codes, which should only be shown once, and their hashes.
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+
+	for codeIndex := range codes {
+		codeRandomData := make([]byte, 5)
+
+		if _, err := rand.Read(codeRandomData); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(codeRandomData)
+		codes[codeIndex] = code[:5] + "-" + code[5:]
+		hashes[codeIndex] = HashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
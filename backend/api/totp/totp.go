@@ -0,0 +1,102 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Length of the codes, and how long each one is valid for. These are what every authenticator app defaults to.
+	codeDigits = 6
+	stepPeriod = 30
+
+	// How many steps before and after the current one are accepted, to allow for clock drift on the user's device
+	allowedSkew = 1
+)
+
+const issuer = "Hermes"
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Generates a new TOTP secret, encoded in base32 like authenticator apps expect
+func GenerateSecret() (string, error) {
+	secretRandomData := make([]byte, 20)
+
+	if _, err := rand.Read(secretRandomData); err != nil {
+		return "", err
+	}
+
+	return secretEncoding.EncodeToString(secretRandomData), nil
+}
+
+// Gets the otpauth:// URL for a secret, which authenticator apps can import (usually as a QR code)
+func GetURL(accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(accountName), query.Encode())
+}
+
+func generateCode(secret []byte, step int64) string {
+	stepBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(stepBytes, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(stepBytes)
+	hash := mac.Sum(nil)
+
+	// Dynamic truncation, as described in RFC 4226
+	offset := hash[len(hash)-1] & 0x0f
+	value := binary.BigEndian.Uint32(hash[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+
+	for range codeDigits {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", codeDigits, value%modulo)
+}
+
+// Validates a code against a secret. Codes from steps at or before lastUsedStep are rejected, so that a code can't be
+// used twice. Returns the step the code was from, which should be saved as the new last used step.
+func Validate(secret, code string, lastUsedStep int64) (int64, bool) {
+	return validateAt(secret, code, lastUsedStep, time.Now())
+}
+
+func validateAt(secret, code string, lastUsedStep int64, now time.Time) (int64, bool) {
+	decodedSecret, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+
+	if len(code) != codeDigits {
+		return 0, false
+	}
+
+	currentStep := now.Unix() / stepPeriod
+
+	for step := currentStep - allowedSkew; step <= currentStep+allowedSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(generateCode(decodedSecret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Secret used by the test vectors in RFC 6238 (appendix B), for SHA-1
+var rfcSecret = []byte("12345678901234567890")
+
+func TestGenerateCode(t *testing.T) {
+	// The RFC uses 8 digit codes. 6 digit codes are the last 6 digits of them, as both are the same number modulo 10^n.
+	testVectors := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, testVector := range testVectors {
+		code := generateCode(rfcSecret, testVector.time/stepPeriod)
+
+		if code != testVector.code {
+			t.Errorf("wrong code for time %d (expected %s, got %s)", testVector.time, testVector.code, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	now := time.Unix(1111111111, 0)
+	currentStep := now.Unix() / stepPeriod
+
+	testCases := []struct {
+		name         string
+		step         int64
+		lastUsedStep int64
+		isValid      bool
+	}{
+		{"current step", currentStep, 0, true},
+		{"previous step (within skew)", currentStep - allowedSkew, 0, true},
+		{"next step (within skew)", currentStep + allowedSkew, 0, true},
+		{"too old", currentStep - allowedSkew - 1, 0, false},
+		{"too new", currentStep + allowedSkew + 1, 0, false},
+		{"already used", currentStep, currentStep, false},
+		{"older than the last used step", currentStep - 1, currentStep, false},
+		{"newer than the last used step", currentStep + 1, currentStep, true},
+	}
+
+	for _, testCase := range testCases {
+		code := generateCode(rfcSecret, testCase.step)
+		step, isValid := validateAt(secret, code, testCase.lastUsedStep, now)
+
+		if isValid != testCase.isValid {
+			t.Errorf("%s: expected valid to be %t, got %t", testCase.name, testCase.isValid, isValid)
+			continue
+		}
+
+		if isValid && step != testCase.step {
+			t.Errorf("%s: expected step %d, got %d", testCase.name, testCase.step, step)
+		}
+	}
+}
+
+func TestValidateReplay(t *testing.T) {
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	now := time.Unix(1234567890, 0)
+	code := generateCode(rfcSecret, now.Unix()/stepPeriod)
+
+	lastUsedStep, isValid := validateAt(secret, code, 0, now)
+
+	if !isValid {
+		t.Fatal("code wasn't valid the first time it was used")
+	}
+
+	if _, isValid := validateAt(secret, code, lastUsedStep, now); isValid {
+		t.Fatal("code was valid the second time it was used")
+	}
+}
+
+func TestValidateFormatting(t *testing.T) {
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	now := time.Unix(59, 0)
+
+	if _, isValid := validateAt(secret, "287 082", 0, now); !isValid {
+		t.Error("code with a space in it wasn't valid")
+	}
+
+	if _, isValid := validateAt(secret, "94287082", 0, now); isValid {
+		t.Error("8 digit code was valid")
+	}
+
+	if _, isValid := validateAt("not base32!", "287082", 0, now); isValid {
+		t.Error("code was valid with an invalid secret")
+	}
+}
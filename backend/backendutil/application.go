@@ -1,8 +1,10 @@
 package backendutil
 
 import (
+	"fmt"
 	"net"
 	"os"
+	"sync"
 
 	"git.terah.dev/imterah/hermes/backend/commonbackend"
 	"github.com/charmbracelet/log"
@@ -12,7 +14,30 @@ type BackendApplicationHelper struct {
 	Backend    BackendInterface
 	SocketPath string
 
-	socket net.Conn
+	socket    net.Conn
+	writeLock sync.Mutex
+}
+
+type queuedCommand struct {
+	requestID  uint32
+	commandRaw interface{}
+}
+
+// How many commands can be waiting to be handled before we stop reading new ones
+const commandQueueSize = 64
+
+func (helper *BackendApplicationHelper) writeMessage(requestID uint32, message interface{}) error {
+	messageMarshalled, err := commonbackend.Marshal(requestID, message)
+
+	if err != nil {
+		return err
+	}
+
+	helper.writeLock.Lock()
+	defer helper.writeLock.Unlock()
+
+	_, err = helper.socket.Write(messageMarshalled)
+	return err
 }
 
 func (helper *BackendApplicationHelper) Start() error {
@@ -33,203 +58,202 @@ func (helper *BackendApplicationHelper) Start() error {
 
 	log.Debug("Sucessfully connected")
 
-	for {
-		commandRaw, err := commonbackend.Unmarshal(helper.socket)
-
-		if err != nil {
-			return err
-		}
-
-		switch command := commandRaw.(type) {
-		case *commonbackend.Start:
-			ok, err := helper.Backend.StartBackend(command.Arguments)
-
-			var (
-				message    string
-				statusCode int
-			)
-
-			if err != nil {
-				message = err.Error()
-				statusCode = commonbackend.StatusFailure
-			} else {
-				statusCode = commonbackend.StatusSuccess
-			}
-
-			response := &commonbackend.BackendStatusResponse{
-				IsRunning:  ok,
-				StatusCode: statusCode,
-				Message:    message,
-			}
+	// The API refuses to talk to us until it knows which version of the protocol we speak, and what we support
+	err = helper.writeMessage(0, &commonbackend.Hello{
+		Version:      commonbackend.CurrentProtocolVersion,
+		Capabilities: helper.Backend.GetCapabilities(),
+	})
 
-			responseMarshalled, err := commonbackend.Marshal(response)
-
-			if err != nil {
-				log.Error("failed to marshal response: %s", err.Error())
-				continue
-			}
-
-			helper.socket.Write(responseMarshalled)
-		case *commonbackend.BackendStatusRequest:
-			ok, err := helper.Backend.GetBackendStatus()
-
-			var (
-				message    string
-				statusCode int
-			)
+	if err != nil {
+		return err
+	}
 
-			if err != nil {
-				message = err.Error()
-				statusCode = commonbackend.StatusFailure
-			} else {
-				statusCode = commonbackend.StatusSuccess
-			}
+	_, helloRaw, err := commonbackend.Unmarshal(helper.socket)
 
-			response := &commonbackend.BackendStatusResponse{
-				IsRunning:  ok,
-				StatusCode: statusCode,
-				Message:    message,
-			}
+	if err != nil {
+		return err
+	}
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+	hello, ok := helloRaw.(*commonbackend.Hello)
 
-			if err != nil {
-				log.Error("failed to marshal response: %s", err.Error())
-				continue
-			}
+	if !ok {
+		return fmt.Errorf("API sent %T instead of Hello", helloRaw)
+	}
 
-			helper.socket.Write(responseMarshalled)
-		case *commonbackend.Stop:
-			ok, err := helper.Backend.StopBackend()
+	if hello.Version != commonbackend.CurrentProtocolVersion {
+		return fmt.Errorf("API speaks protocol version %d, but this backend only supports version %d", hello.Version, commonbackend.CurrentProtocolVersion)
+	}
 
-			var (
-				message    string
-				statusCode int
-			)
+	// Backends are written to handle one command at a time, so commands get handled in order by a single goroutine.
+	// Their request IDs still let the API send more commands without waiting for every response first.
+	commandQueue := make(chan *queuedCommand, commandQueueSize)
+	defer close(commandQueue)
 
-			if err != nil {
-				message = err.Error()
-				statusCode = commonbackend.StatusFailure
-			} else {
-				statusCode = commonbackend.StatusSuccess
+	go func() {
+		for command := range commandQueue {
+			if err := helper.handleCommand(command.requestID, command.commandRaw); err != nil {
+				log.Errorf("failed to handle command: %s", err.Error())
+				helper.socket.Close()
 			}
+		}
+	}()
 
-			response := &commonbackend.BackendStatusResponse{
-				IsRunning:  !ok,
-				StatusCode: statusCode,
-				Message:    message,
-			}
+	for {
+		requestID, commandRaw, err := commonbackend.Unmarshal(helper.socket)
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+		if err != nil {
+			return err
+		}
 
-			if err != nil {
-				log.Error("failed to marshal response: %s", err.Error())
-				continue
+		// Pings are answered right away, so that a slow command (ex. starting a proxy) doesn't make the backend look
+		// unresponsive
+		if _, ok := commandRaw.(*commonbackend.Ping); ok {
+			if err := helper.writeMessage(requestID, &commonbackend.Pong{}); err != nil {
+				return err
 			}
 
-			helper.socket.Write(responseMarshalled)
-		case *commonbackend.AddProxy:
-			ok, err := helper.Backend.StartProxy(command)
-			var hasAnyFailed bool
-
-			if err != nil {
-				log.Warnf("failed to add proxy (%s:%d -> remote:%d): %s", command.SourceIP, command.SourcePort, command.DestPort, err.Error())
-				hasAnyFailed = true
-			} else if !ok {
-				log.Warnf("failed to add proxy (%s:%d -> remote:%d): StartProxy returned into failure state", command.SourceIP, command.SourcePort, command.DestPort)
-				hasAnyFailed = true
-			}
+			continue
+		}
 
-			response := &commonbackend.ProxyStatusResponse{
-				SourceIP:   command.SourceIP,
-				SourcePort: command.SourcePort,
-				DestPort:   command.DestPort,
-				Protocol:   command.Protocol,
-				IsActive:   !hasAnyFailed,
-			}
+		commandQueue <- &queuedCommand{
+			requestID:  requestID,
+			commandRaw: commandRaw,
+		}
+	}
+}
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+func (helper *BackendApplicationHelper) handleCommand(requestID uint32, commandRaw interface{}) error {
+	var response interface{}
 
-			if err != nil {
-				log.Error("failed to marshal response: %s", err.Error())
-				continue
-			}
+	switch command := commandRaw.(type) {
+	case *commonbackend.Start:
+		ok, err := helper.Backend.StartBackend(command.Arguments)
 
-			helper.socket.Write(responseMarshalled)
-		case *commonbackend.RemoveProxy:
-			ok, err := helper.Backend.StopProxy(command)
-			var hasAnyFailed bool
-
-			if err != nil {
-				log.Warnf("failed to remove proxy (%s:%d -> remote:%d): %s", command.SourceIP, command.SourcePort, command.DestPort, err.Error())
-				hasAnyFailed = true
-			} else if !ok {
-				log.Warnf("failed to remove proxy (%s:%d -> remote:%d): RemoveProxy returned into failure state", command.SourceIP, command.SourcePort, command.DestPort)
-				hasAnyFailed = true
-			}
+		var (
+			message    string
+			statusCode int
+		)
 
-			response := &commonbackend.ProxyStatusResponse{
-				SourceIP:   command.SourceIP,
-				SourcePort: command.SourcePort,
-				DestPort:   command.DestPort,
-				Protocol:   command.Protocol,
-				IsActive:   hasAnyFailed,
-			}
+		if err != nil {
+			message = err.Error()
+			statusCode = commonbackend.StatusFailure
+		} else {
+			statusCode = commonbackend.StatusSuccess
+		}
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+		response = &commonbackend.BackendStatusResponse{
+			IsRunning:  ok,
+			StatusCode: statusCode,
+			Message:    message,
+		}
+	case *commonbackend.BackendStatusRequest:
+		ok, err := helper.Backend.GetBackendStatus()
 
-			if err != nil {
-				log.Error("failed to marshal response: %s", err.Error())
-				continue
-			}
+		var (
+			message    string
+			statusCode int
+		)
 
-			helper.socket.Write(responseMarshalled)
-		case *commonbackend.ProxyConnectionsRequest:
-			connections := helper.Backend.GetAllClientConnections()
+		if err != nil {
+			message = err.Error()
+			statusCode = commonbackend.StatusFailure
+		} else {
+			statusCode = commonbackend.StatusSuccess
+		}
 
-			serverParams := &commonbackend.ProxyConnectionsResponse{
-				Connections: connections,
-			}
+		response = &commonbackend.BackendStatusResponse{
+			IsRunning:  ok,
+			StatusCode: statusCode,
+			Message:    message,
+		}
+	case *commonbackend.Stop:
+		ok, err := helper.Backend.StopBackend()
 
-			byteData, err := commonbackend.Marshal(serverParams)
+		var (
+			message    string
+			statusCode int
+		)
 
-			if err != nil {
-				return err
-			}
+		if err != nil {
+			message = err.Error()
+			statusCode = commonbackend.StatusFailure
+		} else {
+			statusCode = commonbackend.StatusSuccess
+		}
 
-			if _, err = helper.socket.Write(byteData); err != nil {
-				return err
-			}
-		case *commonbackend.CheckClientParameters:
-			resp := helper.Backend.CheckParametersForConnections(command)
-			resp.InResponseTo = "checkClientParameters"
+		response = &commonbackend.BackendStatusResponse{
+			IsRunning:  !ok,
+			StatusCode: statusCode,
+			Message:    message,
+		}
+	case *commonbackend.AddProxy:
+		ok, err := helper.Backend.StartProxy(command)
+		var hasAnyFailed bool
 
-			byteData, err := commonbackend.Marshal(resp)
+		if err != nil {
+			log.Warnf("failed to add proxy (%s:%d -> remote:%d): %s", command.SourceIP, command.SourcePort, command.DestPort, err.Error())
+			hasAnyFailed = true
+		} else if !ok {
+			log.Warnf("failed to add proxy (%s:%d -> remote:%d): StartProxy returned into failure state", command.SourceIP, command.SourcePort, command.DestPort)
+			hasAnyFailed = true
+		}
 
-			if err != nil {
-				return err
-			}
+		response = &commonbackend.ProxyStatusResponse{
+			SourceIP:   command.SourceIP,
+			SourcePort: command.SourcePort,
+			DestPort:   command.DestPort,
+			Protocol:   command.Protocol,
+			IsActive:   !hasAnyFailed,
+		}
+	case *commonbackend.RemoveProxy:
+		ok, err := helper.Backend.StopProxy(command)
+		var hasAnyFailed bool
 
-			if _, err = helper.socket.Write(byteData); err != nil {
-				return err
-			}
-		case *commonbackend.CheckServerParameters:
-			resp := helper.Backend.CheckParametersForBackend(command.Arguments)
-			resp.InResponseTo = "checkServerParameters"
+		if err != nil {
+			log.Warnf("failed to remove proxy (%s:%d -> remote:%d): %s", command.SourceIP, command.SourcePort, command.DestPort, err.Error())
+			hasAnyFailed = true
+		} else if !ok {
+			log.Warnf("failed to remove proxy (%s:%d -> remote:%d): RemoveProxy returned into failure state", command.SourceIP, command.SourcePort, command.DestPort)
+			hasAnyFailed = true
+		}
 
-			byteData, err := commonbackend.Marshal(resp)
+		response = &commonbackend.ProxyStatusResponse{
+			SourceIP:   command.SourceIP,
+			SourcePort: command.SourcePort,
+			DestPort:   command.DestPort,
+			Protocol:   command.Protocol,
+			IsActive:   hasAnyFailed,
+		}
+	case *commonbackend.ProxyConnectionsRequest:
+		connections := helper.Backend.GetAllClientConnections()
 
-			if err != nil {
-				return err
-			}
+		response = &commonbackend.ProxyConnectionsResponse{
+			Connections: connections,
+		}
+	case *commonbackend.CheckClientParameters:
+		resp := helper.Backend.CheckParametersForConnections(command)
+		resp.InResponseTo = "checkClientParameters"
+
+		response = resp
+	case *commonbackend.CheckServerParameters:
+		resp := helper.Backend.CheckParametersForBackend(command.Arguments)
+		resp.InResponseTo = "checkServerParameters"
+
+		response = resp
+	case *commonbackend.BackendParametersRequest:
+		response = &commonbackend.BackendParametersResponse{
+			Arguments: helper.Backend.GetUpdatedParameters(),
+		}
+	default:
+		log.Warnf("Unsupported command recieved: %T", command)
 
-			if _, err = helper.socket.Write(byteData); err != nil {
-				return err
-			}
-		default:
-			log.Warnf("Unsupported command recieved: %T", command)
+		// Otherwise, the API would be stuck waiting for a response that never comes
+		response = &commonbackend.ErrorResponse{
+			Message: fmt.Sprintf("unsupported command: %T", command),
 		}
 	}
+
+	return helper.writeMessage(requestID, response)
 }
 
 func NewHelper(backend BackendInterface) *BackendApplicationHelper {
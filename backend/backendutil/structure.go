@@ -11,4 +11,6 @@ type BackendInterface interface {
 	GetAllClientConnections() []*commonbackend.ProxyClientConnection
 	CheckParametersForConnections(clientParameters *commonbackend.CheckClientParameters) *commonbackend.CheckParametersResponse
 	CheckParametersForBackend(arguments []byte) *commonbackend.CheckParametersResponse
+	GetUpdatedParameters() []byte
+	GetCapabilities() uint32
 }
@@ -1,4 +1,21 @@
 #!/usr/bin/env bash
+if [ ! -d "sshbackend/remote-bin" ]; then
+  mkdir "sshbackend/remote-bin"
+fi
+
+pushd sshbackend/udphelper > /dev/null
+echo "building sshbackend/udphelper"
+# Like the sshappbackend remote code, this runs on the remote server, so we build it for as many systems as possible
+echo " - building for arm64"
+CGO_ENABLED=0 GOOS=linux GOARCH=arm64 go build -ldflags="-s -w" -trimpath -o ../remote-bin/udphelper-arm64 .
+echo " - building for arm"
+CGO_ENABLED=0 GOOS=linux GOARCH=arm go build -ldflags="-s -w" -trimpath -o ../remote-bin/udphelper-arm .
+echo " - building for amd64"
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags="-s -w" -trimpath -o ../remote-bin/udphelper-amd64 .
+echo " - building for i386"
+CGO_ENABLED=0 GOOS=linux GOARCH=386 go build -ldflags="-s -w" -trimpath -o ../remote-bin/udphelper-386 .
+popd > /dev/null
+
 pushd sshbackend > /dev/null
 echo "building sshbackend"
 go build -ldflags="-s -w" -trimpath .
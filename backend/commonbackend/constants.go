@@ -86,6 +86,34 @@ type CheckServerParameters struct {
 	Arguments []byte
 }
 
+type BackendParametersRequest struct {
+}
+
+// Sent if the backend has changed its own parameters (ex. trusting a host key on first use), so that the API can
+// persist them. Arguments is empty if nothing has changed.
+type BackendParametersResponse struct {
+	Arguments []byte
+}
+
+// Sent by the backend as soon as it connects, before anything else. Backends that don't send it are assumed to speak
+// the first version of the protocol, which didn't have request IDs, and can't be used.
+type Hello struct {
+	Version      uint16
+	Capabilities uint32 // Capability* constants of everything that the backend supports, ORed together
+}
+
+// Sent by the API to check if the backend is still responsive. The backend responds with Pong right away.
+type Ping struct {
+}
+
+type Pong struct {
+}
+
+// Sent by the backend instead of a response, if it can't handle a command (ex. it doesn't know about it)
+type ErrorResponse struct {
+	Message string
+}
+
 // Sent as a response to either CheckClientParameters or CheckBackendParameters
 type CheckParametersResponse struct {
 	InResponseTo string // Will be either 'checkClientParameters' or 'checkServerParameters'
@@ -109,6 +137,22 @@ const (
 	ProxyStatusResponseID
 	ProxyInstanceResponseID
 	ProxyInstanceRequestID
+	BackendParametersRequestID
+	BackendParametersResponseID
+	HelloID
+	PingID
+	PongID
+	ErrorResponseID
+)
+
+// Version of the protocol implemented by this package. Every message carries a request ID since version 2.
+const CurrentProtocolVersion = 2
+
+const (
+	CapabilityTCP = 1 << iota
+	CapabilityUDP
+	CapabilityConnectionListing // ProxyConnectionsRequest
+	CapabilityProxyInstances    // ProxyInstanceRequest
 )
 
 const (
@@ -84,7 +84,25 @@ func marshalIndividualProxyStruct(conn *ProxyInstance) ([]byte, error) {
 	return proxyBlock, nil
 }
 
-func Marshal(command interface{}) ([]byte, error) {
+// Marshals a command, and tags it with a request ID. Responses carry the ID of the request that they're for, so that
+// many requests can be in flight at once over the same connection.
+func Marshal(requestID uint32, command interface{}) ([]byte, error) {
+	commandBytes, err := marshalCommand(command)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The command ID stays the first byte, as the SSH app backend relies on it to tell its own messages apart
+	messageBytes := make([]byte, len(commandBytes)+4)
+	messageBytes[0] = commandBytes[0]
+	binary.BigEndian.PutUint32(messageBytes[1:5], requestID)
+	copy(messageBytes[5:], commandBytes[1:])
+
+	return messageBytes, nil
+}
+
+func marshalCommand(command interface{}) ([]byte, error) {
 	switch command := command.(type) {
 	case *Start:
 		startCommandBytes := make([]byte, 1+2+len(command.Arguments))
@@ -411,6 +429,34 @@ func Marshal(command interface{}) ([]byte, error) {
 		return []byte{ProxyInstanceRequestID}, nil
 	case *ProxyConnectionsRequest:
 		return []byte{ProxyConnectionsRequestID}, nil
+	case *BackendParametersRequest:
+		return []byte{BackendParametersRequestID}, nil
+	case *BackendParametersResponse:
+		parametersResponseBytes := make([]byte, 1+2+len(command.Arguments))
+		parametersResponseBytes[0] = BackendParametersResponseID
+		binary.BigEndian.PutUint16(parametersResponseBytes[1:3], uint16(len(command.Arguments)))
+		copy(parametersResponseBytes[3:], command.Arguments)
+
+		return parametersResponseBytes, nil
+	case *Hello:
+		helloBytes := make([]byte, 1+2+4)
+		helloBytes[0] = HelloID
+		binary.BigEndian.PutUint16(helloBytes[1:3], command.Version)
+		binary.BigEndian.PutUint32(helloBytes[3:7], command.Capabilities)
+
+		return helloBytes, nil
+	case *Ping:
+		return []byte{PingID}, nil
+	case *Pong:
+		return []byte{PongID}, nil
+	case *ErrorResponse:
+		errorBytes := make([]byte, 1+2+len(command.Message))
+		errorBytes[0] = ErrorResponseID
+
+		binary.BigEndian.PutUint16(errorBytes[1:3], uint16(len(command.Message)))
+		copy(errorBytes[3:], []byte(command.Message))
+
+		return errorBytes, nil
 	}
 
 	return nil, fmt.Errorf("couldn't match command type")
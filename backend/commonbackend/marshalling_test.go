@@ -14,7 +14,7 @@ func TestStart(t *testing.T) {
 		Arguments: []byte("Hello from automated testing"),
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -25,7 +25,7 @@ func TestStart(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -45,7 +45,7 @@ func TestStart(t *testing.T) {
 func TestStop(t *testing.T) {
 	commandInput := &Stop{}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -56,7 +56,7 @@ func TestStop(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -77,7 +77,7 @@ func TestAddConnection(t *testing.T) {
 		Protocol:   "tcp",
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -88,7 +88,7 @@ func TestAddConnection(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -129,7 +129,7 @@ func TestRemoveConnection(t *testing.T) {
 		Protocol:   "tcp",
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -140,7 +140,7 @@ func TestRemoveConnection(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -200,7 +200,7 @@ func TestGetAllConnections(t *testing.T) {
 		},
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -211,7 +211,7 @@ func TestGetAllConnections(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -261,7 +261,7 @@ func TestCheckClientParameters(t *testing.T) {
 		Protocol:   "tcp",
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -272,7 +272,7 @@ func TestCheckClientParameters(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -310,7 +310,7 @@ func TestCheckServerParameters(t *testing.T) {
 		Arguments: []byte("Hello from automated testing"),
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -321,7 +321,7 @@ func TestCheckServerParameters(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -345,7 +345,7 @@ func TestCheckParametersResponse(t *testing.T) {
 		Message:      "Hello from automated testing",
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -356,7 +356,7 @@ func TestCheckParametersResponse(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -386,7 +386,7 @@ func TestCheckParametersResponse(t *testing.T) {
 
 func TestBackendStatusRequest(t *testing.T) {
 	commandInput := &BackendStatusRequest{}
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -397,7 +397,7 @@ func TestBackendStatusRequest(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -417,7 +417,7 @@ func TestBackendStatusResponse(t *testing.T) {
 		Message:    "Hello from automated testing",
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -428,7 +428,7 @@ func TestBackendStatusResponse(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -464,7 +464,7 @@ func TestProxyStatusRequest(t *testing.T) {
 		Protocol:   "tcp",
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -475,7 +475,7 @@ func TestProxyStatusRequest(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -517,7 +517,7 @@ func TestProxyStatusResponse(t *testing.T) {
 		IsActive:   true,
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -528,7 +528,7 @@ func TestProxyStatusResponse(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -569,7 +569,7 @@ func TestProxyStatusResponse(t *testing.T) {
 func TestProxyConnectionRequest(t *testing.T) {
 	commandInput := &ProxyInstanceRequest{}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if logLevel == "debug" {
 		log.Printf("Generated array contents: %v", commandMarshalled)
@@ -580,7 +580,7 @@ func TestProxyConnectionRequest(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -617,7 +617,7 @@ func TestProxyConnectionResponse(t *testing.T) {
 		},
 	}
 
-	commandMarshalled, err := Marshal(commandInput)
+	commandMarshalled, err := Marshal(0, commandInput)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -628,7 +628,7 @@ func TestProxyConnectionResponse(t *testing.T) {
 	}
 
 	buf := bytes.NewBuffer(commandMarshalled)
-	commandUnmarshalledRaw, err := Unmarshal(buf)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
 
 	if err != nil {
 		t.Fatal(err.Error())
@@ -664,3 +664,222 @@ func TestProxyConnectionResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestBackendParametersRequest(t *testing.T) {
+	commandInput := &BackendParametersRequest{}
+
+	commandMarshalled, err := Marshal(0, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, ok := commandUnmarshalledRaw.(*BackendParametersRequest)
+
+	if !ok {
+		t.Fatal("failed typecast")
+	}
+}
+
+func TestBackendParametersResponse(t *testing.T) {
+	commandInput := &BackendParametersResponse{
+		Arguments: []byte("Hello from automated testing"),
+	}
+
+	commandMarshalled, err := Marshal(0, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	commandUnmarshalled, ok := commandUnmarshalledRaw.(*BackendParametersResponse)
+
+	if !ok {
+		t.Fatal("failed typecast")
+	}
+
+	if !bytes.Equal(commandInput.Arguments, commandUnmarshalled.Arguments) {
+		log.Fatalf("Arguments are not equal (orig: '%s', unmsh: '%s')", string(commandInput.Arguments), string(commandUnmarshalled.Arguments))
+	}
+}
+
+func TestHello(t *testing.T) {
+	commandInput := &Hello{
+		Version:      CurrentProtocolVersion,
+		Capabilities: CapabilityTCP | CapabilityConnectionListing,
+	}
+
+	commandMarshalled, err := Marshal(0, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	commandUnmarshalled, ok := commandUnmarshalledRaw.(*Hello)
+
+	if !ok {
+		t.Fatal("failed typecast")
+	}
+
+	if commandInput.Version != commandUnmarshalled.Version {
+		t.Fail()
+		log.Printf("Version's are not equal (orig: %d, unmsh: %d)", commandInput.Version, commandUnmarshalled.Version)
+	}
+
+	if commandInput.Capabilities != commandUnmarshalled.Capabilities {
+		t.Fail()
+		log.Printf("Capabilities are not equal (orig: %d, unmsh: %d)", commandInput.Capabilities, commandUnmarshalled.Capabilities)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	commandInput := &BackendStatusRequest{}
+
+	commandMarshalled, err := Marshal(0xDEADBEEF, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if commandMarshalled[0] != BackendStatusRequestID {
+		t.Fatalf("command ID isn't the first byte (got %d)", commandMarshalled[0])
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	requestID, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, ok := commandUnmarshalledRaw.(*BackendStatusRequest); !ok {
+		t.Fatal("failed typecast")
+	}
+
+	if requestID != 0xDEADBEEF {
+		t.Fatalf("Request IDs are not equal (orig: %d, unmsh: %d)", 0xDEADBEEF, requestID)
+	}
+}
+
+func TestPing(t *testing.T) {
+	commandInput := &Ping{}
+	commandMarshalled, err := Marshal(0, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, ok := commandUnmarshalledRaw.(*Ping)
+
+	if !ok {
+		t.Fatal("failed typecast")
+	}
+}
+
+func TestPong(t *testing.T) {
+	commandInput := &Pong{}
+	commandMarshalled, err := Marshal(0, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, ok := commandUnmarshalledRaw.(*Pong)
+
+	if !ok {
+		t.Fatal("failed typecast")
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	commandInput := &ErrorResponse{
+		Message: "unsupported command",
+	}
+
+	commandMarshalled, err := Marshal(0, commandInput)
+
+	if logLevel == "debug" {
+		log.Printf("Generated array contents: %v", commandMarshalled)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(commandMarshalled)
+	_, commandUnmarshalledRaw, err := Unmarshal(buf)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	commandUnmarshalled, ok := commandUnmarshalledRaw.(*ErrorResponse)
+
+	if !ok {
+		t.Fatal("failed typecast")
+	}
+
+	if commandInput.Message != commandUnmarshalled.Message {
+		t.Fail()
+		log.Printf("Message's are not equal (orig: '%s', unmsh: '%s')", commandInput.Message, commandUnmarshalled.Message)
+	}
+}
@@ -142,14 +142,26 @@ func unmarshalIndividualProxyStruct(conn io.Reader) (*ProxyInstance, error) {
 	}, nil
 }
 
-func Unmarshal(conn io.Reader) (interface{}, error) {
+// Unmarshals a command, along with the request ID that it was tagged with
+func Unmarshal(conn io.Reader) (uint32, interface{}, error) {
 	commandType := make([]byte, 1)
 
 	if _, err := conn.Read(commandType); err != nil {
-		return nil, fmt.Errorf("couldn't read command")
+		return 0, nil, fmt.Errorf("couldn't read command")
 	}
 
-	switch commandType[0] {
+	requestID := make([]byte, 4)
+
+	if _, err := io.ReadFull(conn, requestID); err != nil {
+		return 0, nil, fmt.Errorf("couldn't read request ID")
+	}
+
+	command, err := unmarshalCommand(commandType[0], conn)
+	return binary.BigEndian.Uint32(requestID), command, err
+}
+
+func unmarshalCommand(commandType byte, conn io.Reader) (interface{}, error) {
+	switch commandType {
 	case StartID:
 		argumentsLength := make([]byte, 2)
 
@@ -640,6 +652,70 @@ func Unmarshal(conn io.Reader) (interface{}, error) {
 		}, errorReturn
 	case ProxyConnectionsRequestID:
 		return &ProxyConnectionsRequest{}, nil
+	case BackendParametersRequestID:
+		return &BackendParametersRequest{}, nil
+	case BackendParametersResponseID:
+		argumentsLength := make([]byte, 2)
+
+		if _, err := conn.Read(argumentsLength); err != nil {
+			return nil, fmt.Errorf("couldn't read argument length")
+		}
+
+		arguments := make([]byte, binary.BigEndian.Uint16(argumentsLength))
+
+		if len(arguments) != 0 {
+			if _, err := conn.Read(arguments); err != nil {
+				return nil, fmt.Errorf("couldn't read arguments")
+			}
+		}
+
+		return &BackendParametersResponse{
+			Arguments: arguments,
+		}, nil
+	case HelloID:
+		version := make([]byte, 2)
+
+		if _, err := conn.Read(version); err != nil {
+			return nil, fmt.Errorf("couldn't read protocol version")
+		}
+
+		capabilities := make([]byte, 4)
+
+		if _, err := conn.Read(capabilities); err != nil {
+			return nil, fmt.Errorf("couldn't read capabilities")
+		}
+
+		return &Hello{
+			Version:      binary.BigEndian.Uint16(version),
+			Capabilities: binary.BigEndian.Uint32(capabilities),
+		}, nil
+	case PingID:
+		return &Ping{}, nil
+	case PongID:
+		return &Pong{}, nil
+	case ErrorResponseID:
+		messageLengthBytes := make([]byte, 2)
+
+		if _, err := conn.Read(messageLengthBytes); err != nil {
+			return nil, fmt.Errorf("couldn't read message length")
+		}
+
+		messageLength := binary.BigEndian.Uint16(messageLengthBytes)
+		var message string
+
+		if messageLength != 0 {
+			messageBytes := make([]byte, messageLength)
+
+			if _, err := conn.Read(messageBytes); err != nil {
+				return nil, fmt.Errorf("couldn't read message")
+			}
+
+			message = string(messageBytes)
+		}
+
+		return &ErrorResponse{
+			Message: message,
+		}, nil
 	}
 
 	return nil, fmt.Errorf("couldn't match command ID")
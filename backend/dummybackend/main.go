@@ -53,6 +53,18 @@ func (backend *DummyBackend) CheckParametersForBackend(arguments []byte) *common
 	}
 }
 
+func (backend *DummyBackend) GetUpdatedParameters() []byte {
+	// Only needed if the backend changes its own parameters (ex. to remember something across restarts).
+	// Return nil if nothing has changed.
+	return nil
+}
+
+func (backend *DummyBackend) GetCapabilities() uint32 {
+	// Tells the API what the backend supports, so that it can refuse anything else (ex. UDP proxies on a TCP only
+	// backend). Use the commonbackend.Capability* constants.
+	return commonbackend.CapabilityTCP | commonbackend.CapabilityUDP | commonbackend.CapabilityConnectionListing
+}
+
 func main() {
 	// When using logging, you should use charmbracelet/log, because that's what everything else uses in this ecosystem of a project. - imterah
 	logLevel := os.Getenv("HERMES_LOG_LEVEL")
@@ -111,11 +111,42 @@ func entrypoint(cCtx *cli.Context) error {
 
 			defer sock.Close()
 
+			_, commandRaw, err := commonbackend.Unmarshal(sock)
+
+			if err != nil {
+				log.Errorf("failed to read hello from socket: %s", err.Error())
+				continue
+			}
+
+			hello, ok := commandRaw.(*commonbackend.Hello)
+
+			if !ok || hello.Version != commonbackend.CurrentProtocolVersion {
+				log.Errorf("backend doesn't speak protocol version %d", commonbackend.CurrentProtocolVersion)
+				continue
+			}
+
+			helloMarshalledCommand, err := commonbackend.Marshal(0, &commonbackend.Hello{
+				Version: commonbackend.CurrentProtocolVersion,
+			})
+
+			if err != nil {
+				log.Errorf("failed to generate hello command: %s", err.Error())
+				continue
+			}
+
+			if _, err = sock.Write(helloMarshalledCommand); err != nil {
+				log.Errorf("failed to write to socket: %s", err.Error())
+				continue
+			}
+
+			// Requests are sent one at a time, so they can all share the same ID
+			const requestID = 1
+
 			startCommand := &commonbackend.Start{
 				Arguments: backendParameters,
 			}
 
-			startMarshalledCommand, err := commonbackend.Marshal(startCommand)
+			startMarshalledCommand, err := commonbackend.Marshal(requestID, startCommand)
 
 			if err != nil {
 				log.Errorf("failed to generate start command: %s", err.Error())
@@ -127,7 +158,7 @@ func entrypoint(cCtx *cli.Context) error {
 				continue
 			}
 
-			commandRaw, err := commonbackend.Unmarshal(sock)
+			_, commandRaw, err = commonbackend.Unmarshal(sock)
 
 			if err != nil {
 				log.Errorf("failed to read from/unmarshal from socket: %s", err.Error())
@@ -168,7 +199,7 @@ func entrypoint(cCtx *cli.Context) error {
 					Protocol:   proxy.Protocol,
 				}
 
-				marshalledProxyCommand, err := commonbackend.Marshal(proxyAddCommand)
+				marshalledProxyCommand, err := commonbackend.Marshal(requestID, proxyAddCommand)
 
 				if err != nil {
 					log.Errorf("failed to generate start command: %s", err.Error())
@@ -182,7 +213,7 @@ func entrypoint(cCtx *cli.Context) error {
 					continue
 				}
 
-				commandRaw, err := commonbackend.Unmarshal(sock)
+				_, commandRaw, err := commonbackend.Unmarshal(sock)
 
 				if err != nil {
 					log.Errorf("failed to read from/unmarshal from socket: %s", err.Error())
@@ -0,0 +1,226 @@
+// A minimal OpenID Connect identity provider, for testing single sign-on locally. Every login is approved straight
+// away, as the user configured through the environment.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type pendingCode struct {
+	Nonce         string
+	CodeChallenge string
+	RedirectURI   string
+	ExpiresAt     time.Time
+}
+
+var (
+	issuer       string
+	clientID     string
+	clientSecret string
+
+	signingKey *rsa.PrivateKey
+	keyID      string
+
+	codesLock sync.Mutex
+	codes     = map[string]*pendingCode{}
+)
+
+func getEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+func discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": keyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("client_id") != clientID {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+
+	if err != nil || redirectURI.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	codeRandomData := make([]byte, 16)
+	rand.Read(codeRandomData)
+	code := base64.RawURLEncoding.EncodeToString(codeRandomData)
+
+	codesLock.Lock()
+	codes[code] = &pendingCode{
+		Nonce:         query.Get("nonce"),
+		CodeChallenge: query.Get("code_challenge"),
+		RedirectURI:   redirectURI.String(),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	codesLock.Unlock()
+
+	redirectQuery := redirectURI.Query()
+	redirectQuery.Set("code", code)
+	redirectQuery.Set("state", query.Get("state"))
+	redirectURI.RawQuery = redirectQuery.Encode()
+
+	log.Infof("Approved login for '%s'", getEnv("MOCK_OIDC_USERNAME", "mockuser"))
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	requestClientID, requestClientSecret, ok := r.BasicAuth()
+
+	if ok {
+		requestClientID, _ = url.QueryUnescape(requestClientID)
+		requestClientSecret, _ = url.QueryUnescape(requestClientSecret)
+	} else {
+		requestClientID = r.PostForm.Get("client_id")
+		requestClientSecret = r.PostForm.Get("client_secret")
+	}
+
+	if requestClientID != clientID || requestClientSecret != clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	codesLock.Lock()
+	code, ok := codes[r.PostForm.Get("code")]
+	delete(codes, r.PostForm.Get("code"))
+	codesLock.Unlock()
+
+	if !ok || time.Now().After(code.ExpiresAt) || code.RedirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if code.CodeChallenge != "" {
+		verifierHash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+
+		if base64.RawURLEncoding.EncodeToString(verifierHash[:]) != code.CodeChallenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+			return
+		}
+	}
+
+	username := getEnv("MOCK_OIDC_USERNAME", "mockuser")
+	groups := []string{}
+
+	if groupsString := os.Getenv("MOCK_OIDC_GROUPS"); groupsString != "" {
+		groups = strings.Split(groupsString, ",")
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                issuer,
+		"aud":                clientID,
+		"sub":                getEnv("MOCK_OIDC_SUBJECT", username),
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"nonce":              code.Nonce,
+		"preferred_username": username,
+		"name":               getEnv("MOCK_OIDC_NAME", username),
+		"email":              getEnv("MOCK_OIDC_EMAIL", username+"@example.com"),
+		"email_verified":     true,
+		"groups":             groups,
+	})
+
+	idToken.Header["kid"] = keyID
+	signedIDToken, err := idToken.SignedString(signingKey)
+
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signedIDToken,
+	})
+}
+
+func main() {
+	listeningAddress := getEnv("MOCK_OIDC_LISTENING_ADDRESS", "127.0.0.1:9000")
+	issuer = getEnv("MOCK_OIDC_ISSUER", "http://"+listeningAddress)
+	clientID = getEnv("MOCK_OIDC_CLIENT_ID", "hermes")
+	clientSecret = getEnv("MOCK_OIDC_CLIENT_SECRET", "hermes")
+
+	var err error
+	signingKey, err = rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %s", err.Error())
+	}
+
+	// Changes on every start, like it would when a real identity provider rotates its keys
+	keyIDRandomData := make([]byte, 8)
+	rand.Read(keyIDRandomData)
+	keyID = base64.RawURLEncoding.EncodeToString(keyIDRandomData)
+
+	http.HandleFunc("/.well-known/openid-configuration", discovery)
+	http.HandleFunc("/jwks", jwks)
+	http.HandleFunc("/authorize", authorize)
+	http.HandleFunc("/token", token)
+
+	log.Infof("Mock identity provider listening on %s (issuer: %s)", listeningAddress, issuer)
+
+	if err := http.ListenAndServe(listeningAddress, nil); err != nil {
+		log.Fatalf("Failed to listen: %s", err.Error())
+	}
+}
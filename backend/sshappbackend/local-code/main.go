@@ -1,9 +1,6 @@
 package main
 
 import (
-	"bytes"
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,7 +8,7 @@ import (
 	"math/rand/v2"
 	"net"
 	"os"
-	"strings"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,9 +17,9 @@ import (
 	"git.terah.dev/imterah/hermes/backend/sshappbackend/datacommands"
 	"git.terah.dev/imterah/hermes/backend/sshappbackend/gaslighter"
 	"git.terah.dev/imterah/hermes/backend/sshappbackend/local-code/porttranslation"
+	"git.terah.dev/imterah/hermes/backend/sshutil"
 	"github.com/charmbracelet/log"
 	"github.com/go-playground/validator/v10"
-	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -40,18 +37,43 @@ type SSHAppBackendData struct {
 	IP          string   `json:"ip" validate:"required"`
 	Port        uint16   `json:"port" validate:"required"`
 	Username    string   `json:"username" validate:"required"`
-	PrivateKey  string   `json:"privateKey" validate:"required"`
 	ListenOnIPs []string `json:"listenOnIPs"`
+
+	sshutil.AuthConfig
+
+	HostKeys        []string `json:"hostKeys"`
+	KnownHosts      string   `json:"knownHosts"`
+	TrustOnFirstUse bool     `json:"trustOnFirstUse"`
+
+	// How often (in seconds) we check if the server is still alive, and how many missed checks it takes to reconnect
+	KeepaliveInterval  uint `json:"keepaliveInterval"`
+	KeepaliveMaxMissed uint `json:"keepaliveMaxMissed"`
 }
 
 type SSHAppBackend struct {
-	config      *SSHAppBackendData
-	conn        *ssh.Client
-	listener    net.Listener
-	currentSock net.Conn
-
-	tcpProxies map[uint16]*TCPProxy
-	udpProxies map[uint16]*UDPProxy
+	config          *SSHAppBackendData
+	rawConfig       []byte
+	hostKeyVerifier *sshutil.HostKeyVerifier
+	conn            *ssh.Client
+	listener        net.Listener
+	currentSock     net.Conn
+
+	// sockClosed: Closed once the current socket connection to the remote code goes away, so that nothing waits
+	// on a reply forever.
+	sockClosed chan struct{}
+
+	statusLock     sync.Mutex
+	isStopped      bool
+	isReconnecting bool
+	reconnectError error
+	backoff        sshutil.Backoff
+	stopChan       chan struct{}
+
+	// proxiesLock: Locks tcpProxies and udpProxies, as they're used by both API commands and the connection to the
+	// remote code.
+	proxiesLock sync.Mutex
+	tcpProxies  map[uint16]*TCPProxy
+	udpProxies  map[uint16]*UDPProxy
 
 	// globalNonCriticalMessageLock: Locks all messages that don't need low-latency transmissions & high
 	// speed behind a lock. This ensures safety when it comes to handling messages correctly.
@@ -63,8 +85,11 @@ type SSHAppBackend struct {
 func (backend *SSHAppBackend) StartBackend(configBytes []byte) (bool, error) {
 	log.Info("SSHAppBackend is initializing...")
 	backend.globalNonCriticalMessageChan = make(chan interface{})
+
+	backend.proxiesLock.Lock()
 	backend.tcpProxies = map[uint16]*TCPProxy{}
 	backend.udpProxies = map[uint16]*UDPProxy{}
+	backend.proxiesLock.Unlock()
 
 	var backendData SSHAppBackendData
 
@@ -77,300 +102,72 @@ func (backend *SSHAppBackend) StartBackend(configBytes []byte) (bool, error) {
 	}
 
 	backend.config = &backendData
+	backend.rawConfig = configBytes
 
 	if len(backend.config.ListenOnIPs) == 0 {
 		backend.config.ListenOnIPs = []string{"0.0.0.0"}
 	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(backendData.PrivateKey))
+	hostKeyVerifier, err := sshutil.NewHostKeyVerifier(backendData.HostKeys, backendData.KnownHosts, backendData.TrustOnFirstUse)
 
 	if err != nil {
 		log.Warnf("Failed to initialize: %s", err.Error())
 		return false, err
 	}
 
-	auth := ssh.PublicKeys(signer)
-
-	config := &ssh.ClientConfig{
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		User:            backendData.Username,
-		Auth: []ssh.AuthMethod{
-			auth,
-		},
-	}
+	backend.hostKeyVerifier = hostKeyVerifier
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", backendData.IP, backendData.Port), config)
-
-	if err != nil {
+	if err := backend.connect(); err != nil {
 		log.Warnf("Failed to initialize: %s", err.Error())
 		return false, err
 	}
 
-	backend.conn = conn
-
-	log.Debug("SSHAppBackend has connected successfully.")
-	log.Debug("Getting CPU architecture...")
-
-	session, err := backend.conn.NewSession()
-
-	if err != nil {
-		log.Warnf("Failed to create session: %s", err.Error())
-		conn.Close()
-		backend.conn = nil
-		return false, err
-	}
-
-	var stdoutBuf bytes.Buffer
-	session.Stdout = &stdoutBuf
-
-	err = session.Run("uname -m")
-
-	if err != nil {
-		log.Warnf("Failed to run uname command: %s", err.Error())
-		conn.Close()
-		backend.conn = nil
-		return false, err
-	}
-
-	cpuArchBytes := make([]byte, stdoutBuf.Len())
-	stdoutBuf.Read(cpuArchBytes)
-
-	cpuArch := string(cpuArchBytes)
-	cpuArch = cpuArch[:len(cpuArch)-1]
-
-	var backendBinary string
-
-	// Ordered in (subjective) popularity
-	if cpuArch == "x86_64" {
-		backendBinary = "remote-bin/rt-amd64"
-	} else if cpuArch == "aarch64" {
-		backendBinary = "remote-bin/rt-arm64"
-	} else if cpuArch == "arm" {
-		backendBinary = "remote-bin/rt-arm"
-	} else if len(cpuArch) == 4 && string(cpuArch[0]) == "i" && strings.HasSuffix(cpuArch, "86") {
-		backendBinary = "remote-bin/rt-386"
-	} else {
-		log.Warn("Failed to determine executable to use: CPU architecture not compiled/supported currently")
-		conn.Close()
-		backend.conn = nil
-		return false, fmt.Errorf("CPU architecture not compiled/supported currently")
-	}
-
-	log.Debug("Checking if we need to copy the application...")
-
-	var binary []byte
-	needsToCopyBinary := true
-
-	session, err = backend.conn.NewSession()
-
-	if err != nil {
-		log.Warnf("Failed to create session: %s", err.Error())
-		conn.Close()
-		backend.conn = nil
-		return false, err
-	}
-
-	session.Stdout = &stdoutBuf
-
-	err = session.Start("[ -f /tmp/sshappbackend.runtime ] && md5sum /tmp/sshappbackend.runtime | cut -d \" \" -f 1")
-
-	if err != nil {
-		log.Warnf("Failed to calculate hash of possibly existing backend: %s", err.Error())
-		conn.Close()
-		backend.conn = nil
-		return false, err
-	}
-
-	fileExists := stdoutBuf.Len() != 0
-
-	if fileExists {
-		remoteMD5HashStringBuf := make([]byte, stdoutBuf.Len())
-		stdoutBuf.Read(remoteMD5HashStringBuf)
-
-		remoteMD5HashString := string(remoteMD5HashStringBuf)
-		remoteMD5HashString = remoteMD5HashString[:len(remoteMD5HashString)-1]
-
-		remoteMD5Hash, err := hex.DecodeString(remoteMD5HashString)
-
-		if err != nil {
-			log.Warnf("Failed to decode hex: %s", err.Error())
-			conn.Close()
-			backend.conn = nil
-			return false, err
-		}
-
-		binary, err = binFiles.ReadFile(backendBinary)
-
-		if err != nil {
-			log.Warnf("Failed to read file in the embedded FS: %s", err.Error())
-			conn.Close()
-			backend.conn = nil
-			return false, fmt.Errorf("(embedded FS): %s", err.Error())
-		}
-
-		localMD5Hash := md5.Sum(binary)
-
-		log.Infof("remote: %s, local: %s", remoteMD5HashString, hex.EncodeToString(localMD5Hash[:]))
-
-		if bytes.Compare(localMD5Hash[:], remoteMD5Hash) == 0 {
-			needsToCopyBinary = false
-		}
-	}
-
-	if needsToCopyBinary {
-		log.Debug("Copying binary...")
-
-		sftpInstance, err := sftp.NewClient(conn)
-
-		if err != nil {
-			log.Warnf("Failed to initialize SFTP: %s", err.Error())
-			conn.Close()
-			backend.conn = nil
-			return false, err
-		}
-
-		defer sftpInstance.Close()
-
-		if len(binary) == 0 {
-			binary, err = binFiles.ReadFile(backendBinary)
-
-			if err != nil {
-				log.Warnf("Failed to read file in the embedded FS: %s", err.Error())
-				conn.Close()
-				backend.conn = nil
-				return false, fmt.Errorf("(embedded FS): %s", err.Error())
-			}
-		}
-
-		var file *sftp.File
-
-		if fileExists {
-			file, err = sftpInstance.OpenFile("/tmp/sshappbackend.runtime", os.O_WRONLY)
-		} else {
-			file, err = sftpInstance.Create("/tmp/sshappbackend.runtime")
-		}
-
-		if err != nil {
-			log.Warnf("Failed to create (or open) file: %s", err.Error())
-			conn.Close()
-			backend.conn = nil
-			return false, err
-		}
-
-		_, err = file.Write(binary)
-
-		if err != nil {
-			log.Warnf("Failed to write file: %s", err.Error())
-			conn.Close()
-			backend.conn = nil
-			return false, err
-		}
-
-		err = file.Chmod(0755)
-
-		if err != nil {
-			log.Warnf("Failed to change permissions on file: %s", err.Error())
-			conn.Close()
-			backend.conn = nil
-			return false, err
-		}
-
-		log.Debug("Done copying file.")
-		sftpInstance.Close()
-	} else {
-		log.Debug("Skipping copying as there's a copy on disk already.")
-	}
-
-	log.Debug("Initializing Unix socket...")
+	backend.stopChan = make(chan struct{})
 
-	socketPath := fmt.Sprintf("/tmp/sock-%d.sock", rand.Uint())
-	listener, err := conn.ListenUnix(socketPath)
-
-	if err != nil {
-		log.Warnf("Failed to listen on socket: %s", err.Error())
-		conn.Close()
-		backend.conn = nil
-		return false, err
-	}
+	log.Info("SSHAppBackend has initialized successfully.")
+	go backend.backendDisconnectHandler()
 
-	log.Debug("Starting process...")
+	return true, nil
+}
 
-	session, err = backend.conn.NewSession()
+func (backend *SSHAppBackend) StopBackend() (bool, error) {
+	backend.statusLock.Lock()
 
-	if err != nil {
-		log.Warnf("Failed to create session: %s", err.Error())
-		conn.Close()
-		backend.conn = nil
-		return false, err
+	if backend.isStopped {
+		backend.statusLock.Unlock()
+		return true, nil
 	}
 
-	backend.listener = listener
-
-	session.Stdout = WriteLogger{}
-	session.Stderr = WriteLogger{}
-
-	go func() {
-		for {
-			err := session.Run(fmt.Sprintf("HERMES_LOG_LEVEL=\"%s\" HERMES_API_SOCK=\"%s\" /tmp/sshappbackend.runtime", os.Getenv("HERMES_LOG_LEVEL"), socketPath))
-
-			if err != nil && !errors.Is(err, &ssh.ExitError{}) && !errors.Is(err, &ssh.ExitMissingError{}) {
-				log.Errorf("Critically failed during execution of remote code: %s", err.Error())
-				return
-			} else {
-				log.Warn("Remote code failed for an unknown reason. Restarting...")
-			}
-		}
-	}()
-
-	go backend.sockServerHandler()
+	backend.isStopped = true
+	close(backend.stopChan)
+	backend.statusLock.Unlock()
 
-	log.Debug("Started process. Waiting for Unix socket connection...")
-
-	for backend.currentSock == nil {
-		time.Sleep(10 * time.Millisecond)
+	if backend.conn == nil {
+		return true, nil
 	}
 
-	log.Debug("Detected connection. Sending initialization command...")
-
-	proxyStatusRaw, err := backend.SendNonCriticalMessage(&commonbackend.Start{
-		Arguments: []byte{},
-	})
+	err := backend.conn.Close()
 
 	if err != nil {
 		return false, err
 	}
 
-	proxyStatus, ok := proxyStatusRaw.(*commonbackend.BackendStatusResponse)
-
-	if !ok {
-		return false, fmt.Errorf("recieved invalid response type: %T", proxyStatusRaw)
-	}
-
-	if proxyStatus.StatusCode == commonbackend.StatusFailure {
-		if proxyStatus.Message == "" {
-			return false, fmt.Errorf("failed to initialize backend in remote code")
-		} else {
-			return false, fmt.Errorf("failed to initialize backend in remote code: %s", proxyStatus.Message)
-		}
-	}
-
-	log.Info("SSHAppBackend has initialized successfully.")
-
 	return true, nil
 }
 
-func (backend *SSHAppBackend) StopBackend() (bool, error) {
-	err := backend.conn.Close()
+func (backend *SSHAppBackend) GetBackendStatus() (bool, error) {
+	backend.statusLock.Lock()
+	defer backend.statusLock.Unlock()
 
-	if err != nil {
-		return false, err
-	}
+	if backend.isReconnecting {
+		if backend.reconnectError != nil {
+			return false, fmt.Errorf("reconnecting to the SSH server (attempt %d): %s", backend.backoff.Attempts, backend.reconnectError.Error())
+		}
 
-	return true, nil
-}
+		return false, fmt.Errorf("reconnecting to the SSH server")
+	}
 
-func (backend *SSHAppBackend) GetBackendStatus() (bool, error) {
-	return backend.conn != nil, nil
+	return backend.conn != nil && !backend.isStopped, nil
 }
 
 func (backend *SSHAppBackend) StartProxy(command *commonbackend.AddProxy) (bool, error) {
@@ -391,18 +188,19 @@ func (backend *SSHAppBackend) StartProxy(command *commonbackend.AddProxy) (bool,
 	}
 
 	if command.Protocol == "tcp" {
+		backend.proxiesLock.Lock()
 		backend.tcpProxies[proxyStatus.ProxyID] = &TCPProxy{
 			proxyInformation: command,
+			connections:      map[uint16]net.Conn{},
 		}
-
-		backend.tcpProxies[proxyStatus.ProxyID].connections = map[uint16]net.Conn{}
+		backend.proxiesLock.Unlock()
 	} else if command.Protocol == "udp" {
-		backend.udpProxies[proxyStatus.ProxyID] = &UDPProxy{
+		udpProxy := &UDPProxy{
 			proxyInformation: command,
 			portTranslation:  &porttranslation.PortTranslation{},
 		}
 
-		backend.udpProxies[proxyStatus.ProxyID].portTranslation.UDPAddr = &net.UDPAddr{
+		udpProxy.portTranslation.UDPAddr = &net.UDPAddr{
 			IP:   net.ParseIP(command.SourceIP),
 			Port: int(command.SourcePort),
 		}
@@ -410,7 +208,7 @@ func (backend *SSHAppBackend) StartProxy(command *commonbackend.AddProxy) (bool,
 		udpMessageCommand := &datacommands.UDPProxyData{}
 		udpMessageCommand.ProxyID = proxyStatus.ProxyID
 
-		backend.udpProxies[proxyStatus.ProxyID].portTranslation.WriteFrom = func(ip string, port uint16, data []byte) {
+		udpProxy.portTranslation.WriteFrom = func(ip string, port uint16, data []byte) {
 			udpMessageCommand.ClientIP = ip
 			udpMessageCommand.ClientPort = port
 			udpMessageCommand.DataLength = uint16(len(data))
@@ -433,12 +231,16 @@ func (backend *SSHAppBackend) StartProxy(command *commonbackend.AddProxy) (bool,
 			}
 		}
 
+		backend.proxiesLock.Lock()
+		backend.udpProxies[proxyStatus.ProxyID] = udpProxy
+		backend.proxiesLock.Unlock()
+
 		go func() {
 			for {
 				time.Sleep(3 * time.Minute)
 
 				// Checks if the proxy still exists before continuing
-				_, ok := backend.udpProxies[proxyStatus.ProxyID]
+				proxy, ok := backend.getUDPProxy(proxyStatus.ProxyID)
 
 				if !ok {
 					return
@@ -446,7 +248,7 @@ func (backend *SSHAppBackend) StartProxy(command *commonbackend.AddProxy) (bool,
 
 				// Then attempt to run cleanup tasks
 				log.Debug("Running UDP proxy cleanup tasks (invoking CleanupPorts() on portTranslation)")
-				backend.udpProxies[proxyStatus.ProxyID].portTranslation.CleanupPorts()
+				proxy.portTranslation.CleanupPorts()
 			}
 		}()
 	}
@@ -454,9 +256,46 @@ func (backend *SSHAppBackend) StartProxy(command *commonbackend.AddProxy) (bool,
 	return true, nil
 }
 
+// Gets a copy of tcpProxies and udpProxies, so that they can be looped over while sending messages to the remote code
+func (backend *SSHAppBackend) copyProxies() (map[uint16]*TCPProxy, map[uint16]*UDPProxy) {
+	backend.proxiesLock.Lock()
+	defer backend.proxiesLock.Unlock()
+
+	tcpProxies := make(map[uint16]*TCPProxy, len(backend.tcpProxies))
+	udpProxies := make(map[uint16]*UDPProxy, len(backend.udpProxies))
+
+	for proxyID, proxy := range backend.tcpProxies {
+		tcpProxies[proxyID] = proxy
+	}
+
+	for proxyID, proxy := range backend.udpProxies {
+		udpProxies[proxyID] = proxy
+	}
+
+	return tcpProxies, udpProxies
+}
+
+func (backend *SSHAppBackend) getTCPProxy(proxyID uint16) (*TCPProxy, bool) {
+	backend.proxiesLock.Lock()
+	defer backend.proxiesLock.Unlock()
+
+	proxy, ok := backend.tcpProxies[proxyID]
+	return proxy, ok
+}
+
+func (backend *SSHAppBackend) getUDPProxy(proxyID uint16) (*UDPProxy, bool) {
+	backend.proxiesLock.Lock()
+	defer backend.proxiesLock.Unlock()
+
+	proxy, ok := backend.udpProxies[proxyID]
+	return proxy, ok
+}
+
 func (backend *SSHAppBackend) StopProxy(command *commonbackend.RemoveProxy) (bool, error) {
+	tcpProxies, udpProxies := backend.copyProxies()
+
 	if command.Protocol == "tcp" {
-		for proxyIndex, proxy := range backend.tcpProxies {
+		for proxyIndex, proxy := range tcpProxies {
 			if proxy.proxyInformation.DestPort != command.DestPort {
 				continue
 			}
@@ -500,7 +339,7 @@ func (backend *SSHAppBackend) StopProxy(command *commonbackend.RemoveProxy) (boo
 			}
 		}
 	} else if command.Protocol == "udp" {
-		for proxyIndex, proxy := range backend.udpProxies {
+		for proxyIndex, proxy := range udpProxies {
 			if proxy.proxyInformation.DestPort != command.DestPort {
 				continue
 			}
@@ -526,7 +365,10 @@ func (backend *SSHAppBackend) StopProxy(command *commonbackend.RemoveProxy) (boo
 			}
 
 			proxy.portTranslation.StopAllPorts()
+
+			backend.proxiesLock.Lock()
 			delete(backend.udpProxies, proxyIndex)
+			backend.proxiesLock.Unlock()
 		}
 	}
 
@@ -536,8 +378,9 @@ func (backend *SSHAppBackend) StopProxy(command *commonbackend.RemoveProxy) (boo
 func (backend *SSHAppBackend) GetAllClientConnections() []*commonbackend.ProxyClientConnection {
 	connections := []*commonbackend.ProxyClientConnection{}
 	informationRequest := &datacommands.ProxyConnectionInformationRequest{}
+	tcpProxies, _ := backend.copyProxies()
 
-	for proxyID, tcpProxy := range backend.tcpProxies {
+	for proxyID, tcpProxy := range tcpProxies {
 		informationRequest.ProxyID = proxyID
 
 		for connectionID := range tcpProxy.connections {
@@ -599,13 +442,68 @@ func (backend *SSHAppBackend) CheckParametersForBackend(arguments []byte) *commo
 		}
 	}
 
+	if err := sshutil.CheckAuthConfig(&backendData.AuthConfig); err != nil {
+		return &commonbackend.CheckParametersResponse{
+			IsValid: false,
+			Message: fmt.Sprintf("failed to check authentication parameters: %s", err.Error()),
+		}
+	}
+
+	hostKeyVerifier, err := sshutil.NewHostKeyVerifier(backendData.HostKeys, backendData.KnownHosts, backendData.TrustOnFirstUse)
+
+	if err != nil {
+		return &commonbackend.CheckParametersResponse{
+			IsValid: false,
+			Message: fmt.Sprintf("failed to parse host keys: %s", err.Error()),
+		}
+	}
+
+	if err := sshutil.CheckHostKey(net.JoinHostPort(backendData.IP, strconv.Itoa(int(backendData.Port))), hostKeyVerifier); err != nil {
+		return &commonbackend.CheckParametersResponse{
+			IsValid: false,
+			Message: fmt.Sprintf("failed to verify host key: %s", err.Error()),
+		}
+	}
+
 	return &commonbackend.CheckParametersResponse{
 		IsValid: true,
 	}
 }
 
+func (backend *SSHAppBackend) GetUpdatedParameters() []byte {
+	if backend.hostKeyVerifier == nil {
+		return nil
+	}
+
+	learnedKey := backend.hostKeyVerifier.LearnedKey()
+
+	if learnedKey == nil {
+		return nil
+	}
+
+	updatedConfig, err := sshutil.AddHostKeyToParameters(backend.rawConfig, learnedKey)
+
+	if err != nil {
+		log.Warnf("Failed to save host key into parameters: %s", err.Error())
+		return nil
+	}
+
+	return updatedConfig
+}
+
+func (backend *SSHAppBackend) GetCapabilities() uint32 {
+	return commonbackend.CapabilityTCP | commonbackend.CapabilityUDP | commonbackend.CapabilityConnectionListing
+}
+
 func (backend *SSHAppBackend) OnTCPConnectionOpened(proxyID, connectionID uint16) {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", backend.tcpProxies[proxyID].proxyInformation.SourceIP, backend.tcpProxies[proxyID].proxyInformation.SourcePort))
+	proxy, ok := backend.getTCPProxy(proxyID)
+
+	if !ok {
+		log.Warn("Could not find TCP proxy")
+		return
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(proxy.proxyInformation.SourceIP, strconv.Itoa(int(proxy.proxyInformation.SourcePort))))
 
 	if err != nil {
 		log.Warnf("failed to dial sock: %s", err.Error())
@@ -672,11 +570,11 @@ func (backend *SSHAppBackend) OnTCPConnectionOpened(proxyID, connectionID uint16
 		backend.currentSock.Write(disconnectionCommandMarshalled)
 	}()
 
-	backend.tcpProxies[proxyID].connections[connectionID] = conn
+	proxy.connections[connectionID] = conn
 }
 
 func (backend *SSHAppBackend) OnTCPConnectionClosed(proxyID, connectionID uint16) {
-	proxy, ok := backend.tcpProxies[proxyID]
+	proxy, ok := backend.getTCPProxy(proxyID)
 
 	if !ok {
 		log.Warn("Could not find TCP proxy")
@@ -693,7 +591,7 @@ func (backend *SSHAppBackend) OnTCPConnectionClosed(proxyID, connectionID uint16
 }
 
 func (backend *SSHAppBackend) HandleTCPMessage(message *datacommands.TCPProxyData, data []byte) {
-	proxy, ok := backend.tcpProxies[message.ProxyID]
+	proxy, ok := backend.getTCPProxy(message.ProxyID)
 
 	if !ok {
 		log.Warn("Could not find TCP proxy")
@@ -709,7 +607,7 @@ func (backend *SSHAppBackend) HandleTCPMessage(message *datacommands.TCPProxyDat
 }
 
 func (backend *SSHAppBackend) HandleUDPMessage(message *datacommands.UDPProxyData, data []byte) {
-	proxy, ok := backend.udpProxies[message.ProxyID]
+	proxy, ok := backend.getUDPProxy(message.ProxyID)
 
 	if !ok {
 		log.Warn("Could not find UDP proxy")
@@ -720,15 +618,232 @@ func (backend *SSHAppBackend) HandleUDPMessage(message *datacommands.UDPProxyDat
 	}
 }
 
+func (backend *SSHAppBackend) connect() error {
+	conn, err := sshutil.Dial(net.JoinHostPort(backend.config.IP, strconv.Itoa(int(backend.config.Port))), backend.config.Username, &backend.config.AuthConfig, backend.hostKeyVerifier.Callback)
+
+	if err != nil {
+		return err
+	}
+
+	log.Debug("SSHAppBackend has connected successfully.")
+	log.Debug("Getting CPU architecture...")
+
+	cpuArch, err := sshutil.GetRemoteArchitecture(conn)
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	binary, err := binFiles.ReadFile(fmt.Sprintf("remote-bin/rt-%s", cpuArch))
+
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("(embedded FS): %s", err.Error())
+	}
+
+	log.Debug("Checking if we need to copy the application...")
+
+	if err := sshutil.UploadBinary(conn, binary, "/tmp/sshappbackend.runtime"); err != nil {
+		conn.Close()
+		return err
+	}
+
+	log.Debug("Initializing Unix socket...")
+
+	socketPath := fmt.Sprintf("/tmp/sock-%d.sock", rand.Uint())
+	listener, err := conn.ListenUnix(socketPath)
+
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to listen on socket: %s", err.Error())
+	}
+
+	log.Debug("Starting process...")
+
+	session, err := conn.NewSession()
+
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create session: %s", err.Error())
+	}
+
+	backend.conn = conn
+	backend.listener = listener
+	backend.currentSock = nil
+
+	session.Stdout = WriteLogger{}
+	session.Stderr = WriteLogger{}
+
+	go func() {
+		for {
+			err := session.Run(fmt.Sprintf("HERMES_LOG_LEVEL=\"%s\" HERMES_API_SOCK=\"%s\" /tmp/sshappbackend.runtime", os.Getenv("HERMES_LOG_LEVEL"), socketPath))
+
+			if err != nil && !errors.Is(err, &ssh.ExitError{}) && !errors.Is(err, &ssh.ExitMissingError{}) {
+				log.Errorf("Critically failed during execution of remote code: %s", err.Error())
+				return
+			} else {
+				log.Warn("Remote code failed for an unknown reason. Restarting...")
+			}
+		}
+	}()
+
+	go backend.sockServerHandler(listener)
+
+	log.Debug("Started process. Waiting for Unix socket connection...")
+
+	connectionDeadline := time.Now().Add(30 * time.Second)
+
+	for backend.currentSock == nil {
+		if time.Now().After(connectionDeadline) {
+			conn.Close()
+			return fmt.Errorf("timed out waiting for the remote code to connect")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	go sshutil.SendKeepalives(conn, time.Duration(backend.config.KeepaliveInterval)*time.Second, int(backend.config.KeepaliveMaxMissed))
+
+	log.Debug("Detected connection. Sending initialization command...")
+
+	proxyStatusRaw, err := backend.SendNonCriticalMessage(&commonbackend.Start{
+		Arguments: []byte{},
+	})
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	proxyStatus, ok := proxyStatusRaw.(*commonbackend.BackendStatusResponse)
+
+	if !ok {
+		conn.Close()
+		return fmt.Errorf("recieved invalid response type: %T", proxyStatusRaw)
+	}
+
+	if proxyStatus.StatusCode == commonbackend.StatusFailure {
+		conn.Close()
+
+		if proxyStatus.Message == "" {
+			return fmt.Errorf("failed to initialize backend in remote code")
+		} else {
+			return fmt.Errorf("failed to initialize backend in remote code: %s", proxyStatus.Message)
+		}
+	}
+
+	return nil
+}
+
+func (backend *SSHAppBackend) backendDisconnectHandler() {
+	for {
+		err := backend.conn.Wait()
+
+		backend.statusLock.Lock()
+
+		if backend.isStopped {
+			backend.statusLock.Unlock()
+			return
+		}
+
+		backend.isReconnecting = true
+		backend.reconnectError = nil
+		backend.statusLock.Unlock()
+
+		if err != nil {
+			log.Warnf("Disconnected from the remote SSH server: %s", err.Error())
+		} else {
+			log.Warn("Disconnected from the remote SSH server")
+		}
+
+		// The remote code (and all of its proxies) went away with the connection, so clean up our side of things
+		oldProxies := []*commonbackend.AddProxy{}
+
+		backend.proxiesLock.Lock()
+
+		for _, proxy := range backend.tcpProxies {
+			for _, connection := range proxy.connections {
+				connection.Close()
+			}
+
+			oldProxies = append(oldProxies, proxy.proxyInformation)
+		}
+
+		for _, proxy := range backend.udpProxies {
+			proxy.portTranslation.StopAllPorts()
+			oldProxies = append(oldProxies, proxy.proxyInformation)
+		}
+
+		backend.tcpProxies = map[uint16]*TCPProxy{}
+		backend.udpProxies = map[uint16]*UDPProxy{}
+
+		backend.proxiesLock.Unlock()
+
+		for {
+			backend.statusLock.Lock()
+			delay := backend.backoff.Next()
+			backend.statusLock.Unlock()
+
+			log.Infof("Attempting to reconnect in %s...", delay.Round(time.Millisecond))
+
+			select {
+			case <-backend.stopChan:
+				return
+			case <-time.After(delay):
+			}
+
+			err := backend.connect()
+
+			if err == nil {
+				break
+			}
+
+			log.Errorf("Failed to reconnect to the server: %s", err.Error())
+
+			backend.statusLock.Lock()
+			backend.reconnectError = err
+			backend.statusLock.Unlock()
+		}
+
+		log.Info("SSHAppBackend has reconnected successfully. Attempting to set up proxies again...")
+
+		for _, proxy := range oldProxies {
+			ok, err := backend.StartProxy(proxy)
+
+			if err != nil {
+				log.Errorf("Failed to set up proxy: %s", err.Error())
+				continue
+			}
+
+			if !ok {
+				log.Errorf("Failed to set up proxy: OK status is false")
+				continue
+			}
+		}
+
+		backend.statusLock.Lock()
+		backend.isReconnecting = false
+		backend.reconnectError = nil
+		backend.backoff.Reset()
+		backend.statusLock.Unlock()
+
+		log.Info("SSHAppBackend has reinitialized and restored state successfully.")
+	}
+}
+
 func (backend *SSHAppBackend) SendNonCriticalMessage(iface interface{}) (interface{}, error) {
 	if backend.currentSock == nil {
 		return nil, fmt.Errorf("socket connection not initialized yet")
 	}
 
+	sockClosed := backend.sockClosed
+
 	bytes, err := datacommands.Marshal(iface)
 
 	if err != nil && err.Error() == "unsupported command type" {
-		bytes, err = commonbackend.Marshal(iface)
+		// Messages are sent one at a time (see globalNonCriticalMessageLock), so request IDs aren't used
+		bytes, err = commonbackend.Marshal(0, iface)
 
 		if err != nil {
 			return nil, err
@@ -744,7 +859,13 @@ func (backend *SSHAppBackend) SendNonCriticalMessage(iface interface{}) (interfa
 		return nil, fmt.Errorf("failed to write message: %s", err.Error())
 	}
 
-	reply, ok := <-backend.globalNonCriticalMessageChan
+	var reply interface{}
+	ok := false
+
+	select {
+	case reply, ok = <-backend.globalNonCriticalMessageChan:
+	case <-sockClosed:
+	}
 
 	if !ok {
 		backend.globalNonCriticalMessageLock.Unlock()
@@ -755,70 +876,80 @@ func (backend *SSHAppBackend) SendNonCriticalMessage(iface interface{}) (interfa
 	return reply, nil
 }
 
-func (backend *SSHAppBackend) sockServerHandler() {
+func (backend *SSHAppBackend) sockServerHandler(listener net.Listener) {
 	for {
-		conn, err := backend.listener.Accept()
+		conn, err := listener.Accept()
 
 		if err != nil {
 			log.Warnf("Failed to accept remote connection: %s", err.Error())
+			return
 		}
 
 		log.Debug("Successfully connected.")
+		backend.handleSockConnection(conn)
+	}
+}
 
-		backend.currentSock = conn
+// Handles messages from one connection to the remote code, until it goes away
+func (backend *SSHAppBackend) handleSockConnection(conn net.Conn) {
+	sockClosed := make(chan struct{})
+	defer close(sockClosed)
 
-		commandID := make([]byte, 1)
+	backend.sockClosed = sockClosed
+	backend.currentSock = conn
 
-		gaslighter := &gaslighter.Gaslighter{}
-		gaslighter.ProxiedReader = conn
+	commandID := make([]byte, 1)
 
-		dataBuffer := make([]byte, 65535)
+	gaslighter := &gaslighter.Gaslighter{}
+	gaslighter.ProxiedReader = conn
 
-		var commandRaw interface{}
+	dataBuffer := make([]byte, 65535)
 
-		for {
-			if _, err := conn.Read(commandID); err != nil {
-				log.Warnf("Failed to read command ID: %s", err.Error())
-				return
-			}
+	var commandRaw interface{}
+	var err error
 
-			gaslighter.Byte = commandID[0]
-			gaslighter.HasGaslit = false
+	for {
+		if _, err := conn.Read(commandID); err != nil {
+			log.Warnf("Failed to read command ID: %s", err.Error())
+			return
+		}
 
-			if gaslighter.Byte > 100 {
-				commandRaw, err = datacommands.Unmarshal(gaslighter)
-			} else {
-				commandRaw, err = commonbackend.Unmarshal(gaslighter)
-			}
+		gaslighter.Byte = commandID[0]
+		gaslighter.HasGaslit = false
 
-			if err != nil {
-				log.Warnf("Failed to parse command: %s", err.Error())
-			}
+		if gaslighter.Byte > 100 {
+			commandRaw, err = datacommands.Unmarshal(gaslighter)
+		} else {
+			_, commandRaw, err = commonbackend.Unmarshal(gaslighter)
+		}
 
-			switch command := commandRaw.(type) {
-			case *datacommands.TCPConnectionOpened:
-				backend.OnTCPConnectionOpened(command.ProxyID, command.ConnectionID)
-			case *datacommands.TCPConnectionClosed:
-				backend.OnTCPConnectionClosed(command.ProxyID, command.ConnectionID)
-			case *datacommands.TCPProxyData:
-				if _, err := io.ReadFull(conn, dataBuffer[:command.DataLength]); err != nil {
-					log.Warnf("Failed to read entire data buffer: %s", err.Error())
-					break
-				}
+		if err != nil {
+			log.Warnf("Failed to parse command: %s", err.Error())
+		}
 
-				backend.HandleTCPMessage(command, dataBuffer[:command.DataLength])
-			case *datacommands.UDPProxyData:
-				if _, err := io.ReadFull(conn, dataBuffer[:command.DataLength]); err != nil {
-					log.Warnf("Failed to read entire data buffer: %s", err.Error())
-					break
-				}
+		switch command := commandRaw.(type) {
+		case *datacommands.TCPConnectionOpened:
+			backend.OnTCPConnectionOpened(command.ProxyID, command.ConnectionID)
+		case *datacommands.TCPConnectionClosed:
+			backend.OnTCPConnectionClosed(command.ProxyID, command.ConnectionID)
+		case *datacommands.TCPProxyData:
+			if _, err := io.ReadFull(conn, dataBuffer[:command.DataLength]); err != nil {
+				log.Warnf("Failed to read entire data buffer: %s", err.Error())
+				break
+			}
+
+			backend.HandleTCPMessage(command, dataBuffer[:command.DataLength])
+		case *datacommands.UDPProxyData:
+			if _, err := io.ReadFull(conn, dataBuffer[:command.DataLength]); err != nil {
+				log.Warnf("Failed to read entire data buffer: %s", err.Error())
+				break
+			}
 
-				backend.HandleUDPMessage(command, dataBuffer[:command.DataLength])
+			backend.HandleUDPMessage(command, dataBuffer[:command.DataLength])
+		default:
+			select {
+			case backend.globalNonCriticalMessageChan <- command:
 			default:
-				select {
-				case backend.globalNonCriticalMessageChan <- command:
-				default:
-				}
 			}
 		}
 	}
@@ -57,7 +57,8 @@ func (helper *BackendApplicationHelper) Start() error {
 		if gaslighter.Byte > 100 {
 			commandRaw, err = datacommands.Unmarshal(gaslighter)
 		} else {
-			commandRaw, err = commonbackend.Unmarshal(gaslighter)
+			// Only one message is in flight at a time over this link, so request IDs aren't used
+			_, commandRaw, err = commonbackend.Unmarshal(gaslighter)
 		}
 
 		if err != nil {
@@ -167,7 +168,7 @@ func (helper *BackendApplicationHelper) Start() error {
 				Message:    message,
 			}
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+			responseMarshalled, err := commonbackend.Marshal(0, response)
 
 			if err != nil {
 				log.Error("failed to marshal response: %s", err.Error())
@@ -196,7 +197,7 @@ func (helper *BackendApplicationHelper) Start() error {
 				Message:    message,
 			}
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+			responseMarshalled, err := commonbackend.Marshal(0, response)
 
 			if err != nil {
 				log.Error("failed to marshal response: %s", err.Error())
@@ -225,7 +226,7 @@ func (helper *BackendApplicationHelper) Start() error {
 				Message:    message,
 			}
 
-			responseMarshalled, err := commonbackend.Marshal(response)
+			responseMarshalled, err := commonbackend.Marshal(0, response)
 
 			if err != nil {
 				log.Error("failed to marshal response: %s", err.Error())
@@ -262,7 +263,7 @@ func (helper *BackendApplicationHelper) Start() error {
 			resp := helper.Backend.CheckParametersForConnections(command)
 			resp.InResponseTo = "checkClientParameters"
 
-			byteData, err := commonbackend.Marshal(resp)
+			byteData, err := commonbackend.Marshal(0, resp)
 
 			if err != nil {
 				return err
@@ -275,7 +276,7 @@ func (helper *BackendApplicationHelper) Start() error {
 			resp := helper.Backend.CheckParametersForBackend(command.Arguments)
 			resp.InResponseTo = "checkServerParameters"
 
-			byteData, err := commonbackend.Marshal(resp)
+			byteData, err := commonbackend.Marshal(0, resp)
 
 			if err != nil {
 				return err
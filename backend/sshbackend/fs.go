@@ -0,0 +1,8 @@
+package main
+
+import (
+	"embed"
+)
+
+//go:embed remote-bin
+var binFiles embed.FS
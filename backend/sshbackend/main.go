@@ -13,6 +13,7 @@ import (
 
 	"git.terah.dev/imterah/hermes/backend/backendutil"
 	"git.terah.dev/imterah/hermes/backend/commonbackend"
+	"git.terah.dev/imterah/hermes/backend/sshutil"
 	"github.com/charmbracelet/log"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/ssh"
@@ -24,22 +25,47 @@ type SSHListener struct {
 	DestPort   uint16
 	Protocol   string // Will be either 'tcp' or 'udp'
 	Listeners  []net.Listener
+	UDPRelays  []*UDPRelay
 }
 
 type SSHBackend struct {
-	config         *SSHBackendData
-	conn           *ssh.Client
-	clients        []*commonbackend.ProxyClientConnection
-	proxies        []*SSHListener
-	arrayPropMutex sync.Mutex
+	config          *SSHBackendData
+	rawConfig       []byte
+	hostKeyVerifier *sshutil.HostKeyVerifier
+	conn            *ssh.Client
+	clients         []*commonbackend.ProxyClientConnection
+	proxies         []*SSHListener
+	arrayPropMutex  sync.Mutex
+
+	udpHelperLock sync.Mutex
+	udpHelperConn *ssh.Client
+
+	statusLock     sync.Mutex
+	isStopped      bool
+	isReconnecting bool
+	reconnectError error
+	backoff        sshutil.Backoff
+	stopChan       chan struct{}
 }
 
 type SSHBackendData struct {
 	IP          string   `json:"ip" validate:"required"`
 	Port        uint16   `json:"port" validate:"required"`
 	Username    string   `json:"username" validate:"required"`
-	PrivateKey  string   `json:"privateKey" validate:"required"`
 	ListenOnIPs []string `json:"listenOnIPs"`
+
+	sshutil.AuthConfig
+
+	HostKeys        []string `json:"hostKeys"`
+	KnownHosts      string   `json:"knownHosts"`
+	TrustOnFirstUse bool     `json:"trustOnFirstUse"`
+
+	// How long (in seconds) a UDP client can be idle before we forget about it
+	UDPIdleTimeout uint `json:"udpIdleTimeout"`
+
+	// How often (in seconds) we check if the server is still alive, and how many missed checks it takes to reconnect
+	KeepaliveInterval  uint `json:"keepaliveInterval"`
+	KeepaliveMaxMissed uint `json:"keepaliveMaxMissed"`
 }
 
 func (backend *SSHBackend) StartBackend(bytes []byte) (bool, error) {
@@ -55,34 +81,34 @@ func (backend *SSHBackend) StartBackend(bytes []byte) (bool, error) {
 	}
 
 	backend.config = &backendData
+	backend.rawConfig = bytes
 
 	if len(backend.config.ListenOnIPs) == 0 {
 		backend.config.ListenOnIPs = []string{"0.0.0.0"}
 	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(backendData.PrivateKey))
+	if backend.config.UDPIdleTimeout == 0 {
+		backend.config.UDPIdleTimeout = 3 * 60
+	}
+
+	hostKeyVerifier, err := sshutil.NewHostKeyVerifier(backendData.HostKeys, backendData.KnownHosts, backendData.TrustOnFirstUse)
 
 	if err != nil {
 		return false, err
 	}
 
-	auth := ssh.PublicKeys(signer)
-
-	config := &ssh.ClientConfig{
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		User:            backendData.Username,
-		Auth: []ssh.AuthMethod{
-			auth,
-		},
-	}
+	backend.hostKeyVerifier = hostKeyVerifier
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", backendData.IP, backendData.Port), config)
+	conn, err := backend.connect()
 
 	if err != nil {
 		return false, err
 	}
 
+	backend.statusLock.Lock()
 	backend.conn = conn
+	backend.stopChan = make(chan struct{})
+	backend.statusLock.Unlock()
 
 	log.Info("SSHBackend has initialized successfully.")
 	go backend.backendDisconnectHandler()
@@ -91,7 +117,23 @@ func (backend *SSHBackend) StartBackend(bytes []byte) (bool, error) {
 }
 
 func (backend *SSHBackend) StopBackend() (bool, error) {
-	err := backend.conn.Close()
+	backend.statusLock.Lock()
+
+	if backend.isStopped {
+		backend.statusLock.Unlock()
+		return true, nil
+	}
+
+	backend.isStopped = true
+	close(backend.stopChan)
+	conn := backend.conn
+	backend.statusLock.Unlock()
+
+	if conn == nil {
+		return true, nil
+	}
+
+	err := conn.Close()
 
 	if err != nil {
 		return false, err
@@ -101,10 +143,33 @@ func (backend *SSHBackend) StopBackend() (bool, error) {
 }
 
 func (backend *SSHBackend) GetBackendStatus() (bool, error) {
-	return backend.conn != nil, nil
+	backend.statusLock.Lock()
+	defer backend.statusLock.Unlock()
+
+	if backend.isReconnecting {
+		if backend.reconnectError != nil {
+			return false, fmt.Errorf("reconnecting to the SSH server (attempt %d): %s", backend.backoff.Attempts, backend.reconnectError.Error())
+		}
+
+		return false, fmt.Errorf("reconnecting to the SSH server")
+	}
+
+	return backend.conn != nil && !backend.isStopped, nil
+}
+
+// Gets the current SSH connection, which gets replaced every time we reconnect
+func (backend *SSHBackend) getConnection() *ssh.Client {
+	backend.statusLock.Lock()
+	defer backend.statusLock.Unlock()
+
+	return backend.conn
 }
 
 func (backend *SSHBackend) StartProxy(command *commonbackend.AddProxy) (bool, error) {
+	if command.Protocol == "udp" {
+		return backend.startUDPProxy(command)
+	}
+
 	listenerObject := &SSHListener{
 		SourceIP:   command.SourceIP,
 		SourcePort: command.SourcePort,
@@ -119,7 +184,7 @@ func (backend *SSHBackend) StartProxy(command *commonbackend.AddProxy) (bool, er
 			Port: int(command.DestPort),
 		}
 
-		listener, err := backend.conn.ListenTCP(&ip)
+		listener, err := backend.getConnection().ListenTCP(&ip)
 
 		if err != nil {
 			// Incase we error out, we clean up all the other listeners
@@ -150,7 +215,7 @@ func (backend *SSHBackend) StartProxy(command *commonbackend.AddProxy) (bool, er
 					continue
 				}
 
-				sourceConn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", command.SourceIP, command.SourcePort))
+				sourceConn, err := net.Dial("tcp", net.JoinHostPort(command.SourceIP, strconv.Itoa(int(command.SourcePort))))
 
 				if err != nil {
 					log.Warnf("failed to dial source connection: %s", err.Error())
@@ -274,29 +339,40 @@ func (backend *SSHBackend) StartProxy(command *commonbackend.AddProxy) (bool, er
 }
 
 func (backend *SSHBackend) StopProxy(command *commonbackend.RemoveProxy) (bool, error) {
-	defer backend.arrayPropMutex.Unlock()
+	var foundProxy *SSHListener
+
 	backend.arrayPropMutex.Lock()
 
 	for proxyIndex, proxy := range backend.proxies {
 		if command.SourceIP == proxy.SourceIP && command.SourcePort == proxy.SourcePort && command.DestPort == proxy.DestPort && command.Protocol == proxy.Protocol {
-			for _, listener := range proxy.Listeners {
-				err := listener.Close()
-
-				if err != nil {
-					log.Warnf("failed to stop listener in StopProxy: %s", err.Error())
-				}
-			}
+			foundProxy = proxy
 
 			// Splice out the proxy instance by proxyIndex
-
-			// TODO: change approach. It works but it's a bit wonky imho
-			// I asked AI to do this as it's a relatively simple task and I forgot how to do this effectively
 			backend.proxies = append(backend.proxies[:proxyIndex], backend.proxies[proxyIndex+1:]...)
-			return true, nil
+			break
+		}
+	}
+
+	backend.arrayPropMutex.Unlock()
+
+	if foundProxy == nil {
+		return false, fmt.Errorf("could not find the proxy")
+	}
+
+	// Closing UDP relays removes their clients, which needs arrayPropMutex, so this has to happen after unlocking it
+	for _, listener := range foundProxy.Listeners {
+		err := listener.Close()
+
+		if err != nil {
+			log.Warnf("failed to stop listener in StopProxy: %s", err.Error())
 		}
 	}
 
-	return false, fmt.Errorf("could not find the proxy")
+	for _, relay := range foundProxy.UDPRelays {
+		relay.Close()
+	}
+
+	return true, nil
 }
 
 func (backend *SSHBackend) GetAllClientConnections() []*commonbackend.ProxyClientConnection {
@@ -307,10 +383,10 @@ func (backend *SSHBackend) GetAllClientConnections() []*commonbackend.ProxyClien
 }
 
 func (backend *SSHBackend) CheckParametersForConnections(clientParameters *commonbackend.CheckClientParameters) *commonbackend.CheckParametersResponse {
-	if clientParameters.Protocol != "tcp" {
+	if clientParameters.Protocol != "tcp" && clientParameters.Protocol != "udp" {
 		return &commonbackend.CheckParametersResponse{
 			IsValid: false,
-			Message: "Only TCP is supported for SSH",
+			Message: "Only TCP and UDP are supported for SSH",
 		}
 	}
 
@@ -336,58 +412,144 @@ func (backend *SSHBackend) CheckParametersForBackend(arguments []byte) *commonba
 		}
 	}
 
+	if err := sshutil.CheckAuthConfig(&backendData.AuthConfig); err != nil {
+		return &commonbackend.CheckParametersResponse{
+			IsValid: false,
+			Message: fmt.Sprintf("failed to check authentication parameters: %s", err.Error()),
+		}
+	}
+
+	hostKeyVerifier, err := sshutil.NewHostKeyVerifier(backendData.HostKeys, backendData.KnownHosts, backendData.TrustOnFirstUse)
+
+	if err != nil {
+		return &commonbackend.CheckParametersResponse{
+			IsValid: false,
+			Message: fmt.Sprintf("failed to parse host keys: %s", err.Error()),
+		}
+	}
+
+	if err := sshutil.CheckHostKey(net.JoinHostPort(backendData.IP, strconv.Itoa(int(backendData.Port))), hostKeyVerifier); err != nil {
+		return &commonbackend.CheckParametersResponse{
+			IsValid: false,
+			Message: fmt.Sprintf("failed to verify host key: %s", err.Error()),
+		}
+	}
+
 	return &commonbackend.CheckParametersResponse{
 		IsValid: true,
 	}
 }
 
-func (backend *SSHBackend) backendDisconnectHandler() {
-	for {
-		if backend.conn != nil {
-			err := backend.conn.Wait()
+func (backend *SSHBackend) GetUpdatedParameters() []byte {
+	if backend.hostKeyVerifier == nil {
+		return nil
+	}
 
-			if err == nil || err.Error() != "EOF" {
-				continue
-			}
-		}
+	learnedKey := backend.hostKeyVerifier.LearnedKey()
 
-		log.Info("Disconnected from the remote SSH server. Attempting to reconnect in 5 seconds...")
+	if learnedKey == nil {
+		return nil
+	}
 
-		time.Sleep(5 * time.Second)
+	updatedConfig, err := sshutil.AddHostKeyToParameters(backend.rawConfig, learnedKey)
 
-		// Make the connection nil to accurately report our status incase GetBackendStatus is called
-		backend.conn = nil
+	if err != nil {
+		log.Warnf("Failed to save host key into parameters: %s", err.Error())
+		return nil
+	}
 
-		// Use the last half of the code from the main initialization
-		signer, err := ssh.ParsePrivateKey([]byte(backend.config.PrivateKey))
+	return updatedConfig
+}
 
-		if err != nil {
-			log.Errorf("Failed to parse private key: %s", err.Error())
+func (backend *SSHBackend) GetCapabilities() uint32 {
+	return commonbackend.CapabilityTCP | commonbackend.CapabilityUDP | commonbackend.CapabilityConnectionListing
+}
+
+func (backend *SSHBackend) connect() (*ssh.Client, error) {
+	conn, err := sshutil.Dial(net.JoinHostPort(backend.config.IP, strconv.Itoa(int(backend.config.Port))), backend.config.Username, &backend.config.AuthConfig, backend.hostKeyVerifier.Callback)
+
+	if err != nil {
+		return nil, err
+	}
+
+	go sshutil.SendKeepalives(conn, time.Duration(backend.config.KeepaliveInterval)*time.Second, int(backend.config.KeepaliveMaxMissed))
+
+	return conn, nil
+}
+
+func (backend *SSHBackend) backendDisconnectHandler() {
+	for {
+		err := backend.getConnection().Wait()
+
+		backend.statusLock.Lock()
+
+		if backend.isStopped {
+			backend.statusLock.Unlock()
 			return
 		}
 
-		auth := ssh.PublicKeys(signer)
+		backend.isReconnecting = true
+		backend.reconnectError = nil
+		backend.statusLock.Unlock()
 
-		config := &ssh.ClientConfig{
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			User:            backend.config.Username,
-			Auth: []ssh.AuthMethod{
-				auth,
-			},
+		if err != nil {
+			log.Warnf("Disconnected from the remote SSH server: %s", err.Error())
+		} else {
+			log.Warn("Disconnected from the remote SSH server")
 		}
 
-		conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", backend.config.IP, backend.config.Port), config)
+		for {
+			backend.statusLock.Lock()
+			delay := backend.backoff.Next()
+			backend.statusLock.Unlock()
+
+			log.Infof("Attempting to reconnect in %s...", delay.Round(time.Millisecond))
+
+			select {
+			case <-backend.stopChan:
+				return
+			case <-time.After(delay):
+			}
+
+			conn, err := backend.connect()
+
+			if err == nil {
+				backend.statusLock.Lock()
+
+				// StopBackend could have been called while we were connecting, and it only closes the old connection
+				if backend.isStopped {
+					backend.statusLock.Unlock()
+					conn.Close()
+
+					return
+				}
+
+				backend.conn = conn
+				backend.statusLock.Unlock()
+
+				break
+			}
 
-		if err != nil {
 			log.Errorf("Failed to connect to the server: %s", err.Error())
-			return
-		}
 
-		backend.conn = conn
+			backend.statusLock.Lock()
+			backend.reconnectError = err
+			backend.statusLock.Unlock()
+		}
 
 		log.Info("SSHBackend has reconnected successfully. Attempting to set up proxies again...")
 
-		for _, proxy := range backend.proxies {
+		// The old listeners died with the old connection, so we start over with a clean slate
+		backend.arrayPropMutex.Lock()
+		oldProxies := backend.proxies
+		backend.proxies = []*SSHListener{}
+		backend.arrayPropMutex.Unlock()
+
+		for _, proxy := range oldProxies {
+			for _, relay := range proxy.UDPRelays {
+				relay.Close()
+			}
+
 			ok, err := backend.StartProxy(&commonbackend.AddProxy{
 				SourceIP:   proxy.SourceIP,
 				SourcePort: proxy.SourcePort,
@@ -395,17 +557,31 @@ func (backend *SSHBackend) backendDisconnectHandler() {
 				Protocol:   proxy.Protocol,
 			})
 
+			if err == nil && !ok {
+				err = fmt.Errorf("OK status is false")
+			}
+
 			if err != nil {
 				log.Errorf("Failed to set up proxy: %s", err.Error())
-				continue
-			}
 
-			if !ok {
-				log.Errorf("Failed to set up proxy: OK status is false")
-				continue
+				// Keep track of it anyways, so it can still be stopped, and so that we can try again on the next reconnect
+				backend.arrayPropMutex.Lock()
+				backend.proxies = append(backend.proxies, &SSHListener{
+					SourceIP:   proxy.SourceIP,
+					SourcePort: proxy.SourcePort,
+					DestPort:   proxy.DestPort,
+					Protocol:   proxy.Protocol,
+				})
+				backend.arrayPropMutex.Unlock()
 			}
 		}
 
+		backend.statusLock.Lock()
+		backend.isReconnecting = false
+		backend.reconnectError = nil
+		backend.backoff.Reset()
+		backend.statusLock.Unlock()
+
 		log.Info("SSHBackend has reinitialized and restored state successfully.")
 	}
 }
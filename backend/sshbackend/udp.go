@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.terah.dev/imterah/hermes/backend/commonbackend"
+	"git.terah.dev/imterah/hermes/backend/sshappbackend/datacommands"
+	"git.terah.dev/imterah/hermes/backend/sshutil"
+	"github.com/charmbracelet/log"
+	"golang.org/x/crypto/ssh"
+)
+
+const udpHelperRemotePath = "/tmp/sshbackend.udphelper"
+
+// SSH can only forward TCP, so for UDP, we run a small helper on the remote server (see udphelper/) which listens
+// for datagrams and relays them to us over the stdin/stdout of an SSH session.
+type UDPRelay struct {
+	backend *SSHBackend
+	command *commonbackend.AddProxy
+	session *ssh.Session
+
+	stdin     io.WriteCloser
+	stdinLock sync.Mutex
+
+	clientsLock sync.Mutex
+	clients     map[string]*UDPClient
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// A UDP "connection" to the source, which is one per client IP and port.
+type UDPClient struct {
+	conn           *net.UDPConn
+	advertisedConn *commonbackend.ProxyClientConnection
+	lastActivity   time.Time
+}
+
+type udpHelperLogger struct{}
+
+func (writer udpHelperLogger) Write(p []byte) (n int, err error) {
+	logSplit := strings.Split(string(p), "\n")
+
+	for _, line := range logSplit {
+		if line == "" {
+			continue
+		}
+
+		log.Infof("UDP helper: %s", line)
+	}
+
+	return len(p), err
+}
+
+func (backend *SSHBackend) deployUDPHelper() error {
+	backend.udpHelperLock.Lock()
+	defer backend.udpHelperLock.Unlock()
+
+	conn := backend.getConnection()
+
+	// We only have to copy the helper once per connection
+	if backend.udpHelperConn == conn {
+		return nil
+	}
+
+	arch, err := sshutil.GetRemoteArchitecture(conn)
+
+	if err != nil {
+		return err
+	}
+
+	binary, err := binFiles.ReadFile(fmt.Sprintf("remote-bin/udphelper-%s", arch))
+
+	if err != nil {
+		return fmt.Errorf("(embedded FS): %s", err.Error())
+	}
+
+	if err := sshutil.UploadBinary(conn, binary, udpHelperRemotePath); err != nil {
+		return err
+	}
+
+	backend.udpHelperConn = conn
+	return nil
+}
+
+func (backend *SSHBackend) startUDPProxy(command *commonbackend.AddProxy) (bool, error) {
+	if err := backend.deployUDPHelper(); err != nil {
+		return false, fmt.Errorf("failed to deploy UDP helper: %s", err.Error())
+	}
+
+	listenerObject := &SSHListener{
+		SourceIP:   command.SourceIP,
+		SourcePort: command.SourcePort,
+		DestPort:   command.DestPort,
+		Protocol:   command.Protocol,
+		Listeners:  []net.Listener{},
+		UDPRelays:  []*UDPRelay{},
+	}
+
+	for _, ipListener := range backend.config.ListenOnIPs {
+		relay, err := backend.startUDPRelay(command, ipListener)
+
+		if err != nil {
+			// Incase we error out, we clean up all the other relays
+			for _, relay := range listenerObject.UDPRelays {
+				relay.Close()
+			}
+
+			return false, err
+		}
+
+		listenerObject.UDPRelays = append(listenerObject.UDPRelays, relay)
+	}
+
+	backend.arrayPropMutex.Lock()
+	backend.proxies = append(backend.proxies, listenerObject)
+	backend.arrayPropMutex.Unlock()
+
+	return true, nil
+}
+
+func (backend *SSHBackend) startUDPRelay(command *commonbackend.AddProxy, listenIP string) (*UDPRelay, error) {
+	session, err := backend.getConnection().NewSession()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %s", err.Error())
+	}
+
+	stdin, err := session.StdinPipe()
+
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdin of session: %s", err.Error())
+	}
+
+	stdout, err := session.StdoutPipe()
+
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdout of session: %s", err.Error())
+	}
+
+	session.Stderr = udpHelperLogger{}
+
+	listenAddress := net.JoinHostPort(listenIP, strconv.Itoa(int(command.DestPort)))
+	err = session.Start(fmt.Sprintf("HERMES_LOG_LEVEL=\"%s\" %s %s", os.Getenv("HERMES_LOG_LEVEL"), udpHelperRemotePath, listenAddress))
+
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start UDP helper: %s", err.Error())
+	}
+
+	exitNotification := make(chan error, 1)
+
+	go func() {
+		exitNotification <- session.Wait()
+	}()
+
+	// If the helper can't listen on the port, it'll exit right away, so give it a moment to do so
+	select {
+	case err := <-exitNotification:
+		session.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("UDP helper exited while starting up: %s", err.Error())
+		}
+
+		return nil, fmt.Errorf("UDP helper exited while starting up")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	relay := &UDPRelay{
+		backend: backend,
+		command: command,
+		session: session,
+		stdin:   stdin,
+		clients: map[string]*UDPClient{},
+		closed:  make(chan struct{}),
+	}
+
+	go func() {
+		err := <-exitNotification
+
+		select {
+		case <-relay.closed:
+		default:
+			if err != nil {
+				log.Warnf("UDP helper for %s exited: %s", listenAddress, err.Error())
+			} else {
+				log.Warnf("UDP helper for %s exited", listenAddress)
+			}
+		}
+
+		relay.Close()
+	}()
+
+	go relay.readFromHelper(stdout)
+	go relay.cleanupIdleClients()
+
+	return relay, nil
+}
+
+func (relay *UDPRelay) readFromHelper(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	dataBuf := make([]byte, 65535)
+
+	for {
+		commandRaw, err := datacommands.Unmarshal(reader)
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Warnf("failed to parse command from UDP helper: %s", err.Error())
+			}
+
+			relay.Close()
+			return
+		}
+
+		command, ok := commandRaw.(*datacommands.UDPProxyData)
+
+		if !ok {
+			log.Warnf("unsupported command recieved from UDP helper: %T", commandRaw)
+			continue
+		}
+
+		if _, err := io.ReadFull(reader, dataBuf[:command.DataLength]); err != nil {
+			log.Warnf("failed to read entire data buffer: %s", err.Error())
+
+			relay.Close()
+			return
+		}
+
+		client, err := relay.getClient(command.ClientIP, command.ClientPort)
+
+		if err != nil {
+			log.Warnf("failed to dial source connection: %s", err.Error())
+			continue
+		}
+
+		if _, err := client.conn.Write(dataBuf[:command.DataLength]); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Warnf("failed to write to source connection: %s", err.Error())
+		}
+	}
+}
+
+func (relay *UDPRelay) getClient(clientIP string, clientPort uint16) (*UDPClient, error) {
+	clientKey := net.JoinHostPort(clientIP, strconv.Itoa(int(clientPort)))
+
+	relay.clientsLock.Lock()
+	defer relay.clientsLock.Unlock()
+
+	client, ok := relay.clients[clientKey]
+
+	if ok {
+		client.lastActivity = time.Now()
+		return client, nil
+	}
+
+	// Close removes every client it finds under this lock, so anything added after it ran would never get cleaned up
+	select {
+	case <-relay.closed:
+		return nil, fmt.Errorf("relay is closed")
+	default:
+	}
+
+	sourceAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(relay.command.SourceIP, strconv.Itoa(int(relay.command.SourcePort))))
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, sourceAddr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client = &UDPClient{
+		conn: conn,
+		advertisedConn: &commonbackend.ProxyClientConnection{
+			SourceIP:   relay.command.SourceIP,
+			SourcePort: relay.command.SourcePort,
+			DestPort:   relay.command.DestPort,
+			ClientIP:   clientIP,
+			ClientPort: clientPort,
+		},
+		lastActivity: time.Now(),
+	}
+
+	relay.clients[clientKey] = client
+
+	relay.backend.arrayPropMutex.Lock()
+	relay.backend.clients = append(relay.backend.clients, client.advertisedConn)
+	relay.backend.arrayPropMutex.Unlock()
+
+	go func() {
+		dataBuf := make([]byte, 65535)
+
+		udpProxyData := &datacommands.UDPProxyData{
+			ClientIP:   clientIP,
+			ClientPort: clientPort,
+		}
+
+		for {
+			len, err := conn.Read(dataBuf)
+
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Warnf("failed to read from source connection: %s", err.Error())
+				}
+
+				relay.removeClient(clientKey)
+				return
+			}
+
+			relay.clientsLock.Lock()
+			client.lastActivity = time.Now()
+			relay.clientsLock.Unlock()
+
+			udpProxyData.DataLength = uint16(len)
+			marshalledMessageCommand, err := datacommands.Marshal(udpProxyData)
+
+			if err != nil {
+				log.Warnf("failed to marshal message data: %s", err.Error())
+				continue
+			}
+
+			relay.stdinLock.Lock()
+			_, err = relay.stdin.Write(append(marshalledMessageCommand, dataBuf[:len]...))
+			relay.stdinLock.Unlock()
+
+			if err != nil {
+				log.Warnf("failed to send message data to UDP helper: %s", err.Error())
+				relay.removeClient(clientKey)
+
+				return
+			}
+		}
+	}()
+
+	return client, nil
+}
+
+func (relay *UDPRelay) removeClient(clientKey string) {
+	relay.clientsLock.Lock()
+	client, ok := relay.clients[clientKey]
+
+	if !ok {
+		relay.clientsLock.Unlock()
+		return
+	}
+
+	delete(relay.clients, clientKey)
+	relay.clientsLock.Unlock()
+
+	client.conn.Close()
+
+	relay.backend.arrayPropMutex.Lock()
+	defer relay.backend.arrayPropMutex.Unlock()
+
+	for clientIndex, clientInstance := range relay.backend.clients {
+		if clientInstance == client.advertisedConn {
+			relay.backend.clients = append(relay.backend.clients[:clientIndex], relay.backend.clients[clientIndex+1:]...)
+			return
+		}
+	}
+
+	log.Warn("failed to delete client from clients metadata: couldn't find client in the array")
+}
+
+// UDP has no concept of a connection closing, so we consider a client gone once it has been idle for too long.
+func (relay *UDPRelay) cleanupIdleClients() {
+	idleTimeout := time.Duration(relay.backend.config.UDPIdleTimeout) * time.Second
+	ticker := time.NewTicker(idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-relay.closed:
+			return
+		case <-ticker.C:
+		}
+
+		idleClients := []string{}
+
+		relay.clientsLock.Lock()
+
+		for clientKey, client := range relay.clients {
+			if time.Since(client.lastActivity) > idleTimeout {
+				idleClients = append(idleClients, clientKey)
+			}
+		}
+
+		relay.clientsLock.Unlock()
+
+		for _, clientKey := range idleClients {
+			log.Debugf("Removing idle UDP client %s", clientKey)
+			relay.removeClient(clientKey)
+		}
+	}
+}
+
+func (relay *UDPRelay) Close() {
+	relay.closeOnce.Do(func() {
+		close(relay.closed)
+
+		if err := relay.session.Close(); err != nil && !errors.Is(err, io.EOF) {
+			log.Warnf("failed to close UDP helper session: %s", err.Error())
+		}
+
+		relay.clientsLock.Lock()
+		clientKeys := make([]string, 0, len(relay.clients))
+
+		for clientKey := range relay.clients {
+			clientKeys = append(clientKeys, clientKey)
+		}
+
+		relay.clientsLock.Unlock()
+
+		for _, clientKey := range clientKeys {
+			relay.removeClient(clientKey)
+		}
+	})
+}
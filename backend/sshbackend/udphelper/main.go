@@ -0,0 +1,131 @@
+// udphelper is uploaded to the remote server by the SSH backend to forward UDP traffic, as SSH only supports
+// forwarding TCP. It listens on the UDP address given as the first argument, and relays every datagram over
+// stdin/stdout using the same framing as the sshappbackend (a UDPProxyData header, followed by the data itself).
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"git.terah.dev/imterah/hermes/backend/sshappbackend/datacommands"
+	"github.com/charmbracelet/log"
+)
+
+func main() {
+	// stdout is used for data, so all of our logging has to go to stderr
+	log.SetOutput(os.Stderr)
+
+	logLevel := os.Getenv("HERMES_LOG_LEVEL")
+
+	if logLevel != "" {
+		switch logLevel {
+		case "debug":
+			log.SetLevel(log.DebugLevel)
+
+		case "info":
+			log.SetLevel(log.InfoLevel)
+
+		case "warn":
+			log.SetLevel(log.WarnLevel)
+
+		case "error":
+			log.SetLevel(log.ErrorLevel)
+
+		case "fatal":
+			log.SetLevel(log.FatalLevel)
+		}
+	}
+
+	if len(os.Args) < 2 {
+		log.Fatal("listening address not specified")
+	}
+
+	listenAddr, err := net.ResolveUDPAddr("udp", os.Args[1])
+
+	if err != nil {
+		log.Fatalf("failed to resolve listening address: %s", err.Error())
+	}
+
+	server, err := net.ListenUDP("udp", listenAddr)
+
+	if err != nil {
+		log.Fatalf("failed to open server: %s", err.Error())
+	}
+
+	defer server.Close()
+
+	var stdoutLock sync.Mutex
+
+	go func() {
+		dataBuf := make([]byte, 65535)
+		udpProxyData := &datacommands.UDPProxyData{}
+
+		for {
+			len, addr, err := server.ReadFromUDP(dataBuf)
+
+			if err != nil {
+				log.Warnf("failed to read from UDP socket: %s", err.Error())
+				return
+			}
+
+			udpProxyData.ClientIP = addr.IP.String()
+			udpProxyData.ClientPort = uint16(addr.Port)
+			udpProxyData.DataLength = uint16(len)
+
+			marshalledMessageCommand, err := datacommands.Marshal(udpProxyData)
+
+			if err != nil {
+				log.Warnf("failed to marshal message data: %s", err.Error())
+				continue
+			}
+
+			stdoutLock.Lock()
+			_, err = os.Stdout.Write(append(marshalledMessageCommand, dataBuf[:len]...))
+			stdoutLock.Unlock()
+
+			if err != nil {
+				log.Fatalf("failed to send message data: %s", err.Error())
+			}
+		}
+	}()
+
+	stdin := bufio.NewReader(os.Stdin)
+	dataBuf := make([]byte, 65535)
+
+	for {
+		commandRaw, err := datacommands.Unmarshal(stdin)
+
+		if err != nil {
+			// The SSH session has been closed, so we're done here
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return
+			}
+
+			log.Fatalf("failed to parse command: %s", err.Error())
+		}
+
+		command, ok := commandRaw.(*datacommands.UDPProxyData)
+
+		if !ok {
+			log.Warnf("unsupported command recieved: %T", commandRaw)
+			continue
+		}
+
+		if _, err := io.ReadFull(stdin, dataBuf[:command.DataLength]); err != nil {
+			log.Fatalf("failed to read entire data buffer: %s", err.Error())
+		}
+
+		_, err = server.WriteToUDP(dataBuf[:command.DataLength], &net.UDPAddr{
+			IP:   net.ParseIP(command.ClientIP),
+			Port: int(command.ClientPort),
+		})
+
+		if err != nil {
+			log.Warnf("failed to write to UDP socket: %s", err.Error())
+		}
+	}
+}
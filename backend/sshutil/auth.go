@@ -0,0 +1,202 @@
+package sshutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// AuthConfig holds the authentication parameters shared by all SSH backends. It is meant to be embedded into the
+// backend parameters, so the fields show up at the top level of the JSON.
+type AuthConfig struct {
+	PrivateKey  string `json:"privateKey" validate:"required_without_all=Password AgentSocket"`
+	Passphrase  string `json:"passphrase" validate:"excluded_without=PrivateKey"`
+	Certificate string `json:"certificate" validate:"excluded_without=PrivateKey"` // OpenSSH certificate for the private key
+	Password    string `json:"password"`                                           // Used for both password and keyboard-interactive auth
+	AgentSocket string `json:"agentSocket"`                                        // Path to the Unix socket of an SSH agent
+}
+
+// SSH agents give access to every key in them, so agent sockets can only be used if whoever runs Hermes allows them.
+// This is a list of sockets, or directories that they can be in, separated by commas.
+const agentSocketsEnvironmentVariable = "HERMES_SSH_AGENT_SOCKETS"
+
+// Doesn't say why the socket can't be used, so that it can't be used to find out which files exist
+var errAgentSocketNotAllowed = fmt.Errorf("SSH agent socket is not allowed (see %s)", agentSocketsEnvironmentVariable)
+
+func isAgentSocketAllowed(socketPath string) bool {
+	for _, allowedPath := range strings.Split(os.Getenv(agentSocketsEnvironmentVariable), ",") {
+		allowedPath = strings.TrimSpace(allowedPath)
+
+		if allowedPath == "" {
+			continue
+		}
+
+		if resolvedPath, err := filepath.EvalSymlinks(allowedPath); err == nil {
+			allowedPath = resolvedPath
+		}
+
+		allowedPath = filepath.Clean(allowedPath)
+
+		if socketPath == allowedPath || strings.HasPrefix(socketPath, strings.TrimSuffix(allowedPath, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Gets the real path of the agent socket, if it is allowed to be used
+func getAgentSocketPath(socketPath string) (string, error) {
+	if !filepath.IsAbs(socketPath) || !isAgentSocketAllowed(filepath.Clean(socketPath)) {
+		return "", errAgentSocketNotAllowed
+	}
+
+	// Symlinks could point outside of the allowed paths
+	resolvedPath, err := filepath.EvalSymlinks(socketPath)
+
+	if err != nil || !isAgentSocketAllowed(resolvedPath) {
+		return "", errAgentSocketNotAllowed
+	}
+
+	return resolvedPath, nil
+}
+
+func (config *AuthConfig) getSigners() ([]ssh.Signer, error) {
+	if config.PrivateKey == "" {
+		return []ssh.Signer{}, nil
+	}
+
+	var (
+		signer ssh.Signer
+		err    error
+	)
+
+	if config.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(config.PrivateKey), []byte(config.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(config.PrivateKey))
+	}
+
+	if err != nil {
+		var passphraseMissingError *ssh.PassphraseMissingError
+
+		if errors.As(err, &passphraseMissingError) {
+			return nil, fmt.Errorf("private key is encrypted, but no passphrase was given")
+		}
+
+		return nil, fmt.Errorf("failed to parse private key: %s", err.Error())
+	}
+
+	if config.Certificate == "" {
+		return []ssh.Signer{signer}, nil
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.Certificate))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %s", err.Error())
+	}
+
+	certificate, ok := publicKey.(*ssh.Certificate)
+
+	if !ok {
+		return nil, fmt.Errorf("certificate is a plain public key, not an OpenSSH certificate")
+	}
+
+	certSigner, err := ssh.NewCertSigner(certificate, signer)
+
+	if err != nil {
+		return nil, fmt.Errorf("certificate does not match the private key: %s", err.Error())
+	}
+
+	// Try the certificate first, but fall back to the plain key incase the server doesn't trust our CA
+	return []ssh.Signer{certSigner, signer}, nil
+}
+
+// CheckAuthConfig makes sure every configured authentication method is usable, without connecting to anything.
+func CheckAuthConfig(config *AuthConfig) error {
+	if config.PrivateKey == "" && config.Password == "" && config.AgentSocket == "" {
+		return fmt.Errorf("no authentication method is configured. Set privateKey, password, or agentSocket")
+	}
+
+	if _, err := config.getSigners(); err != nil {
+		return err
+	}
+
+	if config.AgentSocket != "" {
+		if _, err := getAgentSocketPath(config.AgentSocket); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Dial connects to an SSH server, trying every configured authentication method.
+func Dial(address, username string, config *AuthConfig, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	signers, err := config.getSigners()
+
+	if err != nil {
+		return nil, err
+	}
+
+	authMethods := []ssh.AuthMethod{}
+
+	if len(signers) != 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
+	}
+
+	if config.AgentSocket != "" {
+		agentSocketPath, err := getAgentSocketPath(config.AgentSocket)
+
+		if err != nil {
+			return nil, err
+		}
+
+		agentConn, err := net.Dial("unix", agentSocketPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to SSH agent: %s", err.Error())
+		}
+
+		// The agent is only needed during authentication
+		defer agentConn.Close()
+
+		authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+	}
+
+	if config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(config.Password))
+
+		// Some servers only allow passwords through keyboard-interactive auth, so we answer every prompt with it
+		authMethods = append(authMethods, ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+
+			for questionIndex := range questions {
+				answers[questionIndex] = config.Password
+			}
+
+			return answers, nil
+		}))
+	}
+
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("no authentication method is configured")
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		HostKeyCallback: hostKeyCallback,
+		User:            username,
+		Auth:            authMethods,
+		Timeout:         30 * time.Second,
+	}
+
+	return ssh.Dial("tcp", address, clientConfig)
+}
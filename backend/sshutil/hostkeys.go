@@ -0,0 +1,193 @@
+package sshutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Returned by the host key callback in CheckHostKey to abort the handshake once the host key has been verified.
+// We don't care about authentication at that point, so there's no reason to continue.
+var errHostKeyVerified = errors.New("host key verified")
+
+type HostKeyVerifier struct {
+	pinnedKeys         []ssh.PublicKey
+	knownHostsCallback ssh.HostKeyCallback
+	trustOnFirstUse    bool
+
+	learnedKeyLock sync.Mutex
+	learnedKey     ssh.PublicKey
+}
+
+// NewHostKeyVerifier creates a verifier from pinned host keys (in authorized_keys format) and/or a known_hosts blob.
+// If neither are specified, the first key we see is trusted if trustOnFirstUse is set. Otherwise, every key is rejected.
+func NewHostKeyVerifier(hostKeys []string, knownHosts string, trustOnFirstUse bool) (*HostKeyVerifier, error) {
+	verifier := &HostKeyVerifier{
+		pinnedKeys:      []ssh.PublicKey{},
+		trustOnFirstUse: trustOnFirstUse,
+	}
+
+	for keyIndex, hostKey := range hostKeys {
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host key #%d: %s", keyIndex+1, err.Error())
+		}
+
+		verifier.pinnedKeys = append(verifier.pinnedKeys, publicKey)
+	}
+
+	if strings.TrimSpace(knownHosts) != "" {
+		// The knownhosts package only reads from files, so we give it a temporary one. It gets read immediately,
+		// so we can get rid of the file right after.
+		knownHostsFile, err := os.CreateTemp("", "hermes-known-hosts-")
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary known_hosts file: %s", err.Error())
+		}
+
+		defer os.Remove(knownHostsFile.Name())
+
+		if _, err := knownHostsFile.WriteString(knownHosts); err != nil {
+			knownHostsFile.Close()
+			return nil, fmt.Errorf("failed to write temporary known_hosts file: %s", err.Error())
+		}
+
+		if err := knownHostsFile.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write temporary known_hosts file: %s", err.Error())
+		}
+
+		verifier.knownHostsCallback, err = knownhosts.New(knownHostsFile.Name())
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse known hosts: %s", err.Error())
+		}
+	}
+
+	return verifier, nil
+}
+
+func (verifier *HostKeyVerifier) Callback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	if len(verifier.pinnedKeys) != 0 || verifier.knownHostsCallback != nil {
+		for _, pinnedKey := range verifier.pinnedKeys {
+			if bytes.Equal(pinnedKey.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+
+		if verifier.knownHostsCallback != nil {
+			err := verifier.knownHostsCallback(hostname, remote, key)
+
+			if err == nil {
+				return nil
+			}
+
+			var keyError *knownhosts.KeyError
+
+			if !errors.As(err, &keyError) {
+				return fmt.Errorf("failed to check known hosts for %s: %s", hostname, err.Error())
+			}
+		}
+
+		return fmt.Errorf("host key mismatch for %s: server presented %s key %s, which is not in hostKeys or knownHosts", hostname, key.Type(), fingerprint)
+	}
+
+	verifier.learnedKeyLock.Lock()
+	defer verifier.learnedKeyLock.Unlock()
+
+	if verifier.learnedKey != nil {
+		if bytes.Equal(verifier.learnedKey.Marshal(), key.Marshal()) {
+			return nil
+		}
+
+		return fmt.Errorf("host key mismatch for %s: server presented %s key %s, but %s was trusted on first use", hostname, key.Type(), fingerprint, ssh.FingerprintSHA256(verifier.learnedKey))
+	}
+
+	if !verifier.trustOnFirstUse {
+		return fmt.Errorf("no host key is configured for %s (server presented %s key %s). Set hostKeys or knownHosts, or enable trustOnFirstUse", hostname, key.Type(), fingerprint)
+	}
+
+	log.Warnf("Trusting host key for %s on first use: %s %s", hostname, key.Type(), fingerprint)
+	verifier.learnedKey = key
+
+	return nil
+}
+
+// LearnedKey returns the key that was trusted on first use, or nil if we haven't trusted any key that way.
+func (verifier *HostKeyVerifier) LearnedKey() ssh.PublicKey {
+	verifier.learnedKeyLock.Lock()
+	defer verifier.learnedKeyLock.Unlock()
+
+	return verifier.learnedKey
+}
+
+// CheckHostKey connects to the server and verifies its host key without authenticating.
+func CheckHostKey(address string, verifier *HostKeyVerifier) error {
+	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %s", address, err.Error())
+	}
+
+	defer conn.Close()
+
+	var verificationErr error
+
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			verificationErr = verifier.Callback(hostname, remote, key)
+
+			if verificationErr != nil {
+				return verificationErr
+			}
+
+			return errHostKeyVerified
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	_, _, _, err = ssh.NewClientConn(conn, address, config)
+
+	if verificationErr != nil {
+		return verificationErr
+	}
+
+	if err != nil && !errors.Is(err, errHostKeyVerified) {
+		return fmt.Errorf("failed to complete SSH handshake with %s: %s", address, err.Error())
+	}
+
+	return nil
+}
+
+// AddHostKeyToParameters adds a host key to the "hostKeys" array of the backend parameters, keeping the rest of the
+// parameters untouched.
+func AddHostKeyToParameters(parameters []byte, key ssh.PublicKey) ([]byte, error) {
+	parsedParameters := map[string]interface{}{}
+
+	if err := json.Unmarshal(parameters, &parsedParameters); err != nil {
+		return nil, err
+	}
+
+	hostKeys := []interface{}{}
+
+	if existingHostKeys, ok := parsedParameters["hostKeys"].([]interface{}); ok {
+		hostKeys = existingHostKeys
+	}
+
+	hostKeys = append(hostKeys, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))))
+	parsedParameters["hostKeys"] = hostKeys
+
+	return json.Marshal(parsedParameters)
+}
@@ -0,0 +1,92 @@
+package sshutil
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultKeepaliveInterval  = 15 * time.Second
+	DefaultKeepaliveMaxMissed = 3
+
+	initialReconnectDelay = 1 * time.Second
+	maxReconnectDelay     = 2 * time.Minute
+)
+
+// Backoff computes delays between reconnection attempts. The delay doubles on every attempt (up to a limit), and is
+// jittered so that many backends that lost the same server don't all hammer it at the exact same time.
+type Backoff struct {
+	Attempts int
+}
+
+// Next returns how long to wait before the next attempt, and counts the attempt.
+func (backoff *Backoff) Next() time.Duration {
+	delay := maxReconnectDelay
+
+	// Past this point, the shift would go over the max delay anyways (and eventually overflow)
+	if backoff.Attempts < 8 {
+		delay = min(initialReconnectDelay<<backoff.Attempts, maxReconnectDelay)
+	}
+
+	backoff.Attempts++
+
+	// Wait for anywhere between half and all of the delay
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Reset starts the delays over, which should be done after a successful connection.
+func (backoff *Backoff) Reset() {
+	backoff.Attempts = 0
+}
+
+// SendKeepalives periodically pings the SSH server, and closes the connection if it misses maxMissed pings in a row.
+// This makes conn.Wait() return for dead peers, instead of hanging until the TCP connection times out (which can
+// take hours). It returns once the connection has been closed.
+func SendKeepalives(conn *ssh.Client, interval time.Duration, maxMissed int) {
+	if interval == 0 {
+		interval = DefaultKeepaliveInterval
+	}
+
+	if maxMissed == 0 {
+		maxMissed = DefaultKeepaliveMaxMissed
+	}
+
+	missedKeepalives := 0
+
+	for {
+		time.Sleep(interval)
+
+		replyChan := make(chan error, 1)
+
+		go func() {
+			// OpenSSH servers reply with a failure to this, but any reply at all means the server is still alive
+			_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+			replyChan <- err
+		}()
+
+		select {
+		case err := <-replyChan:
+			if err != nil {
+				// The connection has been closed already
+				return
+			}
+
+			missedKeepalives = 0
+			continue
+		case <-time.After(interval):
+		}
+
+		missedKeepalives++
+		log.Warnf("SSH server did not respond to keepalive (%d/%d)", missedKeepalives, maxMissed)
+
+		if missedKeepalives >= maxMissed {
+			log.Warn("SSH server missed too many keepalives. Closing the connection...")
+			conn.Close()
+
+			return
+		}
+	}
+}
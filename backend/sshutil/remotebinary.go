@@ -0,0 +1,104 @@
+package sshutil
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// GetRemoteArchitecture returns the GOARCH value matching the CPU architecture of the remote server.
+func GetRemoteArchitecture(conn *ssh.Client) (string, error) {
+	session, err := conn.NewSession()
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %s", err.Error())
+	}
+
+	defer session.Close()
+
+	cpuArchBytes, err := session.Output("uname -m")
+
+	if err != nil {
+		return "", fmt.Errorf("failed to run uname command: %s", err.Error())
+	}
+
+	cpuArch := strings.TrimSpace(string(cpuArchBytes))
+
+	// Ordered in (subjective) popularity
+	if cpuArch == "x86_64" {
+		return "amd64", nil
+	} else if cpuArch == "aarch64" {
+		return "arm64", nil
+	} else if cpuArch == "arm" || strings.HasPrefix(cpuArch, "armv") {
+		return "arm", nil
+	} else if len(cpuArch) == 4 && string(cpuArch[0]) == "i" && strings.HasSuffix(cpuArch, "86") {
+		return "386", nil
+	}
+
+	return "", fmt.Errorf("CPU architecture '%s' not compiled/supported currently", cpuArch)
+}
+
+// UploadBinary copies an executable to the remote server, unless an identical copy is already there.
+func UploadBinary(conn *ssh.Client, binary []byte, remotePath string) error {
+	session, err := conn.NewSession()
+
+	if err != nil {
+		return fmt.Errorf("failed to create session: %s", err.Error())
+	}
+
+	remoteMD5HashBytes, err := session.Output(fmt.Sprintf("[ -f %s ] && md5sum %s | cut -d \" \" -f 1", remotePath, remotePath))
+	session.Close()
+
+	// The command exits with a non-zero exit code if the file doesn't exist, so we only care about the output here
+	remoteMD5HashString := strings.TrimSpace(string(remoteMD5HashBytes))
+
+	if err == nil && remoteMD5HashString != "" {
+		remoteMD5Hash, err := hex.DecodeString(remoteMD5HashString)
+
+		if err != nil {
+			return fmt.Errorf("failed to decode hex: %s", err.Error())
+		}
+
+		localMD5Hash := md5.Sum(binary)
+
+		if bytes.Equal(localMD5Hash[:], remoteMD5Hash) {
+			log.Debugf("Skipping copying of '%s' as there's a copy on disk already.", remotePath)
+			return nil
+		}
+	}
+
+	log.Debugf("Copying binary to '%s'...", remotePath)
+
+	sftpInstance, err := sftp.NewClient(conn)
+
+	if err != nil {
+		return fmt.Errorf("failed to initialize SFTP: %s", err.Error())
+	}
+
+	defer sftpInstance.Close()
+
+	file, err := sftpInstance.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+
+	if err != nil {
+		return fmt.Errorf("failed to create (or open) file: %s", err.Error())
+	}
+
+	defer file.Close()
+
+	if _, err = file.Write(binary); err != nil {
+		return fmt.Errorf("failed to write file: %s", err.Error())
+	}
+
+	if err = file.Chmod(0755); err != nil {
+		return fmt.Errorf("failed to change permissions on file: %s", err.Error())
+	}
+
+	return nil
+}
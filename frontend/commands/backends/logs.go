@@ -0,0 +1,71 @@
+package backends
+
+import (
+	"fmt"
+	"time"
+
+	"git.terah.dev/imterah/hermes/apiclient/backends"
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/urfave/cli/v2"
+)
+
+func printLogLine(logLine *backends.LogLine) {
+	fmt.Printf("%s %-5s %s\n", logLine.Time.Local().Format(time.DateTime), logLine.Level, logLine.Message)
+}
+
+// Parses either a duration (ex. 10m, for the last 10 minutes), or a timestamp
+func parseSince(since string) (*time.Time, error) {
+	if duration, err := time.ParseDuration(since); err == nil {
+		sinceTime := time.Now().Add(-duration)
+		return &sinceTime, nil
+	}
+
+	sinceTime, err := time.Parse(time.RFC3339, since)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid time '%s' (expected a duration like 10m, or a timestamp like 2006-01-02T15:04:05Z)", since)
+	}
+
+	return &sinceTime, nil
+}
+
+func LogsCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	var since *time.Time
+
+	if cCtx.IsSet("since") {
+		since, err = parseSince(cCtx.String("since"))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	backendID := cCtx.Uint("backend-id")
+	tail := cCtx.Uint("tail")
+
+	if cCtx.Bool("follow") {
+		if err := api.BackendsFollowLogs(token, backendID, since, tail, printLogLine); err != nil {
+			return fmt.Errorf("failed to follow logs: %s", err.Error())
+		}
+
+		return nil
+	}
+
+	logLines, err := api.BackendsGetLogs(token, backendID, since, tail)
+
+	if err != nil {
+		return fmt.Errorf("failed to get logs: %s", err.Error())
+	}
+
+	for _, logLine := range logLines {
+		printLogLine(logLine)
+	}
+
+	return nil
+}
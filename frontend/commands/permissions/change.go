@@ -0,0 +1,54 @@
+package permissions
+
+import (
+	"fmt"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+)
+
+func GrantPermissionCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	if err := api.PermissionsGrant(token, cCtx.Uint("user-id"), cCtx.String("node")); err != nil {
+		return fmt.Errorf("failed to grant permission: %s", err.Error())
+	}
+
+	log.Info("Successfully granted permission.")
+	return nil
+}
+
+func RevokePermissionCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	if err := api.PermissionsRevoke(token, cCtx.Uint("user-id"), cCtx.String("node")); err != nil {
+		return fmt.Errorf("failed to revoke permission: %s", err.Error())
+	}
+
+	log.Info("Successfully revoked permission.")
+	return nil
+}
+
+func ResetPermissionCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	if err := api.PermissionsReset(token, cCtx.Uint("user-id"), cCtx.String("node")); err != nil {
+		return fmt.Errorf("failed to reset permission: %s", err.Error())
+	}
+
+	log.Info("Successfully reset permission. It now comes from the roles of the user.")
+	return nil
+}
@@ -0,0 +1,45 @@
+package permissions
+
+import (
+	"fmt"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/urfave/cli/v2"
+)
+
+func ListPermissionsCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	var uid *uint
+
+	if cCtx.IsSet("user-id") {
+		userID := cCtx.Uint("user-id")
+		uid = &userID
+	}
+
+	permissions, err := api.PermissionsList(token, uid)
+
+	if err != nil {
+		return fmt.Errorf("failed to list permissions: %s", err.Error())
+	}
+
+	for _, permission := range permissions {
+		state := "revoked"
+
+		if permission.HasPermission {
+			state = "granted"
+		}
+
+		if permission.IsOverride {
+			state += " (overrides roles)"
+		}
+
+		fmt.Printf("%s: %s\n", permission.PermissionNode, state)
+	}
+
+	return nil
+}
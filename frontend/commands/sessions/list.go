@@ -0,0 +1,54 @@
+package sessions
+
+import (
+	"fmt"
+	"time"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/urfave/cli/v2"
+)
+
+func ListSessionsCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	var uid *uint
+
+	if cCtx.IsSet("user-id") {
+		userID := cCtx.Uint("user-id")
+		uid = &userID
+	}
+
+	sessions, err := api.SessionsList(token, uid)
+
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %s", err.Error())
+	}
+
+	for _, session := range sessions {
+		lastUsed := "never"
+
+		if session.LastUsedAt != nil {
+			lastUsed = fmt.Sprintf("%s from %s", session.LastUsedAt.Format(time.RFC1123), session.LastUsedIPAddr)
+		}
+
+		fmt.Printf("Session #%d", session.SessionID)
+
+		if session.IsCurrent {
+			fmt.Print(" (current)")
+		}
+
+		fmt.Printf(":\n  Created: %s from %s\n  Last used: %s\n", session.CreatedAt.Format(time.RFC1123), session.CreationIPAddr, lastUsed)
+
+		if session.NeverExpires {
+			fmt.Println("  Never expires")
+		} else if session.ExpiresAt != nil {
+			fmt.Printf("  Expires: %s\n", session.ExpiresAt.Format(time.RFC1123))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package sessions
+
+import (
+	"fmt"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+)
+
+func RevokeSessionCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	if err := api.SessionsRevoke(token, cCtx.Uint("session-id")); err != nil {
+		return fmt.Errorf("failed to revoke session: %s", err.Error())
+	}
+
+	log.Info("Successfully revoked session.")
+	return nil
+}
+
+func RevokeOtherSessionsCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	var uid *uint
+
+	if cCtx.IsSet("user-id") {
+		userID := cCtx.Uint("user-id")
+		uid = &userID
+	}
+
+	revoked, err := api.SessionsRevokeOthers(token, uid)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %s", err.Error())
+	}
+
+	log.Infof("Successfully revoked %d session(s).", revoked)
+	return nil
+}
@@ -95,6 +95,10 @@ func CreateUserCommand(cCtx *cli.Context) error {
 
 	log.Info("Successfully created user.")
 
+	if refreshToken == "" {
+		log.Info("This server requires two-factor authentication. Log in to set it up.")
+	}
+
 	if cCtx.Bool("do-not-save-configuration") {
 		return nil
 	}
@@ -0,0 +1,74 @@
+package users
+
+import (
+	"fmt"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+)
+
+func EditUserCommand(cCtx *cli.Context) error {
+	if cCtx.Bool("disable") && cCtx.Bool("enable") {
+		return fmt.Errorf("can't both disable and enable the user")
+	}
+
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	var uid *uint
+	var name, email, username *string
+	var disabled *bool
+
+	if cCtx.IsSet("user-id") {
+		userID := cCtx.Uint("user-id")
+		uid = &userID
+	}
+
+	if cCtx.IsSet("full-name") {
+		userName := cCtx.String("full-name")
+		name = &userName
+	}
+
+	if cCtx.IsSet("email") {
+		userEmail := cCtx.String("email")
+		email = &userEmail
+	}
+
+	if cCtx.IsSet("username") {
+		userUsername := cCtx.String("username")
+		username = &userUsername
+	}
+
+	if cCtx.Bool("disable") || cCtx.Bool("enable") {
+		isDisabled := cCtx.Bool("disable")
+		disabled = &isDisabled
+	}
+
+	if err := api.UserEdit(token, uid, name, email, username, disabled); err != nil {
+		return fmt.Errorf("failed to edit user: %s", err.Error())
+	}
+
+	log.Info("Successfully edited user.")
+	return nil
+}
+
+func TransferOwnershipCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	backends, proxies, err := api.UserTransferOwnership(token, cCtx.Uint("user-id"), cCtx.Uint("new-owner-id"))
+
+	if err != nil {
+		return fmt.Errorf("failed to transfer ownership: %s", err.Error())
+	}
+
+	log.Infof("Successfully transferred %d backend(s) and %d proxies.", backends, proxies)
+	return nil
+}
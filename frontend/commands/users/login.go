@@ -7,6 +7,7 @@ import (
 	"syscall"
 
 	"git.terah.dev/imterah/hermes/apiclient"
+	apiUsers "git.terah.dev/imterah/hermes/apiclient/users"
 	"git.terah.dev/imterah/hermes/frontend/config"
 	"github.com/charmbracelet/log"
 	"github.com/urfave/cli/v2"
@@ -73,12 +74,49 @@ func GetRefreshTokenCommand(cCtx *cli.Context) error {
 		URL: serverURL,
 	}
 
-	refreshToken, err := api.UserGetRefreshToken(&username, nil, password)
+	var totpCode *string
+	var recoveryCode *string
 
-	if err != nil {
+	if cCtx.IsSet("totp-code") {
+		code := cCtx.String("totp-code")
+		totpCode = &code
+	}
+
+	if cCtx.IsSet("recovery-code") {
+		code := cCtx.String("recovery-code")
+		recoveryCode = &code
+	}
+
+	refreshToken, recoveryCodes, err := api.UserGetRefreshToken(&username, nil, password, totpCode, recoveryCode)
+
+	// The server asks for the second factor once the password is known to be correct, so ask for it and try again
+	var enrollRequiredErr *apiUsers.TOTPEnrollRequiredError
+
+	if errors.As(err, &enrollRequiredErr) {
+		fmt.Println("This server requires two-factor authentication. Add this secret to your authenticator app:")
+		fmt.Printf("\n  Secret: %s\n  URL: %s\n\n", enrollRequiredErr.Secret, enrollRequiredErr.URL)
+	}
+
+	if errors.Is(err, apiUsers.ErrTOTPRequired) || enrollRequiredErr != nil {
+		code, err := askTOTPCode()
+
+		if err != nil {
+			return err
+		}
+
+		refreshToken, recoveryCodes, err = api.UserGetRefreshToken(&username, nil, password, &code, nil)
+
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with the API: %s", err.Error())
+		}
+	} else if err != nil {
 		return fmt.Errorf("failed to authenticate with the API: %s", err.Error())
 	}
 
+	if recoveryCodes != nil {
+		printRecoveryCodes(recoveryCodes)
+	}
+
 	configContents.Username = username
 	configContents.RefreshToken = refreshToken
 	configContents.APIPath = serverURL
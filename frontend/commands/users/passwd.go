@@ -0,0 +1,90 @@
+package users
+
+import (
+	"fmt"
+	"syscall"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+func askPassword(prompt string) (string, error) {
+	fmt.Printf("%s: ", prompt)
+	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Print("\n")
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from console: %s", err.Error())
+	}
+
+	return string(passwordBytes), nil
+}
+
+func askNewPassword() (string, error) {
+	password, err := askPassword("New password")
+
+	if err != nil {
+		return "", err
+	}
+
+	confirmedPassword, err := askPassword("Confirm new password")
+
+	if err != nil {
+		return "", err
+	}
+
+	if password != confirmedPassword {
+		return "", fmt.Errorf("passwords don't match")
+	}
+
+	return password, nil
+}
+
+func ChangePasswordCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	// Users with users.edit can set the password of other users, without knowing their old one
+	if cCtx.IsSet("user-id") {
+		newPassword, err := askNewPassword()
+
+		if err != nil {
+			return err
+		}
+
+		revoked, err := api.UserResetPassword(token, cCtx.Uint("user-id"), newPassword)
+
+		if err != nil {
+			return fmt.Errorf("failed to reset password: %s", err.Error())
+		}
+
+		log.Infof("Successfully reset password. Logged out %d session(s) of the user.", revoked)
+		return nil
+	}
+
+	oldPassword, err := askPassword("Current password")
+
+	if err != nil {
+		return err
+	}
+
+	newPassword, err := askNewPassword()
+
+	if err != nil {
+		return err
+	}
+
+	revoked, err := api.UserChangePassword(token, oldPassword, newPassword)
+
+	if err != nil {
+		return fmt.Errorf("failed to change password: %s", err.Error())
+	}
+
+	log.Infof("Successfully changed password. Logged out %d other session(s).", revoked)
+	return nil
+}
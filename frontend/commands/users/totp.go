@@ -0,0 +1,124 @@
+package users
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.terah.dev/imterah/hermes/frontend/config"
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+)
+
+func askTOTPCode() (string, error) {
+	fmt.Print("Two-factor authentication code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read code from console: %s", err.Error())
+	}
+
+	return strings.TrimSpace(code), nil
+}
+
+func printRecoveryCodes(recoveryCodes []string) {
+	fmt.Println("Recovery codes (each one can be used once in place of a code, keep them somewhere safe):")
+
+	for _, recoveryCode := range recoveryCodes {
+		fmt.Printf("  %s\n", recoveryCode)
+	}
+}
+
+func EnableTOTPCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	secret, url, err := api.TOTPEnroll(token)
+
+	if err != nil {
+		return fmt.Errorf("failed to start enrolling into two-factor authentication: %s", err.Error())
+	}
+
+	fmt.Println("Add this secret to your authenticator app:")
+	fmt.Printf("\n  Secret: %s\n  URL: %s\n\n", secret, url)
+
+	code, err := askTOTPCode()
+
+	if err != nil {
+		return err
+	}
+
+	recoveryCodes, err := api.TOTPVerify(token, code)
+
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %s", err.Error())
+	}
+
+	printRecoveryCodes(recoveryCodes)
+
+	log.Info("Successfully enabled two-factor authentication.")
+	return nil
+}
+
+func DisableTOTPCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	var uid *uint
+	var code *string
+	var recoveryCode *string
+
+	if cCtx.IsSet("user-id") {
+		userID := cCtx.Uint("user-id")
+		uid = &userID
+	} else if cCtx.IsSet("recovery-code") {
+		userRecoveryCode := cCtx.String("recovery-code")
+		recoveryCode = &userRecoveryCode
+	} else {
+		// Users turning it off for themselves need to prove they still have their authenticator
+		userCode, err := askTOTPCode()
+
+		if err != nil {
+			return err
+		}
+
+		code = &userCode
+	}
+
+	if err := api.TOTPDisable(token, uid, code, recoveryCode); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %s", err.Error())
+	}
+
+	log.Info("Successfully disabled two-factor authentication.")
+	return nil
+}
+
+func RegenerateRecoveryCodesCommand(cCtx *cli.Context) error {
+	api, token, err := config.GetAuthenticatedClient(cCtx.String("config-path"), cCtx.String("server-url"))
+
+	if err != nil {
+		return err
+	}
+
+	code, err := askTOTPCode()
+
+	if err != nil {
+		return err
+	}
+
+	recoveryCodes, err := api.TOTPRegenerateRecoveryCodes(token, code)
+
+	if err != nil {
+		return fmt.Errorf("failed to regenerate recovery codes: %s", err.Error())
+	}
+
+	printRecoveryCodes(recoveryCodes)
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"git.terah.dev/imterah/hermes/apiclient"
+	"gopkg.in/yaml.v3"
+)
+
+// GetAuthenticatedClient reads the saved credentials from the configuration file, and exchanges the refresh token for a
+// JWT token, which is what every authenticated endpoint needs.
+func GetAuthenticatedClient(configPath, serverURL string) (*apiclient.HermesAPIClient, string, error) {
+	configContents, err := ReadAndParseConfig(configPath)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read and parse configuration file (are you logged in?): %s", err.Error())
+	}
+
+	if configContents.RefreshToken == "" {
+		return nil, "", fmt.Errorf("refresh token is not in the configuration file (are you logged in?)")
+	}
+
+	if serverURL == "" {
+		if configContents.APIPath == "" {
+			return nil, "", fmt.Errorf("server URL not specified and server URL is not in the configuration file")
+		}
+
+		serverURL = configContents.APIPath
+	}
+
+	api := &apiclient.HermesAPIClient{
+		URL: serverURL,
+	}
+
+	token, refreshToken, err := api.UserGetJWTFromToken(configContents.RefreshToken)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get JWT token: %s", err.Error())
+	}
+
+	// The old refresh token stops working once the server replaces it, so the new one has to be saved
+	if refreshToken != configContents.RefreshToken {
+		configContents.RefreshToken = refreshToken
+		data, err := yaml.Marshal(configContents)
+
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal configuration data: %s", err.Error())
+		}
+
+		if err := os.WriteFile(configPath, data, 0644); err != nil {
+			return nil, "", fmt.Errorf("failed to save new refresh token: %s", err.Error())
+		}
+	}
+
+	return api, token, nil
+}
@@ -4,6 +4,9 @@ import (
 	"os"
 	"path"
 
+	"git.terah.dev/imterah/hermes/frontend/commands/backends"
+	"git.terah.dev/imterah/hermes/frontend/commands/permissions"
+	"git.terah.dev/imterah/hermes/frontend/commands/sessions"
 	"git.terah.dev/imterah/hermes/frontend/commands/users"
 	"github.com/charmbracelet/log"
 	"github.com/urfave/cli/v2"
@@ -74,6 +77,16 @@ func main() {
 						Aliases: []string{"ask-pass", "ap"},
 						Usage:   "asks you the password to authenticate with",
 					},
+					&cli.StringFlag{
+						Name:    "totp-code",
+						Aliases: []string{"code", "c"},
+						Usage:   "two-factor authentication code (asked for if needed and not given)",
+					},
+					&cli.StringFlag{
+						Name:    "recovery-code",
+						Aliases: []string{"rc"},
+						Usage:   "recovery code to use in place of a two-factor authentication code",
+					},
 				},
 			},
 			{
@@ -132,6 +145,349 @@ func main() {
 							},
 						},
 					},
+					{
+						Name:    "edit",
+						Aliases: []string{"e"},
+						Usage:   "edit a user (defaults to yourself)",
+						Action:  users.EditUserCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "ID of the user to edit",
+							},
+							&cli.StringFlag{
+								Name:    "full-name",
+								Aliases: []string{"name", "n"},
+								Usage:   "new full name for the user",
+							},
+							&cli.StringFlag{
+								Name:    "username",
+								Aliases: []string{"user", "us"},
+								Usage:   "new username for the user",
+							},
+							&cli.StringFlag{
+								Name:    "email",
+								Aliases: []string{"e"},
+								Usage:   "new email for the user",
+							},
+							&cli.BoolFlag{
+								Name:  "disable",
+								Usage: "disables the user, which stops them from logging in and makes every token of theirs stop working",
+							},
+							&cli.BoolFlag{
+								Name:  "enable",
+								Usage: "enables the user again",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "transfer",
+						Aliases: []string{"t"},
+						Usage:   "give every backend and proxy of a user to another user",
+						Action:  users.TransferOwnershipCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:     "user-id",
+								Aliases:  []string{"id", "i"},
+								Usage:    "ID of the user to take the backends and proxies from",
+								Required: true,
+							},
+							&cli.UintFlag{
+								Name:     "new-owner-id",
+								Aliases:  []string{"to"},
+								Usage:    "ID of the user to give the backends and proxies to",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "passwd",
+						Aliases: []string{"password", "pw"},
+						Usage:   "change your password (or reset the password of another user)",
+						Action:  users.ChangePasswordCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "ID of the user to reset the password of (defaults to yourself)",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:    "totp",
+				Usage:   "two-factor authentication commands",
+				Aliases: []string{"2fa"},
+				Subcommands: []*cli.Command{
+					{
+						Name:    "enable",
+						Aliases: []string{"e"},
+						Usage:   "enroll into two-factor authentication",
+						Action:  users.EnableTOTPCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "disable",
+						Aliases: []string{"d"},
+						Usage:   "disable two-factor authentication (asks for a code, unless disabling it for another user)",
+						Action:  users.DisableTOTPCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "ID of the user to disable two-factor authentication for",
+							},
+							&cli.StringFlag{
+								Name:    "recovery-code",
+								Aliases: []string{"rc"},
+								Usage:   "recovery code to use in place of a two-factor authentication code",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "recovery-codes",
+						Aliases: []string{"rc"},
+						Usage:   "replace your recovery codes with new ones",
+						Action:  users.RegenerateRecoveryCodesCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:    "sessions",
+				Usage:   "session (refresh token) management commands",
+				Aliases: []string{"se"},
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "list the sessions of a user (defaults to yourself)",
+						Action:  sessions.ListSessionsCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "ID of the user to list the sessions of",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "revoke",
+						Aliases: []string{"r"},
+						Usage:   "revoke a session, logging it out",
+						Action:  sessions.RevokeSessionCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:     "session-id",
+								Aliases:  []string{"id", "i"},
+								Usage:    "ID of the session to revoke",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "revoke-others",
+						Aliases: []string{"ro"},
+						Usage:   "revoke every session except the current one (or every session of another user)",
+						Action:  sessions.RevokeOtherSessionsCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "ID of the user to revoke every session of",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:    "permissions",
+				Usage:   "permission management commands",
+				Aliases: []string{"p"},
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "list the permissions of a user (defaults to yourself)",
+						Action:  permissions.ListPermissionsCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "ID of the user to list the permissions of",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "grant",
+						Aliases: []string{"g"},
+						Usage:   "grant a permission to a user",
+						Action:  permissions.GrantPermissionCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:     "user-id",
+								Aliases:  []string{"id", "i"},
+								Usage:    "ID of the user to change the permissions of",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "node",
+								Aliases:  []string{"n"},
+								Usage:    "permission node to change (ex. routes.add)",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "revoke",
+						Aliases: []string{"r"},
+						Usage:   "revoke a permission from a user",
+						Action:  permissions.RevokePermissionCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:     "user-id",
+								Aliases:  []string{"id", "i"},
+								Usage:    "ID of the user to change the permissions of",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "node",
+								Aliases:  []string{"n"},
+								Usage:    "permission node to change (ex. routes.add)",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+					{
+						Name:    "reset",
+						Aliases: []string{"x"},
+						Usage:   "remove a permission override, so the permission comes from the roles of the user",
+						Action:  permissions.ResetPermissionCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:     "user-id",
+								Aliases:  []string{"id", "i"},
+								Usage:    "ID of the user to change the permissions of",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "node",
+								Aliases:  []string{"n"},
+								Usage:    "permission node to change (ex. routes.add)",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:    "backends",
+				Usage:   "backend management commands",
+				Aliases: []string{"b"},
+				Subcommands: []*cli.Command{
+					{
+						Name:    "logs",
+						Aliases: []string{"lo"},
+						Usage:   "show the logs of a running backend",
+						Action:  backends.LogsCommand,
+						Flags: []cli.Flag{
+							&cli.UintFlag{
+								Name:     "backend-id",
+								Aliases:  []string{"id", "i"},
+								Usage:    "ID of the backend to show the logs of",
+								Required: true,
+							},
+							&cli.UintFlag{
+								Name:    "tail",
+								Aliases: []string{"n"},
+								Usage:   "only show the last n lines",
+							},
+							&cli.StringFlag{
+								Name:    "since",
+								Aliases: []string{"t"},
+								Usage:   "only show lines written since a time (ex. 10m, or 2006-01-02T15:04:05Z)",
+							},
+							&cli.BoolFlag{
+								Name:    "follow",
+								Aliases: []string{"f"},
+								Usage:   "keep showing new lines as they get written",
+							},
+							&cli.StringFlag{
+								Name:    "server-url",
+								Aliases: []string{"server", "s"},
+								Usage:   "URL of the server to connect with",
+							},
+						},
+					},
 				},
 			},
 		},